@@ -0,0 +1,339 @@
+package v1alpha1
+
+import (
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DisabledTile is a Tile instance which was pruned as its 'when' was false
+type DisabledTile struct {
+	TileInstance string   `json:"tileInstance"`
+	When         string   `json:"when"`
+	PrunedFrom   []string `json:"prunedFrom,omitempty"` // Tile instances which depended on it
+}
+
+func (dt DisabledTile) String() string {
+	msg := "Tile instance " + dt.TileInstance + " was disabled as 'when: " + dt.When + "' was false"
+	if len(dt.PrunedFrom) > 0 {
+		msg = msg + ", and was pruned from dependsOn of [" + strings.Join(dt.PrunedFrom, ", ") + "]"
+	}
+	return msg
+}
+
+// EvaluateConditions evaluates 'when' of Tile instances after parameters were resolved, disabled Tile instances are pruned.
+// env is the name of last applied Overlay, which can be referred as 'env' in expression, and parameters are referred as $(params.name).
+func (deployment *Deployment) EvaluateConditions(env string) error {
+	disabled := make(map[string]*DisabledTile)
+	for _, ti := range deployment.OriginalOrder {
+		dt, ok := deployment.Spec.Template.Tiles[ti]
+		if !ok || strings.TrimSpace(string(dt.When)) == "" {
+			continue
+		}
+		enabled, err := EvaluateCondition(string(dt.When), env, deployment.parameters)
+		if err != nil {
+			return errors.Wrap(err, "'when' of Tile instance "+ti+" was invalid")
+		}
+		if !enabled {
+			disabled[ti] = &DisabledTile{TileInstance: ti, When: string(dt.When)}
+		}
+	}
+	if len(disabled) == 0 {
+		return nil
+	}
+
+	var order []string
+	for _, ti := range deployment.OriginalOrder {
+		if _, ok := disabled[ti]; ok {
+			delete(deployment.Spec.Template.Tiles, ti)
+			continue
+		}
+		order = append(order, ti)
+	}
+	if len(order) < 1 {
+		return errors.New("all Tile instances were disabled")
+	}
+	deployment.OriginalOrder = order
+
+	// Inputs referring to a disabled Tile instance are required dependencies, which can't be pruned
+	for _, ti := range order {
+		dt := deployment.Spec.Template.Tiles[ti]
+		for _, input := range dt.Inputs {
+			for _, v := range append([]string{input.InputValue}, input.InputValues...) {
+				if ref := referredInstance(v, disabled); ref != "" {
+					return errors.New("Tile instance " + ti + " requires disabled Tile instance " + ref + " in input " + input.Name)
+				}
+			}
+		}
+		var dependsOn []string
+		for _, d := range dt.DependsOn {
+			if disabledTile, ok := disabled[d]; ok {
+				disabledTile.PrunedFrom = append(disabledTile.PrunedFrom, ti)
+				continue
+			}
+			dependsOn = append(dependsOn, d)
+		}
+		dt.DependsOn = dependsOn
+		deployment.Spec.Template.Tiles[ti] = dt
+	}
+	for _, o := range deployment.Spec.Summary.Outputs {
+		if ref := referredInstance(o.Value, disabled); ref != "" {
+			return errors.New("summary output " + o.Name + " requires disabled Tile instance " + ref)
+		}
+	}
+
+	var names []string
+	for ti := range disabled {
+		names = append(names, ti)
+	}
+	sort.Strings(names)
+	for _, ti := range names {
+		deployment.DisabledTiles = append(deployment.DisabledTiles, *disabled[ti])
+	}
+	return nil
+}
+
+var instanceRefRe = regexp.MustCompile(`\$(?:cdk)?\(([[:alnum:]_-]*)\.`)
+
+// referredInstance returns the first disabled Tile instance referred by $(instance.outputs.name) or $cdk(instance.field.name)
+func referredInstance(value string, disabled map[string]*DisabledTile) string {
+	for _, ms := range instanceRefRe.FindAllStringSubmatch(value, -1) {
+		if _, ok := disabled[ms[1]]; ok {
+			return ms[1]
+		}
+	}
+	return ""
+}
+
+// EvaluateCondition evaluates a boolean expression, which supports:
+// literals - true, false, numbers & quoted strings;
+// variables - env, name of last applied Overlay, and $(params.name), value of parameter;
+// operators - ==, !=, !, &&, || and parentheses.
+// Value of parameter is always an operand, so it can't change the expression.
+func EvaluateCondition(expression string, env string, params map[string]string) (bool, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return false, err
+	}
+	p := &conditionParser{tokens: tokens, env: env, params: params}
+	v, err := p.or()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, errors.New("unexpected '" + p.tokens[p.pos].text + "' in expression: " + expression)
+	}
+	return v.bool()
+}
+
+type tokenKind int
+
+const (
+	operatorToken tokenKind = iota
+	stringToken
+	wordToken
+	paramToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	rs := []rune(expression)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{operatorToken, string(r)})
+			i++
+		case strings.HasPrefix(string(rs[i:]), "&&") || strings.HasPrefix(string(rs[i:]), "||") ||
+			strings.HasPrefix(string(rs[i:]), "==") || strings.HasPrefix(string(rs[i:]), "!="):
+			tokens = append(tokens, token{operatorToken, string(rs[i : i+2])})
+			i += 2
+		case r == '!':
+			tokens = append(tokens, token{operatorToken, "!"})
+			i++
+		case r == '$':
+			loc := paramRefRe.FindStringSubmatchIndex(string(rs[i:]))
+			if loc == nil || loc[0] != 0 {
+				return nil, errors.New("unexpected '$' in expression: " + expression)
+			}
+			ref := string(rs[i:])
+			tokens = append(tokens, token{paramToken, ref[loc[2]:loc[3]]})
+			i += len([]rune(ref[:loc[1]]))
+		case r == '"' || r == '\'':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j >= len(rs) {
+				return nil, errors.New("unterminated string in expression: " + expression)
+			}
+			tokens = append(tokens, token{stringToken, string(rs[i+1 : j])})
+			i = j + 1
+		default:
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || strings.ContainsRune("._-+", rs[j])) {
+				j++
+			}
+			if j == i {
+				return nil, errors.New("unexpected '" + string(r) + "' in expression: " + expression)
+			}
+			tokens = append(tokens, token{wordToken, string(rs[i:j])})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// conditionValue is value of an operand, boolean is kept as "true" or "false"
+type conditionValue string
+
+func (v conditionValue) bool() (bool, error) {
+	b, err := strconv.ParseBool(string(v))
+	if err != nil {
+		return false, errors.New("'" + string(v) + "' isn't a boolean")
+	}
+	return b, nil
+}
+
+func (v conditionValue) equal(o conditionValue) bool {
+	if v == o {
+		return true
+	}
+	f1, err1 := strconv.ParseFloat(string(v), 64)
+	f2, err2 := strconv.ParseFloat(string(o), 64)
+	if err1 == nil && err2 == nil {
+		return f1 == f2
+	}
+	b1, err1 := strconv.ParseBool(string(v))
+	b2, err2 := strconv.ParseBool(string(o))
+	return err1 == nil && err2 == nil && b1 == b2
+}
+
+type conditionParser struct {
+	tokens []token
+	pos    int
+	env    string
+	params map[string]string
+}
+
+func (p *conditionParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == operatorToken && p.tokens[p.pos].text == text
+}
+
+func (p *conditionParser) or() (conditionValue, error) {
+	v, err := p.and()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var r conditionValue
+		if r, err = p.and(); err == nil {
+			v, err = logical(v, r, func(a, b bool) bool { return a || b })
+		}
+	}
+	return v, err
+}
+
+func (p *conditionParser) and() (conditionValue, error) {
+	v, err := p.unary()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var r conditionValue
+		if r, err = p.unary(); err == nil {
+			v, err = logical(v, r, func(a, b bool) bool { return a && b })
+		}
+	}
+	return v, err
+}
+
+func (p *conditionParser) unary() (conditionValue, error) {
+	if p.peek("!") {
+		p.pos++
+		v, err := p.unary()
+		if err != nil {
+			return v, err
+		}
+		b, err := v.bool()
+		return conditionValue(strconv.FormatBool(!b)), err
+	}
+	return p.comparison()
+}
+
+func (p *conditionParser) comparison() (conditionValue, error) {
+	v, err := p.primary()
+	if err != nil {
+		return v, err
+	}
+	if p.peek("==") || p.peek("!=") {
+		op := p.tokens[p.pos].text
+		p.pos++
+		r, err := p.primary()
+		if err != nil {
+			return v, err
+		}
+		return conditionValue(strconv.FormatBool(v.equal(r) == (op == "=="))), nil
+	}
+	return v, nil
+}
+
+func (p *conditionParser) primary() (conditionValue, error) {
+	if p.pos >= len(p.tokens) {
+		return "", errors.New("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case stringToken:
+		return conditionValue(t.text), nil
+	case paramToken:
+		v, ok := p.params[t.text]
+		if !ok {
+			return "", errors.New("parameter " + t.text + " wasn't declared")
+		}
+		return conditionValue(v), nil
+	case wordToken:
+		if t.text == "env" {
+			return conditionValue(p.env), nil
+		}
+		if _, err := strconv.ParseBool(t.text); err == nil {
+			return conditionValue(t.text), nil
+		}
+		if _, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return conditionValue(t.text), nil
+		}
+		return "", errors.New("unknown variable '" + t.text + "', parameter should be referred as $(params.name)")
+	case operatorToken:
+		if t.text == "(" {
+			v, err := p.or()
+			if err != nil {
+				return v, err
+			}
+			if !p.peek(")") {
+				return v, errors.New("missing ')'")
+			}
+			p.pos++
+			return v, nil
+		}
+	}
+	return "", errors.New("unexpected '" + t.text + "'")
+}
+
+func logical(l, r conditionValue, op func(a, b bool) bool) (conditionValue, error) {
+	a, err := l.bool()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bool()
+	if err != nil {
+		return "", err
+	}
+	return conditionValue(strconv.FormatBool(op(a, b))), nil
+}
@@ -0,0 +1,118 @@
+package v1alpha1
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+var huWithConditions = `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-simple
+spec:
+  parameters:
+    - name: tracing
+      type: boolean
+      default: false
+  template:
+    tiles:
+      tileEks0005:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+        inputs:
+          - name: clusterName
+            inputValue: mahjong-eks-cluster
+      tileJaegerTracing:
+        tileReference: Jaeger-Tracing
+        tileVersion: 0.1.0
+        when: $(params.tracing) && env != "dev"
+        dependsOn:
+          - tileEks0005
+      tileArgocd:
+        tileReference: Argocd0
+        tileVersion: 1.5.4
+        dependsOn:
+          - tileEks0005
+          - tileJaegerTracing
+  summary:
+    description: EKS
+    outputs: []
+    notes: []
+`
+
+func TestEvaluateCondition(t *testing.T) {
+	tests := []struct {
+		expression string
+		env        string
+		want       bool
+		hasErr     bool
+	}{
+		{"true", "", true, false},
+		{`"false"`, "", false, false},
+		{`env == "prod"`, "prod", true, false},
+		{`env == 'prod' || env == "stage"`, "dev", false, false},
+		{`!(env == "dev") && "3" == 3.0`, "prod", true, false},
+		{`"yes"`, "", false, true},
+		{`tracing`, "", false, true},
+		{`(true`, "", false, true},
+		{`true false`, "", false, true},
+		{`$(params.tracing) && env != "dev"`, "prod", true, false},
+		{`$(params.region)=="us-east-1"`, "", true, false},
+		{`$(params.injection) == "x"`, "", false, false},
+		{`$(params.injection)`, "", false, true},
+		{`$(params.unknown)`, "", false, true},
+		{`$(tracing)`, "", false, true},
+	}
+	params := map[string]string{"tracing": "true", "region": "us-east-1", "injection": `x' || 'a' == 'a`}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := EvaluateCondition(test.expression, test.env, params)
+			assert.Equal(t, test.hasErr, err != nil)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestData_ParseDeploymentWithConditions(t *testing.T) {
+	x, _ := os.Getwd()
+	deploymentSchema = "file://" + x + "/../.././schema/deployment-schema.json"
+	params := "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nparams:\n  tracing: true"
+	overlay := "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Overlay\nmetadata:\n  name: dev\ntiles: {}"
+	required := "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Overlay\nmetadata:\n  name: dev\ntiles:\n  tileArgocd:\n    inputs:\n      - name: endpoint\n        inputValue: $(tileJaegerTracing.outputs.endpoint)"
+
+	d := Data(huWithConditions)
+	deployment, err := d.ParseDeployment(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tileEks0005", "tileArgocd"}, deployment.OriginalOrder)
+	assert.Equal(t, []string{"tileEks0005"}, deployment.Spec.Template.Tiles["tileArgocd"].DependsOn)
+	assert.Equal(t, []DisabledTile{{TileInstance: "tileJaegerTracing", When: `$(params.tracing) && env != "dev"`, PrunedFrom: []string{"tileArgocd"}}}, deployment.DisabledTiles)
+
+	d = Data(huWithConditions + params)
+	deployment, err = d.ParseDeployment(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tileEks0005", "tileJaegerTracing", "tileArgocd"}, deployment.OriginalOrder)
+	assert.Nil(t, deployment.DisabledTiles)
+
+	d = Data(huWithConditions + overlay + params)
+	deployment, err = d.ParseDeployment(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tileEks0005", "tileArgocd"}, deployment.OriginalOrder)
+
+	// Value of parameter can't inject into the expression
+	for _, injection := range []string{`x' || 'a' == 'a`, `x" || "a" == "a`} {
+		hu := strings.Replace(huWithConditions, "type: boolean\n      default: false", "type: string\n      default: off", 1)
+		hu = strings.Replace(hu, `when: $(params.tracing) && env != "dev"`, `when: $(params.tracing) == "on"`, 1)
+		d = Data(hu + "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nparams:\n  tracing: '" + strings.ReplaceAll(injection, "'", "''") + "'")
+		deployment, err = d.ParseDeployment(context.TODO())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"tileEks0005", "tileArgocd"}, deployment.OriginalOrder)
+	}
+
+	// Required dependency was disabled
+	d = Data(huWithConditions + required)
+	_, err = d.ParseDeployment(context.TODO())
+	assert.EqualError(t, err, "Tile instance tileArgocd requires disabled Tile instance tileJaegerTracing in input endpoint")
+}
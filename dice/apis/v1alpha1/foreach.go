@@ -0,0 +1,229 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	yamlv2 "gopkg.in/yaml.v2"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var eachRefRe = regexp.MustCompile(`\$\(each\.(value|index)((?:\.[[:alnum:]_-]+)*)\)`)
+var instanceSuffixRe = regexp.MustCompile(`[^[:alnum:]]+`)
+
+// ExpandForEach expands Tile instances with forEach into generated Tile instances named as <instance>-<value>,
+// element of forEach is referred as $(each.value), $(each.value.field) or $(each.index).
+// dependsOn pointing at an expanded Tile instance is replaced by all generated Tile instances, while references to its outputs are refused.
+func ExpandForEach(doc Data) (Data, map[string][]string, error) {
+	deployment := yamlv2.MapSlice{}
+	if err := yamlv2.Unmarshal(doc, &deployment); err != nil {
+		// Invalid specification would be reported while parsing
+		return doc, nil, nil
+	}
+	spec, _ := get(deployment, "spec").(yamlv2.MapSlice)
+	template, _ := get(spec, "template").(yamlv2.MapSlice)
+	tiles, ok := get(template, "tiles").(yamlv2.MapSlice)
+	if !ok {
+		return doc, nil, nil
+	}
+	hasForEach := false
+	for _, item := range tiles {
+		if detail, ok := item.Value.(yamlv2.MapSlice); ok && get(detail, "forEach") != nil {
+			hasForEach = true
+		}
+	}
+	if !hasForEach {
+		return doc, nil, nil
+	}
+
+	names := make(map[string]bool)
+	for _, item := range tiles {
+		names[fmt.Sprint(item.Key)] = true
+	}
+	expanded := make(map[string][]string)
+	expandedTiles := yamlv2.MapSlice{}
+	for _, item := range tiles {
+		instance := fmt.Sprint(item.Key)
+		detail, _ := item.Value.(yamlv2.MapSlice)
+		forEach := get(detail, "forEach")
+		if forEach == nil {
+			expandedTiles = append(expandedTiles, item)
+			continue
+		}
+		elements, ok := forEach.([]interface{})
+		if !ok || len(elements) == 0 {
+			return nil, nil, errors.New("forEach of Tile instance " + instance + " should be a list")
+		}
+		detail = remove(detail, "forEach")
+		for i, element := range elements {
+			suffix := instanceSuffix(element, i)
+			if suffix == "" {
+				return nil, nil, errors.Errorf("forEach of Tile instance %s can't name element %d", instance, i)
+			}
+			name := instance + "-" + suffix
+			if names[name] {
+				return nil, nil, errors.New("generated Tile instance was duplicated: " + name)
+			}
+			names[name] = true
+			generated, err := substituteEach(copyValue(detail), element, i)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "forEach of Tile instance "+instance+" was invalid")
+			}
+			expandedTiles = append(expandedTiles, yamlv2.MapItem{Key: name, Value: generated})
+			expanded[instance] = append(expanded[instance], name)
+		}
+	}
+
+	// Point dependsOn at generated Tile instances
+	for i, item := range expandedTiles {
+		detail, _ := item.Value.(yamlv2.MapSlice)
+		dependsOn, ok := get(detail, "dependsOn").([]interface{})
+		if !ok {
+			continue
+		}
+		var replaced []interface{}
+		for _, d := range dependsOn {
+			if generated, ok := expanded[fmt.Sprint(d)]; ok {
+				for _, g := range generated {
+					replaced = append(replaced, g)
+				}
+				continue
+			}
+			replaced = append(replaced, d)
+		}
+		expandedTiles[i].Value = set(detail, "dependsOn", replaced)
+	}
+
+	template = set(template, "tiles", expandedTiles)
+	spec = set(spec, "template", template)
+	deployment = set(deployment, "spec", spec)
+	// Outputs of an expanded Tile instance are ambiguous, which is reported here rather than failing while deploying
+	if err := checkExpandedRefs(deployment, expanded); err != nil {
+		return nil, nil, err
+	}
+	buf, err := yamlv2.Marshal(deployment)
+	return Data(buf), expanded, err
+}
+
+// checkExpandedRefs rejects $(instance.outputs.name) or $cdk(instance.field.name) referring to a Tile instance expanded by forEach
+func checkExpandedRefs(value interface{}, expanded map[string][]string) error {
+	switch v := value.(type) {
+	case string:
+		for _, ms := range instanceRefRe.FindAllStringSubmatch(v, -1) {
+			if generated, ok := expanded[ms[1]]; ok {
+				return errors.Errorf("Tile instance %s was expanded by forEach into [%s], refer to one of them instead in: %s",
+					ms[1], strings.Join(generated, ", "), v)
+			}
+		}
+	case yamlv2.MapSlice:
+		for _, item := range v {
+			if err := checkExpandedRefs(item.Value, expanded); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := checkExpandedRefs(item, expanded); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// instanceSuffix names a generated Tile instance by scalar element, or 'name' of map element, or index
+func instanceSuffix(element interface{}, index int) string {
+	switch e := element.(type) {
+	case yamlv2.MapSlice:
+		if name := get(e, "name"); name != nil {
+			element = name
+		} else {
+			return strconv.Itoa(index)
+		}
+	case []interface{}, nil:
+		return strconv.Itoa(index)
+	}
+	return strings.Trim(instanceSuffixRe.ReplaceAllString(fmt.Sprint(element), "-"), "-")
+}
+
+// substituteEach replaces $(each.*) in all strings, a string which is a whole reference is replaced by the element as it is
+func substituteEach(value interface{}, element interface{}, index int) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if ms := eachRefRe.FindStringSubmatch(v); ms != nil && ms[0] == strings.TrimSpace(v) {
+			return eachValue(ms, element, index)
+		}
+		var err error
+		s := eachRefRe.ReplaceAllStringFunc(v, func(ref string) string {
+			ev, e := eachValue(eachRefRe.FindStringSubmatch(ref), element, index)
+			switch ev.(type) {
+			case yamlv2.MapSlice, []interface{}:
+				e = errors.New(ref + " isn't a scalar value in: " + v)
+			}
+			if e != nil {
+				err = e
+				return ref
+			}
+			return fmt.Sprint(ev)
+		})
+		return s, err
+	case yamlv2.MapSlice:
+		for i, item := range v {
+			sv, err := substituteEach(item.Value, element, index)
+			if err != nil {
+				return nil, err
+			}
+			v[i].Value = sv
+		}
+	case []interface{}:
+		for i, item := range v {
+			sv, err := substituteEach(item, element, index)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = sv
+		}
+	}
+	return value, nil
+}
+
+func eachValue(ms []string, element interface{}, index int) (interface{}, error) {
+	if ms[1] == "index" {
+		if ms[2] != "" {
+			return nil, errors.New(ms[0] + " was invalid")
+		}
+		return index, nil
+	}
+	value := element
+	for _, field := range strings.Split(strings.TrimPrefix(ms[2], "."), ".") {
+		if field == "" {
+			continue
+		}
+		m, ok := value.(yamlv2.MapSlice)
+		if !ok || get(m, field) == nil {
+			return nil, errors.New(ms[0] + " wasn't found in element of forEach")
+		}
+		value = get(m, field)
+	}
+	return value, nil
+}
+
+// copyValue deep copies YAML value, so that each generated Tile instance has its own value
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yamlv2.MapSlice:
+		c := make(yamlv2.MapSlice, len(v))
+		for i, item := range v {
+			c[i] = yamlv2.MapItem{Key: item.Key, Value: copyValue(item.Value)}
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copyValue(item)
+		}
+		return c
+	}
+	return value
+}
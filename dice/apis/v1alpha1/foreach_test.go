@@ -0,0 +1,108 @@
+package v1alpha1
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var huWithForEach = `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-multi-region
+spec:
+  template:
+    tiles:
+      tileEks:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+        forEach:
+          - ap-southeast-1
+          - us-west-2
+        region: $(each.value)
+        inputs:
+          - name: clusterName
+            inputValue: mahjong-$(each.value)-$(each.index)
+      tileArgocd:
+        tileReference: Argocd0
+        tileVersion: 1.5.4
+        forEach:
+          - name: dev
+            profile: dev-account
+            capacity: 2
+            instanceTypes:
+              - m5.large
+          - name: prod
+            profile: prod-account
+            capacity: 5
+            instanceTypes:
+              - m5.large
+              - c5.large
+        profile: $(each.value.profile)
+        dependsOn:
+          - tileEks
+        inputs:
+          - name: capacity
+            inputValue: $(each.value.capacity)
+          - name: instanceTypes
+            inputValues: $(each.value.instanceTypes)
+  summary:
+    description: EKS
+    outputs:
+      - name: endpoint
+        value: $(tileEks-us-west-2.outputs.clusterEndpoint)
+    notes: []
+`
+
+func TestData_ParseDeploymentWithForEach(t *testing.T) {
+	x, _ := os.Getwd()
+	deploymentSchema = "file://" + x + "/../.././schema/deployment-schema.json"
+	d := Data(huWithForEach)
+	deployment, err := d.ParseDeployment(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tileEks-ap-southeast-1", "tileEks-us-west-2", "tileArgocd-dev", "tileArgocd-prod"}, deployment.OriginalOrder)
+	assert.Equal(t, map[string][]string{
+		"tileEks":    {"tileEks-ap-southeast-1", "tileEks-us-west-2"},
+		"tileArgocd": {"tileArgocd-dev", "tileArgocd-prod"},
+	}, deployment.ExpandedTiles)
+
+	eks := deployment.Spec.Template.Tiles["tileEks-us-west-2"]
+	assert.Equal(t, "us-west-2", eks.Region)
+	assert.Equal(t, "mahjong-us-west-2-1", eks.Inputs[0].InputValue)
+
+	argocd := deployment.Spec.Template.Tiles["tileArgocd-prod"]
+	assert.Equal(t, "prod-account", argocd.Profile)
+	assert.Equal(t, []string{"tileEks-ap-southeast-1", "tileEks-us-west-2"}, argocd.DependsOn)
+	assert.Equal(t, "5", argocd.Inputs[0].InputValue)
+	assert.Equal(t, []string{"m5.large", "c5.large"}, argocd.Inputs[1].InputValues)
+	assert.Equal(t, "dev-account", deployment.Spec.Template.Tiles["tileArgocd-dev"].Profile)
+}
+
+func TestExpandForEach(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiles string
+	}{
+		{"not a list", "      tileEks:\n        forEach: us-west-2\n"},
+		{"duplicated", "      tileEks:\n        forEach: [us-west-2, us_west_2]\n"},
+		{"field not found", "      tileEks:\n        forEach: [us-west-2]\n        region: $(each.value.region)\n"},
+		{"not a scalar", "      tileEks:\n        forEach:\n          - name: a\n        region: r-$(each.value)\n"},
+		{"outputs of expanded", "      tileEks:\n        forEach: [a, b]\n      tileArgocd:\n        inputs:\n          - name: clusterName\n            inputValue: $(tileEks.outputs.clusterName)\n"},
+		{"cdk of expanded", "      tileEks:\n        forEach: [a, b]\n      tileArgocd:\n        inputs:\n          - name: vpc\n            inputValues: [$cdk(tileEks.Network0.baseVpc)]\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := ExpandForEach(Data("spec:\n  template:\n    tiles:\n" + test.tiles))
+			assert.Error(t, err)
+		})
+	}
+
+	// References are checked in summary as well, and the generated Tile instances are named
+	_, _, err := ExpandForEach(Data("spec:\n  template:\n    tiles:\n      tileEks:\n        forEach: [a, b]\n" +
+		"  summary:\n    outputs:\n      - name: endpoint\n        value: $(tileEks.outputs.clusterEndpoint)\n"))
+	assert.EqualError(t, err, "Tile instance tileEks was expanded by forEach into [tileEks-a, tileEks-b], refer to one of them instead in: $(tileEks.outputs.clusterEndpoint)")
+	_, _, err = ExpandForEach(Data("spec:\n  template:\n    tiles:\n      tileEks:\n        forEach: [a, b]\n" +
+		"  summary:\n    outputs:\n      - name: endpoint\n        value: $(tileEks-a.outputs.clusterEndpoint)\n"))
+	assert.NoError(t, err)
+}
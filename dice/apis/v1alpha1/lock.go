@@ -0,0 +1,70 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// LockFileName is the conventional name of lock file for a deployment
+const LockFileName = "mahjong.lock"
+
+// Lock pins all resolved Tile instances of a deployment, includes generated dependent instances.
+type Lock struct {
+	ApiVersion string       `json:"apiVersion" valid:"in(mahjong.io/v1alpha1)"`
+	Kind       string       `json:"kind" valid:"in(Lock)"`
+	Metadata   LockMetadata `json:"metadata"`
+	Tiles      []LockedTile `json:"tiles"`
+}
+
+// LockMetadata lock.metadata
+type LockMetadata struct {
+	Name      string    `json:"name"`      // Name of locked deployment: metadata.name
+	Generated time.Time `json:"generated"` // Generated time of lock
+}
+
+// LockedTile lock.tiles
+type LockedTile struct {
+	TileInstance     string `json:"tileInstance"`
+	TileReference    string `json:"tileReference"`
+	TileVersion      string `json:"tileVersion"`
+	Digest           string `json:"digest"` // Digest of pulled Tile, eg: sha256:<hex>
+	RootTileInstance string `json:"rootTileInstance,omitempty"`
+	Generated        bool   `json:"generated,omitempty"` // Generated is true if the instance was generated as dependency
+}
+
+// Differ returns all differences between locked and resolved Tiles, empty means identical.
+func (l *Lock) Differ(resolved *Lock) []string {
+	var diffs []string
+	locked := make(map[string]LockedTile)
+	for _, t := range l.Tiles {
+		locked[t.TileInstance] = t
+	}
+	current := make(map[string]LockedTile)
+	for _, t := range resolved.Tiles {
+		current[t.TileInstance] = t
+	}
+
+	for _, t := range resolved.Tiles {
+		lt, ok := locked[t.TileInstance]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s < %s - %s > wasn't in lock", t.TileInstance, t.TileReference, t.TileVersion))
+			continue
+		}
+		if lt.TileReference != t.TileReference || lt.TileVersion != t.TileVersion {
+			diffs = append(diffs, fmt.Sprintf("%s was locked as < %s - %s > but resolved as < %s - %s >",
+				t.TileInstance, lt.TileReference, lt.TileVersion, t.TileReference, t.TileVersion))
+		}
+		if lt.Digest != t.Digest {
+			diffs = append(diffs, fmt.Sprintf("%s < %s - %s > was locked with digest %s but pulled %s",
+				t.TileInstance, t.TileReference, t.TileVersion, lt.Digest, t.Digest))
+		}
+	}
+	for _, t := range l.Tiles {
+		if _, ok := current[t.TileInstance]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s < %s - %s > was locked but not resolved", t.TileInstance, t.TileReference, t.TileVersion))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
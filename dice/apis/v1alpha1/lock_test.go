@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var lockedTiles = []LockedTile{
+	{TileInstance: "tileEks0005", TileReference: "Eks0", TileVersion: "0.0.5", Digest: "sha256:001"},
+	{TileInstance: "Network0tileEks0005Generated", TileReference: "Network0", TileVersion: "0.0.1", Digest: "sha256:002", Generated: true},
+}
+
+func TestLock_Differ(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolved []LockedTile
+		diffs    int
+	}{
+		{"identical", lockedTiles, 0},
+		{"re-uploaded tile", []LockedTile{lockedTiles[0], {TileInstance: "Network0tileEks0005Generated", TileReference: "Network0", TileVersion: "0.0.1", Digest: "sha256:003"}}, 1},
+		{"changed version", []LockedTile{{TileInstance: "tileEks0005", TileReference: "Eks0", TileVersion: "0.0.6", Digest: "sha256:001"}, lockedTiles[1]}, 1},
+		{"missing generated tile", []LockedTile{lockedTiles[0]}, 1},
+		{"extra tile", append([]LockedTile{{TileInstance: "tileArgocd", TileReference: "Argocd0", TileVersion: "1.5.4", Digest: "sha256:004"}}, lockedTiles...), 1},
+	}
+	lock := &Lock{Tiles: lockedTiles}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffs := lock.Differ(&Lock{Tiles: test.resolved})
+			assert.Equal(t, test.diffs, len(diffs))
+		})
+	}
+}
+
+func TestData_ParseLock(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		tiles int
+		isNil bool
+	}{
+		{"Deployment only", `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-simple`, 0, true},
+		{"Deployment with lock", `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-simple
+---
+apiVersion: mahjong.io/v1alpha1
+kind: Lock
+metadata:
+  name: eks-simple
+tiles:
+  - tileInstance: tileEks0005
+    tileReference: Eks0
+    tileVersion: 0.0.5
+    digest: sha256:001`, 1, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := Data(test.input)
+			lock, err := d.ParseLock(context.TODO())
+			assert.NoError(t, err)
+			if test.isNil {
+				assert.Nil(t, lock)
+			} else {
+				assert.Equal(t, test.tiles, len(lock.Tiles))
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package v1alpha1
+
+import (
+	"github.com/pkg/errors"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+// Enumeration for patch type of Overlay
+type PatchType int
+
+const (
+	StrategicMergePatch PatchType = iota
+	JSONMergePatch
+)
+
+func (pt PatchType) PTString() string {
+	return [...]string{"strategic", "merge"}[pt]
+}
+
+// Overlay patches Tile instances of Deployment for an environment, which is submitted along with Deployment as another document.
+// Overlays are applied in submitted order before Values.
+type Overlay struct {
+	ApiVersion string          `yaml:"apiVersion" valid:"in(mahjong.io/v1alpha1)"`
+	Kind       string          `yaml:"kind" valid:"in(Overlay)"`
+	Metadata   OverlayMetadata `yaml:"metadata"`
+	PatchType  string          `yaml:"patchType,omitempty"` // strategic (default) or merge, which is JSON merge patch
+	Tiles      yamlv2.MapSlice `yaml:"tiles" valid:"-"`     // Tile instance -> patch, null would remove the Tile instance
+}
+
+// OverlayMetadata overlay.metadata
+type OverlayMetadata struct {
+	Name string `yaml:"name"` // Name of environment
+}
+
+// mergeKeys are keys to merge list of Tile instance by strategic merge patch
+var mergeKeys = map[string]string{
+	"inputs": "name",
+}
+
+// ApplyOverlays patches Deployment document with Overlays
+func ApplyOverlays(doc Data, overlays []Overlay) (Data, error) {
+	if len(overlays) == 0 {
+		return doc, nil
+	}
+	deployment := yamlv2.MapSlice{}
+	if err := yamlv2.Unmarshal(doc, &deployment); err != nil {
+		return nil, errors.Wrap(err, "deployment specification was invalid")
+	}
+	spec, _ := get(deployment, "spec").(yamlv2.MapSlice)
+	template, _ := get(spec, "template").(yamlv2.MapSlice)
+	tiles, ok := get(template, "tiles").(yamlv2.MapSlice)
+	if !ok {
+		return nil, errors.New("deployment specification didn't include tiles")
+	}
+
+	for _, overlay := range overlays {
+		if overlay.PatchType != "" && overlay.PatchType != StrategicMergePatch.PTString() && overlay.PatchType != JSONMergePatch.PTString() {
+			return nil, errors.Errorf("patch type of overlay: %s was unsupported: %s", overlay.Metadata.Name, overlay.PatchType)
+		}
+		for _, item := range overlay.Tiles {
+			if item.Value == nil {
+				tiles = remove(tiles, item.Key)
+				continue
+			}
+			if _, ok := item.Value.(yamlv2.MapSlice); !ok {
+				return nil, errors.Errorf("patch of tile instance < %v > in overlay: %s was invalid", item.Key, overlay.Metadata.Name)
+			}
+			if overlay.PatchType == JSONMergePatch.PTString() {
+				tiles = set(tiles, item.Key, mergePatch(get(tiles, item.Key), item.Value))
+			} else {
+				tiles = set(tiles, item.Key, strategicMergePatch(get(tiles, item.Key), item.Value, ""))
+			}
+		}
+	}
+
+	template = set(template, "tiles", tiles)
+	spec = set(spec, "template", template)
+	deployment = set(deployment, "spec", spec)
+	buf, err := yamlv2.Marshal(deployment)
+	return Data(buf), err
+}
+
+// mergePatch applies JSON merge patch (RFC 7386): maps are merged, null removes the key, others are replaced
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(yamlv2.MapSlice)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(yamlv2.MapSlice)
+	if !ok {
+		t = yamlv2.MapSlice{}
+	}
+	for _, item := range p {
+		if item.Value == nil {
+			t = remove(t, item.Key)
+		} else {
+			t = set(t, item.Key, mergePatch(get(t, item.Key), item.Value))
+		}
+	}
+	return t
+}
+
+// strategicMergePatch applies merge patch, but list with merge key is merged by the key, and item with '$patch: delete' is removed,
+// list of dependsOn is merged as a set
+func strategicMergePatch(target interface{}, patch interface{}, key string) interface{} {
+	switch p := patch.(type) {
+	case yamlv2.MapSlice:
+		t, ok := target.(yamlv2.MapSlice)
+		if !ok {
+			t = yamlv2.MapSlice{}
+		}
+		for _, item := range p {
+			if item.Value == nil {
+				t = remove(t, item.Key)
+			} else {
+				k, _ := item.Key.(string)
+				t = set(t, item.Key, strategicMergePatch(get(t, item.Key), item.Value, k))
+			}
+		}
+		return t
+	case []interface{}:
+		t, _ := target.([]interface{})
+		if key == "dependsOn" {
+			merged := append([]interface{}{}, t...)
+			for _, v := range p {
+				if !containsValue(merged, v) {
+					merged = append(merged, v)
+				}
+			}
+			return merged
+		}
+		mergeKey, ok := mergeKeys[key]
+		if !ok {
+			return p
+		}
+		merged := append([]interface{}{}, t...)
+		for _, v := range p {
+			pm, ok := v.(yamlv2.MapSlice)
+			if !ok {
+				continue
+			}
+			idx := -1
+			for i, tv := range merged {
+				if tm, ok := tv.(yamlv2.MapSlice); ok && get(tm, mergeKey) == get(pm, mergeKey) {
+					idx = i
+				}
+			}
+			isDelete := get(pm, "$patch") == "delete"
+			pm = remove(pm, "$patch")
+			switch {
+			case isDelete && idx >= 0:
+				merged = append(merged[:idx], merged[idx+1:]...)
+			case isDelete:
+			case idx >= 0:
+				// Value & values of input are exclusive
+				if key == "inputs" {
+					if get(pm, "inputValue") != nil {
+						merged[idx] = remove(merged[idx].(yamlv2.MapSlice), "inputValues")
+					} else if get(pm, "inputValues") != nil {
+						merged[idx] = remove(merged[idx].(yamlv2.MapSlice), "inputValue")
+					}
+				}
+				merged[idx] = strategicMergePatch(merged[idx], pm, "")
+			default:
+				merged = append(merged, pm)
+			}
+		}
+		return merged
+	}
+	return patch
+}
+
+func get(m yamlv2.MapSlice, key interface{}) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+func set(m yamlv2.MapSlice, key interface{}, value interface{}) yamlv2.MapSlice {
+	for i, item := range m {
+		if item.Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yamlv2.MapItem{Key: key, Value: value})
+}
+
+func remove(m yamlv2.MapSlice, key interface{}) yamlv2.MapSlice {
+	var r yamlv2.MapSlice
+	for _, item := range m {
+		if item.Key != key {
+			r = append(r, item)
+		}
+	}
+	return r
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, i := range list {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
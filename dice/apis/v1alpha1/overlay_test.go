@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var huBase = `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-simple
+spec:
+  template:
+    tiles:
+      tileEks0005:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+        region: us-west-2
+        inputs:
+          - name: clusterName
+            inputValue: mahjong-eks-cluster
+          - name: capacity
+            inputValue: 2
+          - name: capacityInstance
+            inputValues:
+              - m5.large
+      tileArgocd:
+        tileReference: Argocd0
+        tileVersion: 1.5.4
+        dependsOn:
+          - tileEks0005
+  summary:
+    description: EKS
+    outputs: []
+    notes: []
+`
+
+func TestData_ParseOverlays(t *testing.T) {
+	x, _ := os.Getwd()
+	deploymentSchema = "file://" + x + "/../.././schema/deployment-schema.json"
+	tests := []struct {
+		name      string
+		overlay   string
+		instances []string
+		inputs    map[string]TileInput
+		region    string
+	}{
+		{"strategic", `
+patchType: strategic
+tiles:
+  tileEks0005:
+    region: ap-southeast-1
+    inputs:
+      - name: capacity
+        inputValue: 5
+      - name: capacityInstance
+        inputValue: c5.large
+      - name: clusterName
+        $patch: delete
+      - name: clusterVersion
+        inputValue: "1.16"
+  tileArgocd: null`, []string{"tileEks0005"}, map[string]TileInput{
+			"capacity":         {Name: "capacity", InputValue: "5"},
+			"capacityInstance": {Name: "capacityInstance", InputValue: "c5.large"},
+			"clusterVersion":   {Name: "clusterVersion", InputValue: "1.16"},
+		}, "ap-southeast-1"},
+		{"default", `
+tiles:
+  tileEks0005:
+    inputs:
+      - name: capacity
+        inputValue: 3`, []string{"tileEks0005", "tileArgocd"}, map[string]TileInput{
+			"clusterName":      {Name: "clusterName", InputValue: "mahjong-eks-cluster"},
+			"capacity":         {Name: "capacity", InputValue: "3"},
+			"capacityInstance": {Name: "capacityInstance", InputValues: []string{"m5.large"}},
+		}, "us-west-2"},
+		{"merge", `
+patchType: merge
+tiles:
+  tileEks0005:
+    inputs:
+      - name: capacity
+        inputValue: 5
+  tileNetwork:
+    tileReference: Network0
+    tileVersion: 0.0.1`, []string{"tileEks0005", "tileArgocd", "tileNetwork"}, map[string]TileInput{
+			"capacity": {Name: "capacity", InputValue: "5"},
+		}, "us-west-2"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := Data(huBase + "---\napiVersion: mahjong.io/v1alpha1\nkind: Overlay\nmetadata:\n  name: prod" + test.overlay)
+			deployment, err := d.ParseDeployment(context.TODO())
+			assert.NoError(t, err)
+			assert.Equal(t, test.instances, deployment.OriginalOrder)
+			assert.Equal(t, []string{"prod"}, deployment.AppliedOverlays)
+			inputs := make(map[string]TileInput)
+			for _, input := range deployment.Spec.Template.Tiles["tileEks0005"].Inputs {
+				inputs[input.Name] = input
+			}
+			assert.Equal(t, test.inputs, inputs)
+			assert.Equal(t, test.region, deployment.Spec.Template.Tiles["tileEks0005"].Region)
+		})
+	}
+}
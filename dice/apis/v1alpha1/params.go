@@ -0,0 +1,257 @@
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// Enumeration for type of Deployment parameter
+type ParameterType int
+
+const (
+	StringParameter ParameterType = iota
+	NumberParameter
+	BooleanParameter
+	ArrayParameter
+)
+
+func (pt ParameterType) PTString() string {
+	return [...]string{"string", "number", "boolean", "array"}[pt]
+}
+
+// DeploymentParameter deployment.spec.parameters, which is referred as $(params.name) in inputs
+type DeploymentParameter struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type,omitempty"` // string (default), number, boolean, array; array is separated by ','
+	Description string        `json:"description,omitempty"`
+	Default     *ScalarString `json:"default,omitempty"` // Default is nil if it wasn't given, "" is a default as well
+	Secret      bool          `json:"secret,omitempty"`  // Value wouldn't be shown if it's secret
+}
+
+// ScalarString accepts string, number & boolean as string
+type ScalarString string
+
+func (s *ScalarString) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*s = ScalarString(str)
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case float64, bool:
+		*s = ScalarString(strings.TrimSpace(string(b)))
+		return nil
+	}
+	return errors.New("value should be string, number or boolean: " + string(b))
+}
+
+// ParameterValue is resolved value of parameter
+type ParameterValue struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"` // default or given
+}
+
+// SecretMask replaces value of secret parameter
+const SecretMask = "******"
+
+var paramRefRe = regexp.MustCompile(`\$\(params\.([[:alnum:]_-]*)\)`)
+
+// ResolveParameters validates given parameters and replaces $(params.name) in inputs, array parameter would be inputValues if it's the whole value
+func (deployment *Deployment) ResolveParameters(given map[string]string) error {
+	declared := make(map[string]DeploymentParameter)
+	for _, p := range deployment.Spec.Parameters {
+		if p.Type == "" {
+			p.Type = StringParameter.PTString()
+		}
+		if _, ok := declared[p.Name]; ok {
+			return errors.New("parameter was declared repeatedly: " + p.Name)
+		}
+		declared[p.Name] = p
+	}
+	for name := range given {
+		if _, ok := declared[name]; !ok {
+			return errors.New("parameter wasn't declared in deployment: " + name)
+		}
+	}
+
+	var missing []string
+	values := make(map[string]string)
+	for _, p := range deployment.Spec.Parameters {
+		p = declared[p.Name]
+		pv := ParameterValue{Name: p.Name}
+		if v, ok := given[p.Name]; ok {
+			pv.Value, pv.Source = v, "given"
+		} else if p.Default != nil {
+			pv.Value, pv.Source = string(*p.Default), "default"
+		} else {
+			missing = append(missing, p.Name)
+			continue
+		}
+		if err := checkParameterType(p, pv.Value); err != nil {
+			return err
+		}
+		values[p.Name] = pv.Value
+		if p.Secret {
+			if pv.Value != "" {
+				deployment.secrets = append(deployment.secrets, pv.Value)
+			}
+			pv.Value = SecretMask
+		}
+		deployment.ResolvedParameters = append(deployment.ResolvedParameters, pv)
+	}
+	if len(missing) > 0 {
+		return errors.New("parameters: [" + strings.Join(missing, ", ") + "] need values")
+	}
+	deployment.parameters = values
+
+	var undeclared []string
+	replace := func(str string) string {
+		return paramRefRe.ReplaceAllStringFunc(str, func(ref string) string {
+			name := paramRefRe.FindStringSubmatch(ref)[1]
+			if v, ok := values[name]; ok {
+				return v
+			}
+			undeclared = append(undeclared, name)
+			return ref
+		})
+	}
+	for ti, dt := range deployment.Spec.Template.Tiles {
+		inputs := append([]TileInput{}, dt.Inputs...)
+		for i, input := range inputs {
+			ms := paramRefRe.FindStringSubmatch(input.InputValue)
+			if ms != nil && ms[0] == strings.TrimSpace(input.InputValue) && declared[ms[1]].Type == ArrayParameter.PTString() {
+				inputs[i].InputValue = ""
+				inputs[i].InputValues = splitArray(values[ms[1]])
+				continue
+			}
+			inputs[i].InputValue = replace(input.InputValue)
+			var ivs []string
+			for _, iv := range input.InputValues {
+				ivs = append(ivs, replace(iv))
+			}
+			inputs[i].InputValues = ivs
+		}
+		dt.Inputs = inputs
+		// Parameter in 'when' is substituted as an operand while it's evaluated
+		replace(string(dt.When))
+		deployment.Spec.Template.Tiles[ti] = dt
+	}
+	for i, o := range deployment.Spec.Summary.Outputs {
+		deployment.Spec.Summary.Outputs[i].Value = replace(o.Value)
+	}
+	if len(undeclared) > 0 {
+		sort.Strings(undeclared)
+		return errors.New("parameters: [" + strings.Join(undeclared, ", ") + "] were referred but not declared")
+	}
+	return nil
+}
+
+// Secrets returns values of secret parameters, which should be masked before the deployment is shown
+func (deployment *Deployment) Secrets() []string {
+	return deployment.secrets
+}
+
+// MaskSecrets returns v in generic JSON, where values of secret parameters in any string are replaced by SecretMask
+func MaskSecrets(v interface{}, secrets []string) (interface{}, error) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(buf))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	if len(secrets) == 0 {
+		return generic, nil
+	}
+	// The longest one wins if secrets are overlapped
+	sorted := append([]string{}, secrets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+	var pairs []string
+	for _, s := range sorted {
+		pairs = append(pairs, s, SecretMask)
+	}
+	return maskValue(generic, strings.NewReplacer(pairs...)), nil
+}
+
+// Masked returns the documents where values of secret parameters are replaced by SecretMask, comments aren't kept
+func (d *Data) Masked(secrets []string) (Data, error) {
+	var masked []string
+	for _, doc := range d.documents() {
+		var generic interface{}
+		if err := yaml.Unmarshal(doc, &generic); err != nil {
+			return nil, err
+		}
+		if generic == nil {
+			continue
+		}
+		v, err := MaskSecrets(generic, secrets)
+		if err != nil {
+			return nil, err
+		}
+		buf, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		masked = append(masked, string(buf))
+	}
+	return Data(strings.Join(masked, "---\n")), nil
+}
+
+func maskValue(v interface{}, r *strings.Replacer) interface{} {
+	switch value := v.(type) {
+	case string:
+		return r.Replace(value)
+	case []interface{}:
+		for i := range value {
+			value[i] = maskValue(value[i], r)
+		}
+	case map[string]interface{}:
+		for k := range value {
+			value[k] = maskValue(value[k], r)
+		}
+	}
+	return v
+}
+
+func checkParameterType(p DeploymentParameter, value string) error {
+	var err error
+	switch p.Type {
+	case StringParameter.PTString(), ArrayParameter.PTString():
+	case NumberParameter.PTString():
+		_, err = strconv.ParseFloat(value, 64)
+	case BooleanParameter.PTString():
+		_, err = strconv.ParseBool(value)
+	default:
+		return errors.New("type of parameter " + p.Name + " was unsupported: " + p.Type)
+	}
+	if err != nil {
+		return errors.New("parameter " + p.Name + " should be " + p.Type)
+	}
+	return nil
+}
+
+func splitArray(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,113 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var huWithParameters = `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-simple
+spec:
+  parameters:
+    - name: clusterName
+      description: Name of EKS cluster
+    - name: capacity
+      type: number
+      default: 2
+    - name: instanceTypes
+      type: array
+      default: m5.large,c5.large
+    - name: keyPair
+      secret: true
+    - name: suffix
+      default: ""
+  template:
+    tiles:
+      tileEks0005:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+        inputs:
+          - name: clusterName
+            inputValue: $(params.clusterName)-prod
+          - name: capacity
+            inputValue: $(params.capacity)
+          - name: capacityInstance
+            inputValue: $(params.instanceTypes)
+          - name: keyPair4EC2
+            inputValue: $(params.keyPair)
+          - name: clusterSuffix
+            inputValue: cluster$(params.suffix)
+  summary:
+    description: EKS
+    outputs: []
+    notes: []
+`
+
+func TestData_ParseDeploymentWithParameters(t *testing.T) {
+	x, _ := os.Getwd()
+	deploymentSchema = "file://" + x + "/../.././schema/deployment-schema.json"
+	tests := []struct {
+		name   string
+		params string
+		hasErr bool
+	}{
+		{"missing parameters", "", true},
+		{"given parameters", "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nparams:\n  clusterName: mahjong\n  keyPair: ore-keypair", false},
+		{"wrong type", "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nparams:\n  clusterName: mahjong\n  keyPair: ore\n  capacity: two", true},
+		{"undeclared parameter", "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nparams:\n  clusterName: mahjong\n  keyPair: ore\n  region: us-west-2", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := Data(huWithParameters + test.params)
+			deployment, err := d.ParseDeployment(context.TODO())
+			assert.Equal(t, test.hasErr, err != nil)
+			if err != nil {
+				return
+			}
+			inputs := make(map[string]TileInput)
+			for _, input := range deployment.Spec.Template.Tiles["tileEks0005"].Inputs {
+				inputs[input.Name] = input
+			}
+			assert.Equal(t, "mahjong-prod", inputs["clusterName"].InputValue)
+			assert.Equal(t, "2", inputs["capacity"].InputValue)
+			assert.Equal(t, []string{"m5.large", "c5.large"}, inputs["capacityInstance"].InputValues)
+			assert.Equal(t, "ore-keypair", inputs["keyPair4EC2"].InputValue)
+			assert.Equal(t, "cluster", inputs["clusterSuffix"].InputValue)
+			assert.Equal(t, ParameterValue{Name: "keyPair", Value: SecretMask, Source: "given"}, deployment.ResolvedParameters[3])
+			assert.Equal(t, ParameterValue{Name: "suffix", Value: "", Source: "default"}, deployment.ResolvedParameters[4])
+			assert.Equal(t, []string{"ore-keypair"}, deployment.Secrets())
+
+			masked, err := MaskSecrets(deployment, deployment.Secrets())
+			assert.NoError(t, err)
+			buf, _ := json.Marshal(masked)
+			assert.NotContains(t, string(buf), "ore-keypair")
+			assert.Contains(t, string(buf), `"inputValue":"`+SecretMask+`"`)
+		})
+	}
+
+	// Hu is a template, parameters are resolved while deploying
+	d := Data(huWithParameters)
+	hu, err := d.ParseHu(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(hu.Spec.Parameters))
+	assert.Nil(t, hu.Spec.Parameters[0].Default)
+	assert.Equal(t, ScalarString(""), *hu.Spec.Parameters[4].Default)
+}
+
+func TestMaskSecrets(t *testing.T) {
+	v := map[string]interface{}{"key": "ore-keypair", "list": []string{"--key=ore-keypair-2", "ore"}, "size": 1234567890123}
+	masked, err := MaskSecrets(v, []string{"ore-keypair", "ore-keypair-2"})
+	assert.NoError(t, err)
+	buf, _ := json.Marshal(masked)
+	assert.JSONEq(t, `{"key":"******","list":["--key=******","ore"],"size":1234567890123}`, string(buf))
+
+	masked, err = MaskSecrets(v, nil)
+	assert.NoError(t, err)
+	buf, _ = json.Marshal(masked)
+	assert.JSONEq(t, `{"key":"ore-keypair","list":["--key=ore-keypair-2","ore"],"size":1234567890123}`, string(buf))
+}
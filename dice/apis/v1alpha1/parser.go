@@ -21,9 +21,50 @@ type Data []byte
 type ParserCore interface {
 	ParseTile(ctx context.Context) (*Tile, error)
 	ParseDeployment(ctx context.Context) (*Deployment, error)
+	ParseHu(ctx context.Context) (*Deployment, error)
 	ValidateTile(ctx context.Context, tile *Tile) error
 	ValidateDeployment(ctx context.Context, deployment *Deployment) error
 	CheckParameter(ctx context.Context, deployment *Deployment) error
+	ParseLock(ctx context.Context) (*Lock, error)
+	ParseValues(ctx context.Context) ([]Values, error)
+	ParseOverlays(ctx context.Context) ([]Overlay, error)
+}
+
+// documents splits multiple YAML documents, which are separated by '---'
+func (d *Data) documents() []Data {
+	var docs []Data
+	var doc []string
+	for _, line := range strings.Split(string(*d), "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			if len(doc) > 0 {
+				docs = append(docs, Data(strings.Join(doc, "\n")))
+			}
+			doc = nil
+			continue
+		}
+		doc = append(doc, line)
+	}
+	if len(doc) > 0 {
+		docs = append(docs, Data(strings.Join(doc, "\n")))
+	}
+	return docs
+}
+
+// document returns the first document as per kind, return all data if there's only one document
+func (d *Data) document(kind string) (Data, bool) {
+	docs := d.documents()
+	for _, doc := range docs {
+		var k struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := yamlv2.Unmarshal(doc, &k); err == nil && k.Kind == kind {
+			return doc, true
+		}
+	}
+	if len(docs) < 2 {
+		return *d, false
+	}
+	return nil, false
 }
 
 // ParseTile parse Tile
@@ -70,11 +111,35 @@ func (d *Data) ParseTile(ctx context.Context) (*Tile, error) {
 	return &tile, d.ValidateTile(ctx, &tile)
 }
 
-// ParseDeployment parse Deployment
+// ParseDeployment parse Deployment, and apply Values, parameters & conditions for deployment
 func (d *Data) ParseDeployment(ctx context.Context) (*Deployment, error) {
+	return d.parseDeployment(ctx, false)
+}
+
+// ParseHu parse Deployment as a Hu in the repo, which is a template with parameters to be resolved
+func (d *Data) ParseHu(ctx context.Context) (*Deployment, error) {
+	return d.parseDeployment(ctx, true)
+}
+
+func (d *Data) parseDeployment(ctx context.Context, isHu bool) (*Deployment, error) {
 	var deployment Deployment
+	// Deployment could be submitted along with other documents, eg: Lock
+	doc, _ := d.document("Deployment")
+	// Patch Tile instances with Overlays
+	overlays, err := d.ParseOverlays(ctx)
+	if err != nil {
+		return &deployment, err
+	}
+	if doc, err = ApplyOverlays(doc, overlays); err != nil {
+		return &deployment, err
+	}
+	// Expand Tile instances with forEach, prior to original order
+	doc, expanded, err := ExpandForEach(doc)
+	if err != nil {
+		return &deployment, err
+	}
 	mapSlice := yamlv2.MapSlice{}
-	if err := yamlv2.Unmarshal(*d, &mapSlice); err != nil {
+	if err := yamlv2.Unmarshal(doc, &mapSlice); err != nil {
 		log.Errorf("Unmarshal mapSlice yaml error : %s\n", err)
 		return &deployment, errors.New("deployment specification was invalid")
 	}
@@ -116,7 +181,7 @@ func (d *Data) ParseDeployment(ctx context.Context) (*Deployment, error) {
 	}
 	////
 
-	if err := yaml.UnmarshalStrict(*d, &deployment); err != nil {
+	if err := yaml.UnmarshalStrict(doc, &deployment); err != nil {
 		log.Errorf("Unmarshal yaml error : %s\n", err)
 		return &deployment, err
 	}
@@ -130,10 +195,108 @@ func (d *Data) ParseDeployment(ctx context.Context) (*Deployment, error) {
 	//}
 	// Attache original order
 	deployment.OriginalOrder = originalOrder
+	deployment.ExpandedTiles = expanded
+	for _, o := range overlays {
+		deployment.AppliedOverlays = append(deployment.AppliedOverlays, o.Metadata.Name)
+	}
+	if isHu {
+		return &deployment, d.validateDeploymentSchema(ctx, &deployment)
+	}
+
+	// Override inputs with Values
+	values, err := d.ParseValues(ctx)
+	if err != nil {
+		return &deployment, err
+	}
+	if err := deployment.ApplyValues(values); err != nil {
+		return &deployment, err
+	}
+	// Resolve parameters, the latter Values wins
+	params := make(map[string]string)
+	for _, v := range values {
+		for name, value := range v.Params {
+			params[name] = value
+		}
+	}
+	if err := deployment.ResolveParameters(params); err != nil {
+		return &deployment, err
+	}
+	// Prune disabled Tile instances as per 'when', 'env' is the last applied Overlay
+	env := ""
+	if len(deployment.AppliedOverlays) > 0 {
+		env = deployment.AppliedOverlays[len(deployment.AppliedOverlays)-1]
+	}
+	if err := deployment.EvaluateConditions(env); err != nil {
+		return &deployment, err
+	}
 
 	return &deployment, d.ValidateDeployment(ctx, &deployment)
 }
 
+// ParseLock parse Lock if it was submitted along with Deployment, return nil if there's no Lock
+func (d *Data) ParseLock(ctx context.Context) (*Lock, error) {
+	doc, ok := d.document("Lock")
+	if !ok {
+		return nil, nil
+	}
+	var lock Lock
+	if err := yaml.UnmarshalStrict(doc, &lock); err != nil {
+		log.Errorf("Unmarshal lock error : %s\n", err)
+		return nil, errors.Wrap(err, "lock was invalid")
+	}
+	if _, err := valid.ValidateStruct(lock); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// ParseValues parse all Values in submitted order, which were submitted along with Deployment
+func (d *Data) ParseValues(ctx context.Context) ([]Values, error) {
+	var values []Values
+	for _, doc := range d.documents() {
+		var k struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := yamlv2.Unmarshal(doc, &k); err != nil || k.Kind != "Values" {
+			continue
+		}
+		var v Values
+		// Using yaml.v2 to keep value as it is
+		if err := yamlv2.UnmarshalStrict(doc, &v); err != nil {
+			log.Errorf("Unmarshal values error : %s\n", err)
+			return nil, errors.Wrap(err, "values was invalid")
+		}
+		if _, err := valid.ValidateStruct(v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// ParseOverlays parse all Overlays in submitted order, which were submitted along with Deployment
+func (d *Data) ParseOverlays(ctx context.Context) ([]Overlay, error) {
+	var overlays []Overlay
+	for _, doc := range d.documents() {
+		var k struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := yamlv2.Unmarshal(doc, &k); err != nil || k.Kind != "Overlay" {
+			continue
+		}
+		var o Overlay
+		if err := yamlv2.UnmarshalStrict(doc, &o); err != nil {
+			log.Errorf("Unmarshal overlay error : %s\n", err)
+			return nil, errors.Wrap(err, "overlay was invalid")
+		}
+		if _, err := valid.ValidateStruct(o); err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, o)
+	}
+	return overlays, nil
+}
+
 // ValidateTile validates Tile as per tile-spec.yaml
 func (d *Data) ValidateTile(ctx context.Context, tile *Tile) error {
 	// Validate json schema
@@ -164,6 +327,14 @@ func (d *Data) ValidateTile(ctx context.Context, tile *Tile) error {
 
 // ValidateDeployment validate Deployment as per deployment-spec.yaml
 func (d *Data) ValidateDeployment(ctx context.Context, deployment *Deployment) error {
+	if err := d.validateDeploymentSchema(ctx, deployment); err != nil {
+		return err
+	}
+	// Check parameters if need to be replaced
+	return d.CheckParameter(ctx, deployment)
+}
+
+func (d *Data) validateDeploymentSchema(ctx context.Context, deployment *Deployment) error {
 	// Validate json schema
 	schemaLoader := gojsonschema.NewReferenceLoader(deploymentSchema)
 	jsonLoader := gojsonschema.NewGoLoader(deployment)
@@ -187,11 +358,7 @@ func (d *Data) ValidateDeployment(ctx context.Context, deployment *Deployment) e
 	}
 	// Validate as per annotation
 	_, err = valid.ValidateStruct(deployment)
-	if err != nil {
-		return err
-	}
-	// Check parameters if need to be replaced
-	return d.CheckParameter(ctx, deployment)
+	return err
 }
 
 func (d *Data) CheckParameter(ctx context.Context, deployment *Deployment) error {
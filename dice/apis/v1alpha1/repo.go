@@ -2,6 +2,14 @@ package v1alpha1
 
 import "time"
 
+// RepoIndex is index of Tiles & Hu in the repo, which is stored as index.yaml on the root of repo
+type RepoIndex struct {
+	ApiVersion string                    `json:"apiVersion"`
+	Generated  time.Time                 `json:"generated"`
+	Tiles      map[string][]TileMetadata `json:"tiles"` // Tile name -> all versions of Tile
+	Hus        map[string]HuMetadata     `json:"hus"`   // Hu name -> Hu
+}
+
 type Repo struct {
 	Tiles []TileMetadata `json:"tiles"`
 	Hu    []HuMetadata   `json:"hus"`
@@ -19,17 +27,20 @@ func (l License) LicenseString() string {
 }
 
 type TileMetadata struct {
-	Name         string         `json:"name"`
-	Version      string         `json:"version"`
-	Category     string         `json:"category"`
-	Description  string         `json:"description"`
-	TileRepo     string         `json:"tileRepo"`
-	VersionTag   string         `json:"versionTag"`
-	Author       string         `json:"author,omitempty"`
-	Email        string         `json:"email,omitempty"`
-	License      string         `json:"license,omitempty"`
-	Dependencies []TileMetadata `json:"dependencies,omitempty"`
-	Released     time.Time      `json:"released"`
+	Name                     string         `json:"name"`
+	Version                  string         `json:"version"`
+	Category                 string         `json:"category"`
+	VendorService            string         `json:"vendorService,omitempty"`
+	DependentOnVendorService string         `json:"dependentOnVendorService,omitempty"`
+	Description              string         `json:"description"`
+	TileRepo                 string         `json:"tileRepo"`
+	VersionTag               string         `json:"versionTag"`
+	Author                   string         `json:"author,omitempty"`
+	Email                    string         `json:"email,omitempty"`
+	License                  string         `json:"license,omitempty"`
+	Dependencies             []TileMetadata `json:"dependencies,omitempty"`
+	Released                 time.Time      `json:"released"`
+	Digest                   string         `json:"digest,omitempty"`
 }
 
 type HuMetadata struct {
@@ -42,4 +53,5 @@ type HuMetadata struct {
 	License      string         `json:"license,omitempty"`
 	Dependencies []TileMetadata `json:"dependencies"`
 	Released     time.Time      `json:"released"`
+	Digest       string         `json:"digest,omitempty"`
 }
@@ -0,0 +1,102 @@
+package v1alpha1
+
+import (
+	"sort"
+	"strings"
+)
+
+// TileQuery is criteria to search Tiles in the repo
+type TileQuery struct {
+	Category      string `form:"category"`
+	VendorService string `form:"vendorService"` // Matches vendorService or dependentOnVendorService
+	Author        string `form:"author"`
+	Q             string `form:"q"`    // Matches name, description or category
+	Sort          string `form:"sort"` // name (default), version or released
+	Latest        bool   `form:"latest"`
+	Page          int    `form:"page"`
+	PageSize      int    `form:"pageSize"` // All matched Tiles would be returned if it's 0
+}
+
+// Search returns Tiles matched the query in requested page, and total number of matched Tiles
+func (q *TileQuery) Search(tiles []TileMetadata) ([]TileMetadata, int) {
+	var matched []TileMetadata
+	for _, t := range tiles {
+		if q.match(t) {
+			matched = append(matched, t)
+		}
+	}
+
+	// Newer version comes first for same Tile
+	sort.SliceStable(matched, func(i, j int) bool {
+		switch q.Sort {
+		case "released":
+			if !matched[i].Released.Equal(matched[j].Released) {
+				return matched[i].Released.After(matched[j].Released)
+			}
+		case "version":
+			if c := CompareVersion(matched[i].Version, matched[j].Version); c != 0 {
+				return c > 0
+			}
+		}
+		if matched[i].Name != matched[j].Name {
+			return matched[i].Name < matched[j].Name
+		}
+		return CompareVersion(matched[i].Version, matched[j].Version) > 0
+	})
+
+	if q.Latest {
+		latest := make(map[string]TileMetadata)
+		for _, t := range matched {
+			if l, ok := latest[t.Name]; !ok || CompareVersion(t.Version, l.Version) > 0 {
+				latest[t.Name] = t
+			}
+		}
+		var filtered []TileMetadata
+		for _, t := range matched {
+			if latest[t.Name].Version == t.Version {
+				filtered = append(filtered, t)
+			}
+		}
+		matched = filtered
+	}
+
+	total := len(matched)
+	if q.PageSize <= 0 {
+		return matched, total
+	}
+	page := q.Page
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * q.PageSize
+	if start >= total {
+		return []TileMetadata{}, total
+	}
+	end := start + q.PageSize
+	if end > total {
+		end = total
+	}
+	return matched[start:end], total
+}
+
+func (q *TileQuery) match(t TileMetadata) bool {
+	if q.Category != "" && !strings.EqualFold(q.Category, t.Category) {
+		return false
+	}
+	if q.VendorService != "" && !strings.EqualFold(q.VendorService, t.VendorService) &&
+		!strings.EqualFold(q.VendorService, t.DependentOnVendorService) {
+		return false
+	}
+	if q.Author != "" && !strings.Contains(strings.ToLower(t.Author), strings.ToLower(q.Author)) {
+		return false
+	}
+	if q.Q != "" {
+		term := strings.ToLower(q.Q)
+		if !strings.Contains(strings.ToLower(t.Name), term) &&
+			!strings.Contains(strings.ToLower(t.Description), term) &&
+			!strings.Contains(strings.ToLower(t.Category), term) {
+			return false
+		}
+	}
+	return true
+}
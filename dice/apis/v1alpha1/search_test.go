@@ -0,0 +1,62 @@
+package v1alpha1
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var catalog = []TileMetadata{
+	{Name: "Network0", Version: "0.0.1", Category: "Network", Author: "Mahjong"},
+	{Name: "Eks0", Version: "0.0.5", Category: "ContainerProvider", VendorService: "EKS"},
+	{Name: "Eks0", Version: "0.0.10", Category: "ContainerProvider", VendorService: "EKS"},
+	{Name: "Argocd0", Version: "1.5.4", Category: "ContainerApplication", DependentOnVendorService: "EKS", Description: "GitOps"},
+	{Name: "Istio0", Version: "1.5.4", Category: "ContainerApplication", DependentOnVendorService: "Kubernetes"},
+}
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		a      string
+		b      string
+		output int
+	}{
+		{"0.0.10", "0.0.5", 1},
+		{"1.16.8", "1.16.8", 0},
+		{"0.1", "0.1.1", -1},
+		{"v1.5.4", "1.5.3", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.a+" vs "+test.b, func(t *testing.T) {
+			assert.Equal(t, test.output, CompareVersion(test.a, test.b))
+		})
+	}
+}
+
+func TestTileQuery_Search(t *testing.T) {
+	tests := []struct {
+		name  string
+		query TileQuery
+		names []string
+		total int
+	}{
+		{"all", TileQuery{}, []string{"Argocd0", "Eks0", "Eks0", "Istio0", "Network0"}, 5},
+		{"container applications on EKS", TileQuery{Category: "containerapplication", VendorService: "EKS"}, []string{"Argocd0"}, 1},
+		{"latest only", TileQuery{Q: "eks", Latest: true}, []string{"Eks0"}, 1},
+		{"by description", TileQuery{Q: "gitops"}, []string{"Argocd0"}, 1},
+		{"by author", TileQuery{Author: "mahjong"}, []string{"Network0"}, 1},
+		{"second page", TileQuery{Page: 2, PageSize: 2}, []string{"Eks0", "Istio0"}, 5},
+		{"out of range", TileQuery{Page: 4, PageSize: 2}, []string{}, 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tiles, total := test.query.Search(catalog)
+			names := []string{}
+			for _, tile := range tiles {
+				names = append(names, tile.Name)
+			}
+			assert.Equal(t, test.names, names)
+			assert.Equal(t, test.total, total)
+		})
+	}
+	tiles, _ := (&TileQuery{Q: "eks", Latest: true}).Search(catalog)
+	assert.Equal(t, "0.0.10", tiles[0].Version)
+}
@@ -65,17 +65,25 @@ func (mts ManifestType) MTString() string {
 
 // Deployment specification
 type Deployment struct {
-	ApiVersion    string         `json:"apiVersion" jsonschema:"required" valid:"in(mahjong.io/v1alpha1)"`
-	Kind          string         `json:"kind" jsonschema:"required" valid:"in(Deployment)"`
-	Metadata      Metadata       `json:"metadata" jsonschema:"required"`
-	Spec          DeploymentSpec `json:"spec" jsonschema:"required"`
-	OriginalOrder []string       `json:"originalOrder,omitempty"` // Stored TileInstance, keep original order as same as in yaml
+	ApiVersion         string              `json:"apiVersion" jsonschema:"required" valid:"in(mahjong.io/v1alpha1)"`
+	Kind               string              `json:"kind" jsonschema:"required" valid:"in(Deployment)"`
+	Metadata           Metadata            `json:"metadata" jsonschema:"required"`
+	Spec               DeploymentSpec      `json:"spec" jsonschema:"required"`
+	OriginalOrder      []string            `json:"originalOrder,omitempty"`      // Stored TileInstance, keep original order as same as in yaml
+	AppliedOverlays    []string            `json:"appliedOverlays,omitempty"`    // Name of applied Overlays in order
+	ExpandedTiles      map[string][]string `json:"expandedTiles,omitempty"`      // Tile instance with forEach -> generated Tile instances
+	AppliedValues      []AppliedValue      `json:"appliedValues,omitempty"`      // Input values overridden by Values, in order of precedence
+	ResolvedParameters []ParameterValue    `json:"resolvedParameters,omitempty"` // Resolved values of spec.parameters, secret was masked
+	DisabledTiles      []DisabledTile      `json:"disabledTiles,omitempty"`      // Tile instances were pruned as per 'when'
+	parameters         map[string]string   // Resolved values of spec.parameters, which are referred in 'when'
+	secrets            []string            // Values of secret parameters
 }
 
 // DeploymentSpec deployment.spec
 type DeploymentSpec struct {
-	Template DeploymentTemplate `json:"template" jsonschema:"required"`
-	Summary  DeploymentSummary  `json:"summary"`
+	Parameters []DeploymentParameter `json:"parameters,omitempty"`
+	Template   DeploymentTemplate    `json:"template" jsonschema:"required"`
+	Summary    DeploymentSummary     `json:"summary"`
 }
 
 // DeploymentTemplate deployment.spec.template
@@ -105,6 +113,7 @@ type DeploymentTemplateDetail struct {
 	Manifests     TileManifest `json:"manifests,omitempty"`
 	Region        string       `json:"region,omitempty"`
 	Profile       string       `json:"profile,omitempty"`
+	When          ScalarString `json:"when,omitempty"` // Tile instance would be disabled if the expression was false
 }
 
 // Tile specification
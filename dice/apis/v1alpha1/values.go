@@ -0,0 +1,168 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
+
+// Values overrides inputs of Tile instances, which is submitted along with Deployment as other documents.
+// Order of precedence from low to high: Deployment < values of Values documents in submitted order < set of Values documents in submitted order
+type Values struct {
+	ApiVersion string                          `json:"apiVersion" yaml:"apiVersion" valid:"in(mahjong.io/v1alpha1)"`
+	Kind       string                          `json:"kind" yaml:"kind" valid:"in(Values)"`
+	Metadata   ValuesMetadata                  `json:"metadata" yaml:"metadata"`
+	Values     map[string]map[string]ValueItem `json:"values,omitempty" yaml:"values,omitempty"` // Tile instance -> input name -> value
+	Set        []string                        `json:"set,omitempty" yaml:"set,omitempty"`       // <tile instance>.<input name>=<value>, array as {a,b,c}
+	Params     map[string]string               `json:"params,omitempty" yaml:"params,omitempty"` // Parameter name -> value
+}
+
+// ValuesMetadata values.metadata
+type ValuesMetadata struct {
+	Name string `json:"name" yaml:"name"` // Name of Values, e.g. file name
+}
+
+// ValueItem is either a value or array of values
+type ValueItem struct {
+	Value   string
+	Values  []string
+	IsArray bool
+}
+
+// UnmarshalYAML keeps value as it is, e.g. 1.20 wouldn't be 1.2
+func (v *ValueItem) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&v.Values); err == nil {
+		v.IsArray = true
+		return nil
+	}
+	v.Values = nil
+	return unmarshal(&v.Value)
+}
+
+// AppliedValue records input value which was overridden by Values
+type AppliedValue struct {
+	TileInstance string   `json:"tileInstance"`
+	Input        string   `json:"input"`
+	Value        string   `json:"value,omitempty"`
+	Values       []string `json:"values,omitempty"`
+	Source       string   `json:"source"` // Source is name of Values, or 'set' if it was from set
+}
+
+func (av AppliedValue) String() string {
+	value := av.Value
+	if av.Values != nil {
+		value = "{" + strings.Join(av.Values, ",") + "}"
+	}
+	return fmt.Sprintf("%s.%s=%s (%s)", av.TileInstance, av.Input, value, av.Source)
+}
+
+// ParseSet parses <tile instance>.<input name>=<value>, value as {a,b,c} is array
+func ParseSet(set string) (string, string, ValueItem, error) {
+	kv := strings.SplitN(set, "=", 2)
+	if len(kv) != 2 {
+		return "", "", ValueItem{}, errors.New("set should be <tile instance>.<input name>=<value> : " + set)
+	}
+	key := strings.SplitN(strings.TrimSpace(kv[0]), ".", 2)
+	if len(key) != 2 || key[0] == "" || key[1] == "" {
+		return "", "", ValueItem{}, errors.New("set should be <tile instance>.<input name>=<value> : " + set)
+	}
+	value := kv[1]
+	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+		item := ValueItem{IsArray: true, Values: []string{}}
+		if inner := strings.TrimSpace(value[1 : len(value)-1]); inner != "" {
+			for _, v := range strings.Split(inner, ",") {
+				item.Values = append(item.Values, strings.TrimSpace(v))
+			}
+		}
+		return key[0], key[1], item, nil
+	}
+	return key[0], key[1], ValueItem{Value: value}, nil
+}
+
+// ApplyValues overrides inputs of Deployment as per order of precedence, and records applied values
+func (deployment *Deployment) ApplyValues(values []Values) error {
+	type override struct {
+		tileInstance string
+		input        string
+		item         ValueItem
+		source       string
+	}
+	var overrides []override
+	for i := range values {
+		if values[i].Metadata.Name == "" {
+			values[i].Metadata.Name = fmt.Sprintf("values[%d]", i)
+		}
+	}
+	for _, v := range values {
+		var tileInstances []string
+		for ti := range v.Values {
+			tileInstances = append(tileInstances, ti)
+		}
+		// Keep same order as Deployment, then the rest
+		ordered := make(map[string]bool)
+		for _, ti := range deployment.OriginalOrder {
+			if inputs, ok := v.Values[ti]; ok {
+				ordered[ti] = true
+				for _, name := range sortedKeys(inputs) {
+					overrides = append(overrides, override{ti, name, inputs[name], v.Metadata.Name})
+				}
+			}
+		}
+		for _, ti := range tileInstances {
+			if !ordered[ti] {
+				return errors.New("tile instance < " + ti + " > in values: " + v.Metadata.Name + " wasn't existed in deployment")
+			}
+		}
+	}
+	for _, v := range values {
+		for _, set := range v.Set {
+			ti, name, item, err := ParseSet(set)
+			if err != nil {
+				return err
+			}
+			overrides = append(overrides, override{ti, name, item, "set"})
+		}
+	}
+
+	for _, o := range overrides {
+		dt, ok := deployment.Spec.Template.Tiles[o.tileInstance]
+		if !ok {
+			return errors.New("tile instance < " + o.tileInstance + " > in " + o.source + " wasn't existed in deployment")
+		}
+		inputs := append([]TileInput{}, dt.Inputs...)
+		idx := -1
+		for i, input := range inputs {
+			if input.Name == o.input {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			inputs = append(inputs, TileInput{Name: o.input})
+			idx = len(inputs) - 1
+		}
+		applied := AppliedValue{TileInstance: o.tileInstance, Input: o.input, Source: o.source}
+		if o.item.IsArray {
+			inputs[idx].InputValue = ""
+			inputs[idx].InputValues = o.item.Values
+			applied.Values = o.item.Values
+		} else {
+			inputs[idx].InputValue = o.item.Value
+			inputs[idx].InputValues = nil
+			applied.Value = o.item.Value
+		}
+		dt.Inputs = inputs
+		deployment.Spec.Template.Tiles[o.tileInstance] = dt
+		deployment.AppliedValues = append(deployment.AppliedValues, applied)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]ValueItem) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package v1alpha1
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+var huWithValues = `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: eks-simple
+spec:
+  template:
+    tiles:
+      tileEks0005:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+        inputs:
+          - name: clusterName
+            inputValue: mahjong-eks-cluster
+          - name: capacity
+            inputValue: 2
+  summary:
+    description: EKS
+    outputs: []
+    notes: []
+---
+apiVersion: mahjong.io/v1alpha1
+kind: Values
+metadata:
+  name: prod.yaml
+values:
+  tileEks0005:
+    capacity: 3
+    clusterVersion: 1.20
+    capacityInstance:
+      - m5.large
+      - c5.large
+---
+apiVersion: mahjong.io/v1alpha1
+kind: Values
+set:
+  - tileEks0005.capacity=5
+  - tileEks0005.capacityInstance={r5.large}
+`
+
+func TestData_ParseValues(t *testing.T) {
+	x, _ := os.Getwd()
+	deploymentSchema = "file://" + x + "/../.././schema/deployment-schema.json"
+	d := Data(huWithValues)
+	values, err := d.ParseValues(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(values))
+	assert.Equal(t, "1.20", values[0].Values["tileEks0005"]["clusterVersion"].Value)
+
+	deployment, err := d.ParseDeployment(context.TODO())
+	assert.NoError(t, err)
+	inputs := make(map[string]TileInput)
+	for _, input := range deployment.Spec.Template.Tiles["tileEks0005"].Inputs {
+		inputs[input.Name] = input
+	}
+	assert.Equal(t, "mahjong-eks-cluster", inputs["clusterName"].InputValue)
+	assert.Equal(t, "5", inputs["capacity"].InputValue)
+	assert.Equal(t, "1.20", inputs["clusterVersion"].InputValue)
+	assert.Equal(t, []string{"r5.large"}, inputs["capacityInstance"].InputValues)
+	assert.Equal(t, 5, len(deployment.AppliedValues))
+	assert.Equal(t, "set", deployment.AppliedValues[4].Source)
+}
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		input  string
+		ti     string
+		name   string
+		item   ValueItem
+		hasErr bool
+	}{
+		{"tileEks0005.capacity=5", "tileEks0005", "capacity", ValueItem{Value: "5"}, false},
+		{"tileEks0005.tags={a, b}", "tileEks0005", "tags", ValueItem{Values: []string{"a", "b"}, IsArray: true}, false},
+		{"tileEks0005.empty=", "tileEks0005", "empty", ValueItem{}, false},
+		{"capacity=5", "", "", ValueItem{}, true},
+		{"tileEks0005.capacity", "", "", ValueItem{}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			ti, name, item, err := ParseSet(test.input)
+			assert.Equal(t, test.hasErr, err != nil)
+			assert.Equal(t, test.ti, ti)
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.item, item)
+		})
+	}
+}
+
+func TestDeployment_ApplyValues(t *testing.T) {
+	deployment := &Deployment{
+		Spec:          DeploymentSpec{Template: DeploymentTemplate{Tiles: map[string]DeploymentTemplateDetail{"tileEks0005": {}}}},
+		OriginalOrder: []string{"tileEks0005"},
+	}
+	err := deployment.ApplyValues([]Values{{Values: map[string]map[string]ValueItem{"tileNotExisted": {"capacity": {Value: "5"}}}}})
+	assert.Error(t, err)
+	err = deployment.ApplyValues([]Values{{Set: []string{"tileNotExisted.capacity=5"}}})
+	assert.Error(t, err)
+}
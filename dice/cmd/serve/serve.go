@@ -0,0 +1,157 @@
+package serve
+
+import (
+	"context"
+	"dice/engine"
+	"dice/utils"
+	"dice/web"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var Serve = &cobra.Command{
+	Use:   "serve",
+	Short: "\tServe Dice.",
+	Long: "\tServe Dice with configuration, which is merged in order: defaults, --config file, M_* environment variables and flags, " +
+		"and validated before serving, e.g.\n" +
+		"\t\tdice serve -c /etc/dice/dice.yaml\n" +
+		"\t\tdice serve --mode dev --work-home /tmp/dice --local-repo ./repo --insecure",
+	Args: cobra.NoArgs,
+	Run: func(c *cobra.Command, args []string) {
+		sc, err := Config(c)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := engine.Setup(sc); err != nil {
+			log.Fatal(err)
+		}
+		serve(sc)
+	},
+}
+
+// flags are string flags of serve, which override fields of configuration
+var flags = map[string]func(sc *utils.ServeConfig) *string{
+	"listen":           func(sc *utils.ServeConfig) *string { return &sc.Listen },
+	"log-level":        func(sc *utils.ServeConfig) *string { return &sc.LogLevel },
+	"work-home":        func(sc *utils.ServeConfig) *string { return &sc.WorkHome },
+	"mode":             func(sc *utils.ServeConfig) *string { return &sc.Mode },
+	"s3-bucket-region": func(sc *utils.ServeConfig) *string { return &sc.Repo.Region },
+	"s3-bucket":        func(sc *utils.ServeConfig) *string { return &sc.Repo.Bucket },
+	"local-repo":       func(sc *utils.ServeConfig) *string { return &sc.Repo.Local },
+}
+
+func init() {
+	Serve.Flags().StringP("config", "c", "", "Configuration file of Dice")
+	Serve.Flags().String("listen", "", "Address of Dice, default : 0.0.0.0:9090")
+	Serve.Flags().String("log-level", "", "Log level: debug, info, warning or error, default : info")
+	Serve.Flags().String("work-home", "", "The main working folder for all activities")
+	Serve.Flags().String("mode", "", "dev - looking for Tiles in local repo, prod - looking for Tiles in S3, default : prod")
+	Serve.Flags().String("s3-bucket-region", "", "Region of S3 repo on prod mode")
+	Serve.Flags().String("s3-bucket", "", "Name of S3 repo on prod mode")
+	Serve.Flags().String("local-repo", "", "Folder of local repo on dev mode")
+	Serve.Flags().Int("max-deployments", 0, "Max running deployments, 0 is unlimited")
+	Serve.Flags().Int("max-per-account", 0, "Max running deployments per AWS account/region/profile, 0 is unlimited")
+	Serve.Flags().Duration("shutdown-grace-period", 0, "How long running stages could take after SIGTERM, default : 2m")
+	Serve.Flags().Bool("insecure", false, "Serve without authentication, every caller is admin, it's only for local development")
+}
+
+// Config loads configuration file, and overrides it with M_* environment variables & given flags
+func Config(c *cobra.Command) (*utils.ServeConfig, error) {
+	file, _ := c.Flags().GetString("config")
+	sc, err := utils.LoadServeConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := sc.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	for flag, field := range flags {
+		if c.Flags().Changed(flag) {
+			*field(sc), _ = c.Flags().GetString(flag)
+		}
+	}
+	if c.Flags().Changed("max-deployments") {
+		sc.Concurrency.Deployments, _ = c.Flags().GetInt("max-deployments")
+	}
+	if c.Flags().Changed("max-per-account") {
+		sc.Concurrency.PerAccount, _ = c.Flags().GetInt("max-per-account")
+	}
+	if c.Flags().Changed("insecure") {
+		sc.Insecure, _ = c.Flags().GetBool("insecure")
+	}
+	if c.Flags().Changed("shutdown-grace-period") {
+		grace, _ := c.Flags().GetDuration("shutdown-grace-period")
+		sc.ShutdownGracePeriod = utils.Duration(grace)
+	}
+	return sc, nil
+}
+
+func serve(sc *utils.ServeConfig) {
+	srv := &http.Server{
+		Addr:    sc.Listen,
+		Handler: web.Router(context.Background()),
+	}
+	listen := srv.ListenAndServe
+	if sc.TLS.Cert != "" {
+		reloader, err := utils.NewTLSReloader(sc.TLS.Cert, sc.TLS.Key, sc.TLS.ClientCA)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Reload certificate & client CA on SIGHUP, eg: after rotation
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := reloader.Reload(); err != nil {
+					log.Errorf("Failed to reload TLS, keep using the current one : %s\n", err)
+				} else {
+					log.Info("TLS certificate was reloaded.")
+				}
+			}
+		}()
+		srv.TLSConfig = reloader.TLSConfig()
+		listen = func() error { return srv.ListenAndServeTLS("", "") }
+		log.Printf("Listening and serving HTTPS on %s, mTLS: %t\n", srv.Addr, sc.TLS.ClientCA != "")
+	} else {
+		log.Printf("Listening and serving HTTP on %s\n", srv.Addr)
+	}
+
+	// Sweep runs as per retention in background
+	gcCtx, stopGC := context.WithCancel(context.Background())
+	go engine.RunGC(gcCtx, time.Duration(sc.Retention.Interval))
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		if err := listen(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	sig := <-stop
+	stopGC()
+	grace := time.Duration(sc.ShutdownGracePeriod)
+	log.Warningf("Received %s, shutting down with grace period %s ...\n", sig, grace)
+	// Stop accepting connections, WebSocket connections were hijacked and are kept until their deployments were drained
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Errorf("Failed to shut down HTTP server : %s\n", err)
+	}
+	engine.Drain(grace)
+	// Audit entries & spans of drained deployments are flushed
+	tctx, tcancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer tcancel()
+	if engine.DiceConfig.Audit != nil {
+		engine.DiceConfig.Audit.Close(tctx)
+	}
+	if err := utils.ShutdownTracing(tctx); err != nil {
+		log.Errorf("Failed to flush spans : %s\n", err)
+	}
+	log.Info("Dice was shut down.")
+}
@@ -0,0 +1,22 @@
+package version
+
+import (
+	"dice/utils"
+	"fmt"
+	"github.com/spf13/cobra"
+	"runtime"
+)
+
+var Version = &cobra.Command{
+	Use:   "version",
+	Short: "\tPrint the version number of Dice",
+	Args:  cobra.NoArgs,
+	Run: func(c *cobra.Command, args []string) {
+		fmt.Printf("\tVersion:\t%s\n\tGo version:\t%s\n\tGit commit:\t%s\n\tBuilt:\t%s\n\tOS/Arch:\t%s/%s\n",
+			utils.ServerVersion,
+			runtime.Version(),
+			utils.GitCommit,
+			utils.Built,
+			runtime.GOOS, runtime.GOARCH)
+	},
+}
@@ -13,6 +13,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"os"
+	"regexp"
 	"sigs.k8s.io/yaml"
 	"strings"
 	"text/template"
@@ -21,6 +22,8 @@ import (
 
 type AssembleData struct {
 	Deployment *v1alpha1.Deployment
+	Lock       *v1alpha1.Lock // Lock pins resolved Tiles if it was submitted
+	DryRun     bool           // DryRun doesn't move the current run, which is protected from sweeping
 }
 
 // AssemblerCore represents a group of functions to assemble CDK App.
@@ -47,6 +50,9 @@ type AssembleCore interface {
 		profile string,
 		out *websocket.Conn) error
 
+	// VerifyLock verifies resolved Tiles against submitted lock
+	VerifyLock(ctx context.Context, out *websocket.Conn) error
+
 	// ApplyMainTs applies Ts to main CDK app
 	ApplyMainTs(ctx context.Context, aTs *Ts, out *websocket.Conn) error
 
@@ -57,46 +63,67 @@ type AssembleCore interface {
 	GenerateParallelPlan(ctx context.Context, aTs *Ts, out *websocket.Conn) error
 }
 
+// DiceConfig is configuration of the default workspace, which is set by Setup
 var DiceConfig *utils.DiceConfig
 
-func init() {
-	// Must
-	workHome, ok := os.LookupEnv("M_WORK_HOME")
-	if !ok {
-		log.Fatal("Failed to lookup M_WORK_HOME.")
-	}
-	// Must
-	mode, ok := os.LookupEnv("M_MODE")
-	if !ok {
-		// Using prod mode and pulling tiles from S3 repo.
-		log.Fatal("!!!Failed to lookup M_MODE and setup to 'prod' mode.!!!")
-		mode = "prod"
-	}
-	// Must when 'prod' mode
-	region, ok := os.LookupEnv("M_S3_BUCKET_REGION")
-	if !ok && mode == "prod" {
-		log.Fatal("Failed to lookup M_S3_BUCKET_REGION  on 'prod' mode.")
-	}
-	// Must when 'prod' mode
-	bucketName, ok := os.LookupEnv("M_S3_BUCKET")
-	if !ok && mode == "prod" {
-		log.Fatal("Failed to lookup M_S3_BUCKET  on 'prod' mode.")
-	}
-	// Must when 'dev' mode
-	localRepo, ok := os.LookupEnv("M_LOCAL_TILE_REPO")
-	if !ok && mode == "dev" {
-		log.Fatal("Failed to lookup M_LOCAL_TILE_REPO on 'dev' mode.")
-	}
-
-	DiceConfig = &utils.DiceConfig{
-		WorkHome:   workHome,
-		Region:     region,
-		BucketName: bucketName,
-		Mode:       mode,
-		LocalRepo:  localRepo,
-	}
-	c, _ := yaml.Marshal(DiceConfig)
+// ServeConfig is the validated configuration of `dice serve`, which is set by Setup
+var ServeConfig *utils.ServeConfig
+
+// Setup applies validated configuration to engine, including workspaces & tracing. It must be called before serving.
+func Setup(sc *utils.ServeConfig) error {
+	if err := sc.Validate(); err != nil {
+		return err
+	}
+	level, _ := log.ParseLevel(sc.LogLevel)
+	log.SetLevel(level)
+
+	dc, err := sc.DiceConfig()
+	if err != nil {
+		return err
+	}
+	if dc.Auth == nil {
+		log.Warning("Dice is insecure, authentication was disabled and every caller is admin.")
+	}
+	// Workspaces are optional, and all callers are in the default workspace without workspaces file
+	workspaces, err := utils.LoadWorkspaces(sc.Workspaces, dc)
+	if err != nil {
+		return fmt.Errorf("failed to load workspaces %s: %s", sc.Workspaces, err)
+	}
+	// Spans are exported as per trace exporter: otlp or stdout, tracing is disabled without it
+	exporter, err := utils.NewExporter(context.Background(), sc.Trace.Exporter, sc.Trace.Endpoint, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if exporter != nil {
+		utils.SetupTracing(exporter)
+	}
+
+	ServeConfig, DiceConfig, Workspaces = sc, dc, workspaces
+	// Deployments which were in progress when Dice exited last time are Interrupted
+	Store = &utils.FileStore{Dir: sc.StateDir()}
+	recovered, err := RecoverCheckpoints()
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoints in %s: %s", sc.StateDir(), err)
+	}
+	for _, cp := range recovered {
+		log.Warningf("Deployment %s - %s was interrupted when Dice exited, it could be resumed from %s.\n", cp.Name, cp.SID, cp.ResumeFrom)
+	}
+	// Locks left by Dice exited unexpectedly are kept, as commands of the deployment might be still running
+	for name := range Workspaces {
+		locks, err := NameLocks(name)
+		if err != nil {
+			return fmt.Errorf("failed to load locks in %s: %s", sc.StateDir(), err)
+		}
+		for _, l := range locks {
+			log.Warningf("Deployment %s in workspace %s is locked by %s since %s, force unlock it if it's stale.\n", l.Name, l.Workspace, l.SID, l.Acquired.Format(time.RFC3339))
+		}
+	}
+	c, _ := yaml.Marshal(sc)
 	log.Printf("Loaded configuration: \n%s\n", c)
+	for name, w := range Workspaces {
+		log.Printf("Loaded workspace %s: workHome=%s, mode=%s, members=%d\n", name, w.WorkHome, w.Mode, len(w.Members))
+	}
+	return nil
 }
 
 func UpdateDR(dr *DeploymentRecord, status string) {
@@ -104,8 +131,16 @@ func UpdateDR(dr *DeploymentRecord, status string) {
 	dr.Updated = time.Now()
 }
 
+// RunFolder returns folder of a run under work home, every run of a deployment has its own folder, so that logs,
+// scripts & super.ts of previous runs are kept
+func RunFolder(name string, dSid string) string {
+	return "/" + name + "/" + dSid
+}
+
 // GenerateMainApp return path where the base CDK App was generated.
-func (d *AssembleData) GenerateMainApp(ctx context.Context, out *websocket.Conn) (*ExecutionPlan, error) {
+func (d *AssembleData) GenerateMainApp(ctx context.Context, out *websocket.Conn) (_ *ExecutionPlan, err error) {
+	ctx, span := utils.StartSpan(ctx, "GenerateMainApp", "dice.deployment", d.Deployment.Metadata.Name)
+	defer func() { span.End(err) }()
 
 	dSid := ctx.Value("d-sid").(string)
 	aon := make(map[string]*TsOutput)
@@ -114,30 +149,37 @@ func (d *AssembleData) GenerateMainApp(ctx context.Context, out *websocket.Conn)
 			SID:         dSid,
 			Name:        d.Deployment.Metadata.Name,
 			Created:     time.Now(),
-			SuperFolder: "/" + d.Deployment.Metadata.Name,
+			SuperFolder: RunFolder(d.Deployment.Metadata.Name, dSid),
 			Status:      Created.DSString(),
+			Workspace:   CtxWorkspace(ctx),
 		},
 		AllTilesN:    make(map[string]*v1alpha1.Tile),
 		TsLibsMap:    make(map[string]TsLib),
 		TsStacksMapN: make(map[string]*TsStack),
 		AllOutputsN:  &aon,
+		secrets:      d.Deployment.Secrets(),
 	}
 
 	// 1. Caching Ts
 	// Cached here with point, so can be used in following procedures
-	AllTs[dSid] = *aTs
+	StoreTs(*aTs)
 
 	// 2. Loading Super from s3 & unzip
 	UpdateDR(aTs.DR, Progress.DSString())
 	var override = make(map[string]*v1alpha1.TileInputOverride) //TileName->TileInputOverride
 	var ep *ExecutionPlan
 	SR(out, []byte("Loading Super ... from RePO."))
-	_, err := DiceConfig.LoadSuper(aTs.DR.SuperFolder)
+	_, err = aTs.DR.Config().LoadSuper(aTs.DR.SuperFolder)
 	if err != nil {
 		UpdateDR(aTs.DR, Interrupted.DSString())
 		return ep, err
 	}
 	SR(out, []byte("Loading Super ... from RePO with success."))
+	if !d.DryRun {
+		if err := aTs.DR.Config().LinkCurrent(aTs.DR.SuperFolder); err != nil {
+			log.Warningf("Failed to link %s as the current run : %s\n", aTs.DR.SuperFolder, err)
+		}
+	}
 
 	// 3. Loading Tiles from s3 & unzip
 	if err := d.ProcessTiles(ctx, aTs, override, out); err != nil {
@@ -148,6 +190,10 @@ func (d *AssembleData) GenerateMainApp(ctx context.Context, out *websocket.Conn)
 		UpdateDR(aTs.DR, Interrupted.DSString())
 		return ep, errors.New("invalid deployment without parsed Tiles")
 	}
+	if err := d.VerifyLock(ctx, out); err != nil {
+		UpdateDR(aTs.DR, Interrupted.DSString())
+		return ep, err
+	}
 
 	// 4. Generate super.ts
 	if err := d.ApplyMainTs(ctx, aTs, out); err != nil {
@@ -273,14 +319,24 @@ func (d *AssembleData) PullTile(ctx context.Context,
 	override map[string]*v1alpha1.TileInputOverride,
 	region string,
 	profile string,
-	out *websocket.Conn) (string, error) {
+	out *websocket.Conn) (_ string, err error) {
+	ctx, span := utils.StartSpan(ctx, "PullTile",
+		"dice.tile", tileName,
+		"dice.tile.version", version,
+		"dice.tile.instance", tileInstance)
+	defer func() { span.End(err) }()
 
 	dSid := ctx.Value("d-sid").(string)
 	ti := generateTileInstance(tileInstance, tileName, rootTileInstance)
-	rStack := "Stack" + ti
+	if err := checkTsIdentifier(ti, aTs.TsStacksMapN); err != nil {
+		return ti, err
+	}
+	rStack := "Stack" + tsIdentifier(ti)
 
 	// Pre-Process 1: Loading Tile from s3 & unzip
-	tileSpecFile, err := DiceConfig.LoadTile(tileName, version, aTs.DR.SuperFolder)
+	pullStarted := time.Now()
+	tileSpecFile, digest, err := aTs.DR.Config().LoadTile(tileName, version, aTs.DR.SuperFolder)
+	pulled := time.Since(pullStarted)
 	if err != nil {
 		SRf(out, "Failed to pulling Tile < %s - %s > ... from RePO\n", tileName, version)
 		return ti, err
@@ -300,6 +356,9 @@ func (d *AssembleData) PullTile(ctx context.Context,
 		return ti, err
 	}
 
+	TilePullDuration.WithLabelValues(tileName, parsedTile.Metadata.Category).Observe(pulled.Seconds())
+	span.SetAttributes("dice.tile.category", parsedTile.Metadata.Category, "dice.tile.digest", digest)
+
 	// Pre-Process 3: Caching TilesGrid, which presents relation between Tiles
 	tg := TilesGrid{
 		TileInstance:        ti,
@@ -310,6 +369,8 @@ func (d *AssembleData) PullTile(ctx context.Context,
 		RootTileInstance:    rootTileInstance,
 		TileCategory:        parsedTile.Metadata.Category,
 		Status:              Created.DSString(),
+		TileDigest:          digest,
+		Generated:           tileInstance == "",
 	}
 	if parsedTile.Spec.Dependencies == nil && tileInstance == "" {
 		tg.ParentTileInstances = []string{"root"}
@@ -512,6 +573,10 @@ func (d *AssembleData) PullTile(ctx context.Context,
 	////
 
 	// Step 9. Store import Stacks && avoid repeated one
+	// Using default AWS profile of workspace if it wasn't given
+	if profile == "" {
+		profile = aTs.DR.Config().Profile
+	}
 	ts := &TsStack{
 		TileInstance:      ti,
 		TileName:          parsedTile.Metadata.Name,
@@ -582,9 +647,11 @@ func array2String(array []string, inputType string) string {
 }
 
 // ApplyMainTs apply values with super.ts template
-func (d *AssembleData) ApplyMainTs(ctx context.Context, aTs *Ts, out *websocket.Conn) error {
+func (d *AssembleData) ApplyMainTs(ctx context.Context, aTs *Ts, out *websocket.Conn) (err error) {
+	ctx, span := utils.StartSpan(ctx, "ApplyMainTs")
+	defer func() { span.End(err) }()
 	dSid := ctx.Value("d-sid").(string)
-	superFile := DiceConfig.WorkHome + aTs.DR.SuperFolder + "/bin/super.ts"
+	superFile := aTs.DR.Config().WorkHome + aTs.DR.SuperFolder + "/bin/super.ts"
 	SR(out, []byte("Generating main.ts for Super ..."))
 
 	tp, _ := template.ParseFiles(superFile)
@@ -661,7 +728,7 @@ func (d *AssembleData) GenerateExecutePlan(ctx context.Context, aTs *Ts, out *we
 	}
 
 	for _, ts := range aTs.TsStacks {
-		workHome := DiceConfig.WorkHome + aTs.DR.SuperFolder
+		workHome := aTs.DR.Config().WorkHome + aTs.DR.SuperFolder
 		stage := ExecutionStage{
 			Name:          ts.TileInstance,
 			Kind:          ts.TileCategory,
@@ -678,8 +745,8 @@ func (d *AssembleData) GenerateExecutePlan(ctx context.Context, aTs *Ts, out *we
 		} else {
 			stage.Kind = CDK.SKString()
 		}
-		stage.InjectedEnv = append(stage.InjectedEnv, "export WORK_HOME="+DiceConfig.WorkHome+aTs.DR.SuperFolder)
-		stage.InjectedEnv = append(stage.InjectedEnv, "export TILE_HOME="+DiceConfig.WorkHome+aTs.DR.SuperFolder+ts.TileFolder)
+		stage.InjectedEnv = append(stage.InjectedEnv, "export WORK_HOME="+aTs.DR.Config().WorkHome+aTs.DR.SuperFolder)
+		stage.InjectedEnv = append(stage.InjectedEnv, "export TILE_HOME="+aTs.DR.Config().WorkHome+aTs.DR.SuperFolder+ts.TileFolder)
 		if ts.Region != "" {
 			stage.InjectedEnv = append(stage.InjectedEnv, "export AWS_DEFAULT_REGION="+ts.Region)
 		}
@@ -701,7 +768,7 @@ func (d *AssembleData) GenerateExecutePlan(ctx context.Context, aTs *Ts, out *we
 				}
 
 				// Process different manifests
-				prefix := DiceConfig.WorkHome + aTs.DR.SuperFolder + ts.TileFolder + "/lib/"
+				prefix := aTs.DR.Config().WorkHome + aTs.DR.SuperFolder + ts.TileFolder + "/lib/"
 				switch ts.TsManifests.ManifestType {
 				case v1alpha1.K8s.MTString():
 
@@ -737,7 +804,7 @@ func (d *AssembleData) GenerateExecutePlan(ctx context.Context, aTs *Ts, out *we
 			}
 
 			// Commands & output values to output.log
-			fileName := DiceConfig.WorkHome + aTs.DR.SuperFolder + "/" + stage.Name + "-output.log"
+			fileName := aTs.DR.Config().WorkHome + aTs.DR.SuperFolder + "/" + stage.Name + "-output.log"
 			//Sleep 5 seconds to waiting pod's ready
 			stage.Commands = append(stage.Commands, "sleep 10")
 			if tile, ok := aTs.AllTilesN[ts.TileInstance]; ok {
@@ -774,7 +841,7 @@ func (d *AssembleData) GenerateExecutePlan(ctx context.Context, aTs *Ts, out *we
 		}
 
 		dSid := ctx.Value("d-sid").(string)
-		if at, ok := AllTs[dSid]; ok {
+		if at, ok := LoadTs(dSid); ok {
 			if tile, ok := at.AllTilesN[ts.TileInstance]; ok {
 				// Inject Global environment variables
 				for _, e := range tile.Spec.Global.Env {
@@ -872,6 +939,26 @@ func ToParallelFlow(p *ExecutionPlan) []string {
 	return flows
 }
 
+var tsIdentifierRe = regexp.MustCompile(`[^[:alnum:]]+([[:alnum:]]?)`)
+
+// tsIdentifier converts Tile instance to be part of TypeScript identifier & stack name, eg: tileEks-ap-southeast-1 -> tileEksApSoutheast1
+func tsIdentifier(tileInstance string) string {
+	return tsIdentifierRe.ReplaceAllStringFunc(tileInstance, func(s string) string {
+		return strings.ToUpper(tsIdentifierRe.FindStringSubmatch(s)[1])
+	})
+}
+
+// checkTsIdentifier makes sure no other Tile instance has the same identifier, otherwise their stacks would clash, eg: tileEks-a-b & tileEksAB
+func checkTsIdentifier(tileInstance string, stacks map[string]*TsStack) error {
+	id := tsIdentifier(tileInstance)
+	for other := range stacks {
+		if other != tileInstance && tsIdentifier(other) == id {
+			return fmt.Errorf("Tile instances %s and %s have the same stack name %s, rename one of them", other, tileInstance, id)
+		}
+	}
+	return nil
+}
+
 func generateTileInstance(tileInstance string, tileName string, rootTileInstance string) string {
 	if tileInstance == "" {
 		return fmt.Sprintf("%s%s%s", tileName, rootTileInstance, "Generated")
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"container/list"
+	"context"
+	"dice/apis/v1alpha1"
+	"dice/utils"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wsClient returns a connection to a WebSocket server which discards messages
+func wsClient(t *testing.T) *websocket.Conn {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGenerateMainApp_RunFolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assembler")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dc := DiceConfig
+	DiceConfig = &utils.DiceConfig{Mode: "dev", WorkHome: filepath.Join(dir, "work"), LocalRepo: filepath.Join(dir, "repo")}
+	defer func() { DiceConfig = dc }()
+	assert.NoError(t, os.MkdirAll(filepath.Join(DiceConfig.LocalRepo, "super", "bin"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(DiceConfig.LocalRepo, "super", "bin", "super.ts"), []byte("// super"), 0644))
+
+	out := wsClient(t)
+
+	// Every run has its own folder, and the latest one is linked as current
+	assemble := AssembleData{Deployment: &v1alpha1.Deployment{Metadata: v1alpha1.Metadata{Name: "eks-simple"}}}
+	for _, dSid := range []string{"run-folder-sid-1", "run-folder-sid-2"} {
+		ctx := context.WithValue(context.Background(), "d-sid", dSid)
+		_, err := assemble.GenerateMainApp(ctx, out)
+		assert.EqualError(t, err, "invalid deployment without parsed Tiles")
+		defer DeleteTs(dSid)
+		ts, ok := LoadTs(dSid)
+		assert.True(t, ok)
+		assert.Equal(t, RunFolder("eks-simple", dSid), ts.DR.SuperFolder)
+		assert.FileExists(t, filepath.Join(DiceConfig.WorkHome, "eks-simple", dSid, "bin", "super.ts"))
+	}
+	// Dry run has its own folder, but it isn't the current run
+	dryRun := AssembleData{Deployment: assemble.Deployment, DryRun: true}
+	_, err = dryRun.GenerateMainApp(context.WithValue(context.Background(), "d-sid", "run-folder-sid-3"), out)
+	assert.Error(t, err)
+	defer DeleteTs("run-folder-sid-3")
+	assert.FileExists(t, filepath.Join(DiceConfig.WorkHome, "eks-simple", "run-folder-sid-3", "bin", "super.ts"))
+	current, err := os.Readlink(filepath.Join(DiceConfig.WorkHome, "eks-simple", utils.CurrentRun))
+	assert.NoError(t, err)
+	assert.Equal(t, "run-folder-sid-2", current)
+
+	// Summary is written into folder of the run
+	ep := &ExecutionPlan{Name: "eks-simple", Plan: list.New(), OriginDeployment: &v1alpha1.Deployment{}}
+	ep.OriginDeployment.Spec.Summary.Description = "EKS"
+	assert.NoError(t, ep.GenerateSummary(context.WithValue(context.Background(), "d-sid", "run-folder-sid-1"), out))
+	buf, err := ioutil.ReadFile(filepath.Join(DiceConfig.WorkHome, "eks-simple", "run-folder-sid-1", "output-summary.txt"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), "EKS")
+	assert.NoFileExists(t, filepath.Join(DiceConfig.WorkHome, "eks-simpleoutput-summary.txt"))
+}
+
+func TestTsIdentifier(t *testing.T) {
+	var tests = []struct {
+		tileInstance string
+		expected     string
+	}{
+		{"tileEks", "tileEks"},
+		{"tileEks-ap-southeast-1", "tileEksApSoutheast1"},
+		{"tileEks-a-b", "tileEksAB"},
+		{"tileEks_us.west--2", "tileEksUsWest2"},
+		{"tileEks-", "tileEks"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, tsIdentifier(test.tileInstance))
+	}
+
+	stacks := map[string]*TsStack{"tileEks-a-b": {TileInstance: "tileEks-a-b"}}
+	assert.NoError(t, checkTsIdentifier("tileEks-a-b", stacks))
+	assert.NoError(t, checkTsIdentifier("tileEks-a-c", stacks))
+	assert.Error(t, checkTsIdentifier("tileEksAB", stacks))
+}
@@ -120,10 +120,17 @@ func (ep *ExecutionPlan) ExecutePlan(ctx context.Context, dryRun bool, out *webs
 
 func (ep *ExecutionPlan) RunPlan(ctx context.Context, dryRun bool, wg *sync.WaitGroup, out *websocket.Conn) error {
 	dSid := ctx.Value("d-sid").(string)
-	if aTs, ok := AllTs[dSid]; ok {
+	if wg != nil {
+		defer wg.Done()
+	}
+	if aTs, ok := LoadTs(dSid); ok {
 		for e := ep.Plan.Back(); e != nil; e = e.Prev() {
 			stage := e.Value.(*ExecutionStage)
 			for {
+				// Stop before the next stage if Dice is shutting down, so that it could be resumed from here
+				if Draining() {
+					return ErrShuttingDown
+				}
 				if dependency, ok, err := IsDependenciesDone(dSid, stage.Name); ok {
 					break
 				} else {
@@ -138,70 +145,90 @@ func (ep *ExecutionPlan) RunPlan(ctx context.Context, dryRun bool, wg *sync.Wait
 
 			ep.CurrentStage = stage
 			setStatus(dSid, stage.Name, Progress.DSString())
+			started := time.Now()
+			sctx, span := startStageSpan(ctx, dSid, stage)
 
 			// 1. Wrap commands into a shell script
-			cmd, err := ep.CommandWrapperExecutor(ctx, dryRun, out)
+			step, stepSpan := utils.StartSpan(sctx, "preparation")
+			cmd, err := ep.CommandWrapperExecutor(step, dryRun, out)
+			stepSpan.End(err)
 			if err != nil {
-				setStatus(dSid, stage.Name, Interrupted.DSString())
+				finishStage(dSid, stage, Interrupted.DSString(), started)
+				span.End(err)
 				return err
 			}
 			//
 
 			// 2. Execute wrapped script
-			if err := ep.CommandExecutor(ctx, dryRun, []byte(cmd), out); err != nil {
-				setStatus(dSid, stage.Name, Interrupted.DSString())
+			step, stepSpan = utils.StartSpan(sctx, "commands")
+			step, _ = withSteps(step, stage.Preparation, stage.Commands)
+			err = ep.CommandExecutor(step, dryRun, []byte(cmd), out)
+			stepSpan.End(err)
+			if err != nil {
+				finishStage(dSid, stage, Interrupted.DSString(), started)
+				span.End(err)
 				return err
 			}
 			//
 
 			// 3.Extract output values & caching results
-			buf, err := ioutil.ReadFile(DiceConfig.WorkHome + aTs.DR.SuperFolder + "/" + stage.Name + "-output.log")
-			if err != nil {
-				setStatus(dSid, stage.Name, Interrupted.DSString())
-				return err
+			step, stepSpan = utils.StartSpan(sctx, "output")
+			buf, err := ioutil.ReadFile(aTs.DR.Config().WorkHome + aTs.DR.SuperFolder + "/" + stage.Name + "-output.log")
+			if err == nil {
+				err = ep.ExtractValue(step, buf, out)
 			}
-			err = ep.ExtractValue(ctx, buf, out)
+			stepSpan.End(err)
 			if err != nil {
-				setStatus(dSid, stage.Name, Interrupted.DSString())
+				finishStage(dSid, stage, Interrupted.DSString(), started)
+				span.End(err)
 				return err
 			}
 			//
 
 			// 4. Post run with commands
 			if ep.CurrentStage.PostRunCommands != nil {
-				cmd, err := ep.PostRun(ctx, dryRun, out)
-				if err != nil {
-					setStatus(dSid, stage.Name, Interrupted.DSString())
-					return err
+				step, stepSpan = utils.StartSpan(sctx, "postRun")
+				cmd, err := ep.PostRun(step, dryRun, out)
+				if err == nil {
+					step, _ = withSteps(step, stage.PostRunCommands)
+					err = ep.CommandExecutor(step, dryRun, []byte(cmd), out)
 				}
-				err = ep.CommandExecutor(ctx, dryRun, []byte(cmd), out)
+				stepSpan.End(err)
 				if err != nil {
-					setStatus(dSid, stage.Name, Interrupted.DSString())
+					finishStage(dSid, stage, Interrupted.DSString(), started)
+					span.End(err)
 					return err
 				}
 			}
 			//
-			setStatus(dSid, stage.Name, Done.DSString())
+			finishStage(dSid, stage, Done.DSString(), started)
+			span.End(nil)
 
 		}
 	}
-	if wg!=nil {wg.Done()}
 	return nil
 }
 
 func (ep *ExecutionPlan) ExecuteParallelPlan(ctx context.Context, dryRun bool, out *websocket.Conn) error {
 	// 1. Run plan by parallel
 	wg := new(sync.WaitGroup)
+	errs := make(chan error, len(ep.ParallelPlan))
 	for _, pp := range ep.ParallelPlan {
 		sep := &ExecutionPlan{
 			Name: "parallel-plan",
 			Plan: pp,
 		}
 
-		go sep.RunPlan(ctx, dryRun, wg, out)
 		wg.Add(1)
+		go func() { errs <- sep.RunPlan(ctx, dryRun, wg, out) }()
 	}
 	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
 
 	// 2. GENERATE REPORT
 	if ep.Plan.Len() > 0 { //avoid empty plan
@@ -214,9 +241,11 @@ func (ep *ExecutionPlan) ExecuteParallelPlan(ctx context.Context, dryRun bool, o
 	return nil
 }
 
-// GenerateSummary generate summary after running execution plan.
+// GenerateSummary generate summary after running execution plan into folder of the run.
 func (ep *ExecutionPlan) GenerateSummary(ctx context.Context, out *websocket.Conn) error {
-	file, err := os.OpenFile(DiceConfig.WorkHome+"/"+ep.Name+"output-summary.txt",
+	dSid := ctx.Value("d-sid").(string)
+	aTs, _ := LoadTs(dSid)
+	file, err := os.OpenFile(aTs.DR.Config().WorkHome+aTs.DR.SuperFolder+"/output-summary.txt",
 		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		SRf(out, "Failed to write summary, %s\n", err)
@@ -226,19 +255,18 @@ func (ep *ExecutionPlan) GenerateSummary(ctx context.Context, out *websocket.Con
 
 	SRF(out, file, []byte("\n"))
 	SRF(out, file, []byte("\n\n============================Summary====================================\n\n"))
-	dSid := ctx.Value("d-sid").(string)
-	kv := ep.ExtractAllEnv()
-	if ep.OriginDeployment.Spec.Summary.Description != "" {
+	summary := ep.Summary(dSid)
+	if summary.Description != "" {
 
-		SRF(out, file, []byte(ep.ReplaceAll(ep.OriginDeployment.Spec.Summary.Description, dSid, kv)+"\n"))
+		SRF(out, file, []byte(summary.Description+"\n"))
 	}
 	SRF(out, file, []byte("\n"))
-	for _, ot := range ep.OriginDeployment.Spec.Summary.Outputs {
-		SRF(out, file, []byte(fmt.Sprintf("%s = %s\n", ot.Name, ep.ReplaceAll(ot.Value, dSid, kv))))
+	for _, ot := range summary.Outputs {
+		SRF(out, file, []byte(fmt.Sprintf("%s = %s\n", ot.Name, ot.Value)))
 	}
 	SRF(out, file, []byte("\n"))
-	for _, n := range ep.OriginDeployment.Spec.Summary.Notes {
-		SRF(out, file, []byte(ep.ReplaceAll(n, dSid, kv)+"\n"))
+	for _, n := range summary.Notes {
+		SRF(out, file, []byte(n+"\n"))
 	}
 	SRF(out, file, []byte("\n\n=======================================================================\n"))
 	return nil
@@ -271,7 +299,7 @@ func (ep *ExecutionPlan) ReplaceAll(str string, dSid string, kv map[string]strin
 func (ep *ExecutionPlan) ReplaceAllValueRef(str string, dSid string, ti string) string {
 	max := strings.Count(str, "$")
 	for {
-		re := regexp.MustCompile(`.*(\$\([[:alnum:]]*\.[[:alnum:]]*\.[[:alnum:]]*\)).*`)
+		re := regexp.MustCompile(`.*(\$\([[:alnum:]_-]*\.[[:alnum:]]*\.[[:alnum:]]*\)).*`)
 		s := re.FindStringSubmatch(str)
 		//
 		if len(s) == 2 {
@@ -306,11 +334,11 @@ func (ep *ExecutionPlan) ReplaceAllEnv(str string, allEnv map[string]string) str
 // CommandExecutor exec command and return output.
 func (ep *ExecutionPlan) CommandExecutor(ctx context.Context, dryRun bool, cmdTxt []byte, out *websocket.Conn) error {
 	dSid := ctx.Value("d-sid").(string)
-	aTs := AllTs[dSid]
+	aTs, _ := LoadTs(dSid)
 	var stageLog *log.Logger
 	SR(out, []byte("Initializing stage log file ..."))
 	stageLog = log.New()
-	fileName := DiceConfig.WorkHome + aTs.DR.SuperFolder + "/" + ep.CurrentStage.Name + "-output.log"
+	fileName := aTs.DR.Config().WorkHome + aTs.DR.SuperFolder + "/" + ep.CurrentStage.Name + "-output.log"
 	logFile, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		SRf(out, "Failed to save stage log : %s\n", err)
@@ -328,7 +356,7 @@ func (ep *ExecutionPlan) CommandExecutor(ctx context.Context, dryRun bool, cmdTx
 	if !dryRun {
 		return ep.LinuxCommandExecutor(ctx, cmdTxt, stageLog, out)
 	} else {
-		testData, err := DiceConfig.LoadTestOutput(ep.CurrentStage.TileName, aTs.DR.SuperFolder)
+		testData, err := aTs.DR.Config().LoadTestOutput(ep.CurrentStage.TileName, aTs.DR.SuperFolder)
 		if err != nil {
 			log.Printf("No testing output for %s\n", ep.CurrentStage.TileName)
 		} else {
@@ -342,6 +370,12 @@ func (ep *ExecutionPlan) LinuxCommandExecutor(ctx context.Context, cmdTxt []byte
 	ct := strings.TrimSpace(string(cmdTxt))
 	cts := strings.Split(ct, " ")
 	cmd := exec.Command(cts[0], cts[1:]...)
+	// Commands are in their own process group, so that children such as cdk could be killed together
+	setProcessGroup(cmd)
+	// Scripts & tools could join the trace of stage
+	if env := utils.TraceEnv(ctx); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	stdoutIn, _ := cmd.StdoutPipe()
 	stderrIn, _ := cmd.StderrPipe()
@@ -357,16 +391,22 @@ func (ep *ExecutionPlan) LinuxCommandExecutor(ctx context.Context, cmdTxt []byte
 	wg.Add(1)
 	go ep.WsTail(ctx, stderrIn, stageLog, wg, out)
 	wg.Add(1)
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
 		select {
 		case <-ctx.Done():
-			err := cmd.Process.Kill()
+			err := killProcessGroup(cmd)
 			log.Printf("halted cmd with %s\n", err)
+		case <-done:
 		}
 	}()
 
 	err = cmd.Wait()
 	wg.Wait()
+	if st := ctxSteps(ctx); st != nil {
+		st.End(err)
+	}
 
 	if err != nil {
 		SRf(out, "cmd.Run() failed with %s\n", err)
@@ -411,7 +451,7 @@ set -xe
 echo $?
 `
 	//Inject kube.config if need to
-	if at, ok := AllTs[dSid]; ok {
+	if at, ok := LoadTs(dSid); ok {
 		// Looking for initial kube.config. For EKS, require clusterName, masterRoleARN ; For others, not implementing.
 		if tile, ok := at.AllTilesN[ep.CurrentStage.Name]; ok {
 			if tile.Spec.Manifests.ManifestType != "" || tile.Metadata.DependentOnVendorService == v1alpha1.EKS.VSString() {
@@ -447,8 +487,8 @@ echo $?
 					fmt.Sprintf("aws eks update-kubeconfig --name %s --role-arn %s --kubeconfig %s\nexport KUBECONFIG=%s",
 						clusterName,
 						masterRoleARN,
-						DiceConfig.WorkHome+at.DR.SuperFolder+at.TsStacksMapN[tile.TileInstance].TileFolder+"/kube.config",
-						DiceConfig.WorkHome+at.DR.SuperFolder+at.TsStacksMapN[tile.TileInstance].TileFolder+"/kube.config",
+						at.DR.Config().WorkHome+at.DR.SuperFolder+at.TsStacksMapN[tile.TileInstance].TileFolder+"/kube.config",
+						at.DR.Config().WorkHome+at.DR.SuperFolder+at.TsStacksMapN[tile.TileInstance].TileFolder+"/kube.config",
 					))
 				tContent = tContent4K8s
 			}
@@ -576,6 +616,9 @@ func (ep *ExecutionPlan) WsTail(ctx context.Context, reader io.ReadCloser, stage
 		if stageLog != nil {
 			stageLog.Printf("%s", buf)
 		}
+		if st := ctxSteps(ctx); st != nil {
+			st.Line(buf)
+		}
 		SR(out, buf)
 	}
 	if wg != nil {
@@ -587,7 +630,7 @@ func (ep *ExecutionPlan) WsTail(ctx context.Context, reader io.ReadCloser, stage
 func (ep *ExecutionPlan) ExtractValue(ctx context.Context, buf []byte, out *websocket.Conn) error {
 	dSid := ctx.Value(`d-sid`).(string)
 	allEnv := ep.ExtractAllEnv()
-	if ts, ok := AllTs[dSid]; ok {
+	if ts, ok := LoadTs(dSid); ok {
 		tileInstance := ep.CurrentStage.Name
 		var tileCategory string
 		//var vendorService string
@@ -739,7 +782,7 @@ echo $?
 `
 	// Injected output values of current Tile as env
 	dSid := ctx.Value(`d-sid`).(string)
-	if at, ok := AllTs[dSid]; ok {
+	if at, ok := LoadTs(dSid); ok {
 		if tile, ok := at.AllTilesN[stage.Name]; ok {
 			if to, ok := (*at.AllOutputsN)[tile.TileInstance]; ok {
 				for k, v := range *to.TsOutputs {
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"dice/apis/v1alpha1"
+	"dice/utils"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+// checkpointKind is kind of checkpoints in Store
+const checkpointKind = "checkpoints"
+
+// Checkpoint is persisted state of a deployment, which tells where an interrupted deployment could be resumed from
+type Checkpoint struct {
+	SID        string
+	Name       string
+	Workspace  string
+	Folder     string `json:",omitempty"` // Folder of the run under work home
+	Status     string
+	Error      string `json:",omitempty"`
+	DryRun     bool
+	Parallel   bool
+	Submitted  string                       `json:",omitempty"` // Submitted is the deployment as it was submitted, values of secret parameters are masked
+	Digest     string                       // Digest is sha256 of the deployment as it was submitted
+	Stages     []StageCheckpoint            // Stages are in executable order
+	ResumeFrom string                       `json:",omitempty"` // ResumeFrom is the first Tile instance which isn't Done
+	Outputs    map[string]map[string]string `json:",omitempty"` // Outputs are values of Tile instances which were Done
+	Created    time.Time
+	Updated    time.Time
+}
+
+// StageCheckpoint is status of a Tile instance
+type StageCheckpoint struct {
+	TileInstance string
+	TileName     string
+	TileVersion  string
+	Status       string
+}
+
+// NewCheckpoint returns checkpoint of the deployment, stages & outputs are from in memory state
+func NewCheckpoint(r *Run, result error) *Checkpoint {
+	cp := &Checkpoint{
+		SID:       r.SID,
+		Name:      r.Name,
+		Workspace: r.Workspace,
+		Status:    Progress.DSString(),
+		DryRun:    r.DryRun,
+		Parallel:  r.Parallel,
+		Digest:    utils.Digest(r.Submitted),
+		Updated:   time.Now(),
+	}
+	submitted := v1alpha1.Data(r.Submitted)
+	if masked, err := submitted.Masked(r.Secrets); err == nil {
+		cp.Submitted = string(masked)
+	} else {
+		log.Errorf("Failed to mask the deployment %s as submitted : %s", r.SID, err)
+	}
+	if ts, ok := LoadTs(r.SID); ok && ts.DR != nil {
+		cp.Name, cp.Status, cp.Created, cp.Folder = ts.DR.Name, ts.DR.Status, ts.DR.Created, ts.DR.SuperFolder
+	}
+	if result != nil {
+		cp.Status, cp.Error = Interrupted.DSString(), result.Error()
+	}
+	for _, tg := range SortedTilesGrid(r.SID) {
+		cp.Stages = append(cp.Stages, StageCheckpoint{TileInstance: tg.TileInstance, TileName: tg.TileName, TileVersion: tg.TileVersion, Status: tg.Status})
+		if tg.Status != Done.DSString() && cp.ResumeFrom == "" {
+			cp.ResumeFrom = tg.TileInstance
+		}
+	}
+	if ts, ok := LoadTs(r.SID); ok && ts.AllOutputsN != nil {
+		for instance, o := range *ts.AllOutputsN {
+			if o.TsOutputs == nil || !isDone(r.SID, instance) {
+				continue
+			}
+			values := make(map[string]string)
+			for name, detail := range *o.TsOutputs {
+				values[name] = detail.OutputValue
+			}
+			if cp.Outputs == nil {
+				cp.Outputs = make(map[string]map[string]string)
+			}
+			cp.Outputs[instance] = values
+		}
+	}
+	return cp
+}
+
+func isDone(dSid string, tileInstance string) bool {
+	if tilesGrid, ok := AllTilesGrids[dSid]; ok {
+		if tg, ok := (*tilesGrid)[tileInstance]; ok {
+			return tg.Status == Done.DSString()
+		}
+	}
+	return false
+}
+
+// SaveCheckpoint persists checkpoint of the deployment
+func SaveCheckpoint(r *Run, result error) error {
+	if Store == nil {
+		return nil
+	}
+	return Store.Put(checkpointKind, r.SID, NewCheckpoint(r, result))
+}
+
+// Checkpoints returns checkpoints in the workspace, the latest one first
+func Checkpoints(workspace string) ([]Checkpoint, error) {
+	cps := []Checkpoint{}
+	if Store == nil {
+		return cps, nil
+	}
+	keys, err := Store.Keys(checkpointKind)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		var cp Checkpoint
+		if ok, err := Store.Get(checkpointKind, k, &cp); err != nil {
+			return nil, err
+		} else if ok && cp.Workspace == workspace {
+			cps = append(cps, cp)
+		}
+	}
+	sort.SliceStable(cps, func(i, j int) bool {
+		return cps[i].Updated.After(cps[j].Updated)
+	})
+	return cps, nil
+}
+
+// RecoverCheckpoints marks deployments which were in progress when Dice exited as Interrupted, and returns them
+func RecoverCheckpoints() ([]Checkpoint, error) {
+	var recovered []Checkpoint
+	keys, err := Store.Keys(checkpointKind)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		var cp Checkpoint
+		if ok, err := Store.Get(checkpointKind, k, &cp); err != nil || !ok {
+			continue
+		}
+		if cp.Status == Progress.DSString() || cp.Status == Created.DSString() {
+			cp.Status, cp.Error, cp.Updated = Interrupted.DSString(), "Dice exited while the deployment was in progress", time.Now()
+			if err := Store.Put(checkpointKind, k, &cp); err != nil {
+				return nil, err
+			}
+			recovered = append(recovered, cp)
+		}
+	}
+	return recovered, nil
+}
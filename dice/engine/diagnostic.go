@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"github.com/pkg/errors"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DiagnosticTimeout is the longest time of a diagnostic command
+var DiagnosticTimeout = 30 * time.Second
+
+// maxDiagnosticOutput is the limit of returned output, the rest would be truncated
+const maxDiagnosticOutput = 1 << 20
+
+// diagnosticVerbs are allowed kubectl verbs, which are read only
+var diagnosticVerbs = map[string]bool{"get": true, "describe": true}
+
+// diagnosticOutputs are allowed output formats of kubectl get
+var diagnosticOutputs = map[string]bool{"": true, "wide": true, "yaml": true, "json": true, "name": true}
+
+// diagnosticDeniedResources are never exposed through diagnostics
+var diagnosticDeniedResources = map[string]bool{"secret": true, "secrets": true}
+
+// diagnosticArgRe limits resource, name, namespace & selector, so that no flag can be injected
+var diagnosticArgRe = regexp.MustCompile(`^[[:alnum:]][[:alnum:]._/=,!-]*$`)
+
+// DiagnosticRequest is an allowlisted kubectl command, such as: kubectl get pods -n default
+type DiagnosticRequest struct {
+	TileInstance string `json:"tileInstance,omitempty"` // TileInstance provides kube.config, the first one with kube.config would be chosen if it's empty
+	Verb         string `json:"verb"`                   // Verb is get or describe
+	Resource     string `json:"resource"`               // Resource is type of resource, such as pods or deployment/nginx
+	Name         string `json:"name,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Selector     string `json:"selector,omitempty"` // Selector is label selector
+	Output       string `json:"output,omitempty"`   // Output is format of get: wide, yaml, json or name
+}
+
+// DiagnosticResult is output of a diagnostic command
+type DiagnosticResult struct {
+	SID          string   `json:"sid"`
+	TileInstance string   `json:"tileInstance"`
+	Command      []string `json:"command"`
+	Output       string   `json:"output"`
+	Truncated    bool     `json:"truncated,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// Args validates the request and returns arguments of kubectl, without kube.config
+func (dr *DiagnosticRequest) Args() ([]string, error) {
+	if !diagnosticVerbs[dr.Verb] {
+		return nil, errors.Errorf("verb %q isn't allowed, only get & describe are supported", dr.Verb)
+	}
+	if dr.Resource == "" {
+		return nil, errors.New("resource is required")
+	}
+	// Resource could be a list, such as pods,secrets or pods/nginx,secrets/token
+	for _, r := range strings.Split(dr.Resource, ",") {
+		kind := strings.ToLower(strings.SplitN(strings.SplitN(r, "/", 2)[0], ".", 2)[0])
+		if diagnosticDeniedResources[kind] {
+			return nil, errors.Errorf("resource %q isn't allowed", dr.Resource)
+		}
+	}
+	if !diagnosticOutputs[dr.Output] || (dr.Output != "" && dr.Verb != "get") {
+		return nil, errors.Errorf("output %q isn't allowed", dr.Output)
+	}
+	args := []string{dr.Verb}
+	for _, a := range []struct {
+		flag  string
+		value string
+	}{{"", dr.Resource}, {"", dr.Name}, {"--namespace", dr.Namespace}, {"--selector", dr.Selector}} {
+		if a.value == "" {
+			continue
+		}
+		if !diagnosticArgRe.MatchString(a.value) {
+			return nil, errors.Errorf("invalid argument: %q", a.value)
+		}
+		if a.flag != "" {
+			args = append(args, a.flag)
+		}
+		args = append(args, a.value)
+	}
+	if dr.Output != "" {
+		args = append(args, "--output", dr.Output)
+	}
+	return args, nil
+}
+
+// KubeConfig returns kube.config of the Tile instance, which was generated during deployment
+func KubeConfig(dSid string, tileInstance string) (string, string, error) {
+	ts, ok := LoadTs(dSid)
+	if !ok || ts.DR == nil {
+		return "", "", errors.Errorf("deployment wasn't found: %s", dSid)
+	}
+	path := func(instance string) string {
+		return ts.DR.Config().WorkHome + ts.DR.SuperFolder + ts.TsStacksMapN[instance].TileFolder + "/kube.config"
+	}
+	if tileInstance != "" {
+		if _, ok := ts.TsStacksMapN[tileInstance]; !ok {
+			return "", "", errors.Errorf("Tile instance wasn't found: %s", tileInstance)
+		}
+		if _, err := os.Stat(path(tileInstance)); err != nil {
+			return "", "", errors.Errorf("no kube.config for Tile instance: %s", tileInstance)
+		}
+		return tileInstance, path(tileInstance), nil
+	}
+	var instances []string
+	for instance := range ts.TsStacksMapN {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	for _, instance := range instances {
+		if _, err := os.Stat(path(instance)); err == nil {
+			return instance, path(instance), nil
+		}
+	}
+	return "", "", errors.Errorf("no kube.config in deployment: %s", dSid)
+}
+
+// RunDiagnostic runs an allowlisted kubectl command without shell in the deployment's context
+func RunDiagnostic(ctx context.Context, workspace string, sidOrName string, dr *DiagnosticRequest) (*DiagnosticResult, error) {
+	args, err := dr.Args()
+	if err != nil {
+		return nil, err
+	}
+	dSid, ok := ResolveSID(workspace, sidOrName)
+	if !ok {
+		return nil, errors.Errorf("deployment wasn't found: %s", sidOrName)
+	}
+	instance, kubeConfig, err := KubeConfig(dSid, dr.TileInstance)
+	if err != nil {
+		return nil, err
+	}
+	result := &DiagnosticResult{SID: dSid, TileInstance: instance, Command: append([]string{"kubectl"}, args...)}
+
+	stx, cancel := context.WithTimeout(ctx, DiagnosticTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(stx, "kubectl", append([]string{"--kubeconfig", kubeConfig}, args...)...)
+	ts, _ := LoadTs(dSid)
+	cmd.Dir = ts.DR.Config().WorkHome + ts.DR.SuperFolder
+	var buf bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &buf, &buf
+	err = cmd.Run()
+	if buf.Len() > maxDiagnosticOutput {
+		buf.Truncate(maxDiagnosticOutput)
+		result.Truncated = true
+	}
+	result.Output = buf.String()
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, nil
+}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiagnosticRequest_Args(t *testing.T) {
+	tests := []struct {
+		name    string
+		request DiagnosticRequest
+		want    []string
+	}{
+		{"get", DiagnosticRequest{Verb: "get", Resource: "pods", Namespace: "kube-system", Selector: "app=nginx", Output: "wide"},
+			[]string{"get", "pods", "--namespace", "kube-system", "--selector", "app=nginx", "--output", "wide"}},
+		{"describe", DiagnosticRequest{Verb: "describe", Resource: "deployment", Name: "nginx"},
+			[]string{"describe", "deployment", "nginx"}},
+		{"delete isn't allowed", DiagnosticRequest{Verb: "delete", Resource: "pods"}, nil},
+		{"exec isn't allowed", DiagnosticRequest{Verb: "exec", Resource: "nginx"}, nil},
+		{"secrets aren't allowed", DiagnosticRequest{Verb: "get", Resource: "secrets", Output: "yaml"}, nil},
+		{"secret by name isn't allowed", DiagnosticRequest{Verb: "describe", Resource: "Secret/token"}, nil},
+		{"secret with group isn't allowed", DiagnosticRequest{Verb: "get", Resource: "secrets.v1"}, nil},
+		{"secrets in list aren't allowed", DiagnosticRequest{Verb: "get", Resource: "pods,secrets", Output: "yaml"}, nil},
+		{"secret by name in list isn't allowed", DiagnosticRequest{Verb: "get", Resource: "pods/nginx,Secret.v1/token"}, nil},
+		{"list", DiagnosticRequest{Verb: "get", Resource: "pods,services", Namespace: "default"},
+			[]string{"get", "pods,services", "--namespace", "default"}},
+		{"flag injection", DiagnosticRequest{Verb: "get", Resource: "pods", Name: "--kubeconfig=/etc/other"}, nil},
+		{"shell injection", DiagnosticRequest{Verb: "get", Resource: "pods; rm -rf /"}, nil},
+		{"output of describe", DiagnosticRequest{Verb: "describe", Resource: "pods", Output: "yaml"}, nil},
+		{"unknown output", DiagnosticRequest{Verb: "get", Resource: "pods", Output: "go-template={{.}}"}, nil},
+		{"without resource", DiagnosticRequest{Verb: "get"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := test.request.Args()
+			assert.Equal(t, test.want, args)
+			assert.Equal(t, test.want == nil, err != nil)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"context"
+	"dice/utils"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Reasons why runs are swept
+const (
+	SweepKeepRuns     = "keep-runs"
+	SweepMaxAge       = "max-age"
+	SweepMaxDiskUsage = "max-disk-usage"
+)
+
+// sweeping serializes sweeps of background & admin
+var sweeping sync.Mutex
+
+// SweptRun is a run which was removed by the sweep, or would be removed on dry run
+type SweptRun struct {
+	Workspace string
+	Name      string
+	SID       string
+	Folder    string `json:",omitempty"` // Folder of the run under work home, empty if only its checkpoint was left
+	Size      int64  // Size of the folder in bytes
+	Updated   time.Time
+	Reason    string
+}
+
+// SweepReport tells what was removed by the sweep
+type SweepReport struct {
+	DryRun  bool
+	Started time.Time
+	Removed []SweptRun
+	Kept    int      // Kept is number of runs which were kept
+	Freed   int64    // Freed is bytes of removed runs
+	Usage   int64    // Usage is bytes of kept runs
+	Errors  []string `json:",omitempty"`
+}
+
+// gcRun is a run in work home, or a checkpoint whose folder was removed
+type gcRun struct {
+	SweptRun
+	dir     string // dir is absolute folder of the run
+	current bool   // current tells if the run is the latest one of the deployment
+}
+
+// isSID tells if name of folder is d-sid
+func isSID(name string) bool {
+	_, err := uuid.Parse(name)
+	return err == nil
+}
+
+// dirSize returns total size of regular files in dir, links aren't followed
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// listRuns returns runs in work homes of all workspaces and checkpoints without folder, the latest one first
+func listRuns() ([]*gcRun, error) {
+	checkpoints := make(map[string]Checkpoint)
+	if Store != nil {
+		keys, err := Store.Keys(checkpointKind)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			var cp Checkpoint
+			if ok, err := Store.Get(checkpointKind, k, &cp); err == nil && ok {
+				checkpoints[k] = cp
+			}
+		}
+	}
+	// Workspaces could share a work home
+	homes := make(map[string]string)
+	var names []string
+	for name := range Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := homes[Workspaces[name].WorkHome]; !ok {
+			homes[Workspaces[name].WorkHome] = name
+		}
+	}
+
+	var all []*gcRun
+	for home, workspace := range homes {
+		deployments, err := ioutil.ReadDir(home)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		for _, d := range deployments {
+			if !d.IsDir() {
+				continue
+			}
+			runs, err := ioutil.ReadDir(filepath.Join(home, d.Name()))
+			if err != nil {
+				return nil, err
+			}
+			current, _ := os.Readlink(filepath.Join(home, d.Name(), utils.CurrentRun))
+			for _, r := range runs {
+				if !r.IsDir() || !isSID(r.Name()) {
+					continue
+				}
+				run := &gcRun{
+					SweptRun: SweptRun{Workspace: workspace, Name: d.Name(), SID: r.Name(), Folder: RunFolder(d.Name(), r.Name()), Updated: r.ModTime()},
+					dir:      filepath.Join(home, d.Name(), r.Name()),
+					current:  r.Name() == current,
+				}
+				if cp, ok := checkpoints[r.Name()]; ok {
+					run.Workspace, run.Updated = cp.Workspace, cp.Updated
+					delete(checkpoints, r.Name())
+				}
+				run.Size = dirSize(run.dir)
+				all = append(all, run)
+			}
+		}
+	}
+	for _, cp := range checkpoints {
+		all = append(all, &gcRun{SweptRun: SweptRun{Workspace: cp.Workspace, Name: cp.Name, SID: cp.SID, Updated: cp.Updated}})
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Updated.After(all[j].Updated)
+	})
+	return all, nil
+}
+
+// lockHolders returns d-sid of deployments holding lock of their name
+func lockHolders() map[string]bool {
+	held := make(map[string]bool)
+	if Store == nil {
+		return held
+	}
+	keys, _ := Store.Keys(nameLockKind)
+	for _, k := range keys {
+		var lock NameLock
+		if ok, err := Store.Get(nameLockKind, k, &lock); err == nil && ok {
+			held[lock.SID] = true
+		}
+	}
+	return held
+}
+
+// retention returns retention of runs, zero means unlimited
+func retention() utils.RetentionConfig {
+	if ServeConfig == nil {
+		return utils.RetentionConfig{}
+	}
+	return ServeConfig.Retention
+}
+
+// Sweep removes runs & their checkpoints as per retention: the latest runs kept per deployment, max age and max disk
+// usage, where the oldest runs are removed first. Running deployments, the current run of each deployment and runs
+// holding lock of their name are never removed, but they are counted. Nothing is removed on dry run.
+func Sweep(dryRun bool) (*SweepReport, error) {
+	sweeping.Lock()
+	defer sweeping.Unlock()
+	report := &SweepReport{DryRun: dryRun, Started: time.Now(), Removed: []SweptRun{}}
+	rc := retention()
+	all, err := listRuns()
+	if err != nil {
+		return nil, err
+	}
+	held := lockHolders()
+
+	var removed, candidates []*gcRun
+	kept := make(map[string]int)
+	for _, r := range all {
+		key := r.Workspace + "/" + r.Name
+		if r.current || held[r.SID] || isRunning(r.SID) {
+			kept[key]++
+			report.Usage += r.Size
+			continue
+		}
+		switch {
+		case rc.KeepRuns > 0 && kept[key] >= rc.KeepRuns:
+			r.Reason = SweepKeepRuns
+			removed = append(removed, r)
+		case rc.MaxAge > 0 && report.Started.Sub(r.Updated) > time.Duration(rc.MaxAge):
+			r.Reason = SweepMaxAge
+			removed = append(removed, r)
+		default:
+			kept[key]++
+			report.Usage += r.Size
+			candidates = append(candidates, r)
+		}
+	}
+	for i := len(candidates) - 1; i >= 0 && rc.MaxDiskUsage > 0 && report.Usage > int64(rc.MaxDiskUsage); i-- {
+		if r := candidates[i]; r.Size > 0 {
+			r.Reason = SweepMaxDiskUsage
+			removed = append(removed, r)
+			report.Usage -= r.Size
+		}
+	}
+
+	for _, r := range removed {
+		if !dryRun {
+			if r.dir != "" {
+				if err := os.RemoveAll(r.dir); err != nil {
+					report.Errors = append(report.Errors, err.Error())
+					report.Usage += r.Size
+					continue
+				}
+			}
+			if Store != nil {
+				if err := Store.Delete(checkpointKind, r.SID); err != nil {
+					report.Errors = append(report.Errors, err.Error())
+				}
+			}
+			SweptRuns.WithLabelValues(r.Reason).Inc()
+			SweptBytes.Add(float64(r.Size))
+		}
+		report.Removed = append(report.Removed, r.SweptRun)
+		report.Freed += r.Size
+	}
+	report.Kept = len(all) - len(report.Removed)
+	if !dryRun {
+		RunsDiskUsage.Set(float64(report.Usage))
+	}
+	return report, nil
+}
+
+// RunGC sweeps runs every interval until ctx is done
+func RunGC(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		report, err := Sweep(false)
+		if err != nil {
+			log.Errorf("Failed to sweep runs : %s\n", err)
+			continue
+		}
+		for _, e := range report.Errors {
+			log.Errorf("Failed to sweep runs : %s\n", e)
+		}
+		if len(report.Removed) > 0 {
+			log.Printf("Swept %d runs & freed %d bytes, %d runs were kept in %d bytes.\n", len(report.Removed), report.Freed, report.Kept, report.Usage)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"context"
+	"dice/utils"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSweep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	Store = &utils.FileStore{Dir: filepath.Join(dir, "state")}
+	workspaces := Workspaces
+	Workspaces = map[string]*utils.DiceConfig{utils.DefaultWorkspace: {Workspace: utils.DefaultWorkspace, WorkHome: dir}}
+	defer func() {
+		Store, Workspaces, ServeConfig = nil, workspaces, nil
+	}()
+
+	// Runs of eks-simple from the latest one, each has 100 bytes, and a checkpoint of a removed run
+	now := time.Now()
+	var sids []string
+	for i := 1; i <= 4; i++ {
+		sid := uuid.New().String()
+		sids = append(sids, sid)
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "eks-simple", sid, "bin"), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "eks-simple", sid, "bin", "super.ts"), make([]byte, 100), 0644))
+		assert.NoError(t, Store.Put(checkpointKind, sid, &Checkpoint{SID: sid, Name: "eks-simple", Workspace: utils.DefaultWorkspace, Updated: now.Add(-time.Duration(i) * time.Hour)}))
+	}
+	assert.NoError(t, (&utils.DiceConfig{WorkHome: dir}).LinkCurrent(RunFolder("eks-simple", sids[0])))
+	gone := uuid.New().String()
+	assert.NoError(t, Store.Put(checkpointKind, gone, &Checkpoint{SID: gone, Name: "gone", Workspace: utils.DefaultWorkspace, Updated: now.Add(-100 * time.Hour)}))
+	// The oldest one is still running, whose checkpoint is updated as the latest one
+	running := &Run{SID: sids[3], Name: "eks-simple", Workspace: utils.DefaultWorkspace}
+	_, err = StartRun(context.Background(), running)
+	assert.NoError(t, err)
+	defer FinishRun(running, nil)
+
+	reasons := func(report *SweepReport) map[string]string {
+		removed := make(map[string]string)
+		for _, r := range report.Removed {
+			removed[r.SID] = r.Reason
+		}
+		return removed
+	}
+
+	// Nothing is removed without retention
+	report, err := Sweep(true)
+	assert.NoError(t, err)
+	assert.Empty(t, report.Removed)
+	assert.Equal(t, 5, report.Kept)
+	assert.Equal(t, int64(400), report.Usage)
+
+	ServeConfig = &utils.ServeConfig{Retention: utils.RetentionConfig{KeepRuns: 1}}
+	report, err = Sweep(true)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{sids[1]: SweepKeepRuns, sids[2]: SweepKeepRuns}, reasons(report))
+	assert.Equal(t, int64(200), report.Freed)
+	assert.DirExists(t, filepath.Join(dir, "eks-simple", sids[1]))
+
+	ServeConfig = &utils.ServeConfig{Retention: utils.RetentionConfig{KeepRuns: 3, MaxAge: utils.Duration(90 * time.Hour), MaxDiskUsage: 250}}
+	report, err = Sweep(false)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{gone: SweepMaxAge, sids[2]: SweepKeepRuns, sids[1]: SweepMaxDiskUsage}, reasons(report))
+	assert.Equal(t, 2, report.Kept)
+	assert.Equal(t, int64(200), report.Usage)
+	assert.Empty(t, report.Errors)
+	for i, sid := range sids {
+		_, err := os.Stat(filepath.Join(dir, "eks-simple", sid))
+		found, _ := Store.Get(checkpointKind, sid, &Checkpoint{})
+		if i == 1 || i == 2 {
+			assert.True(t, os.IsNotExist(err), sid)
+			assert.False(t, found, sid)
+		} else {
+			assert.NoError(t, err, sid)
+			assert.True(t, found, sid)
+		}
+	}
+	found, _ := Store.Get(checkpointKind, gone, &Checkpoint{})
+	assert.False(t, found)
+	assert.Equal(t, float64(200), testutil.ToFloat64(RunsDiskUsage))
+}
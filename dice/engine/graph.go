@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Graph is dependency graph of all Tile instances in a deployment
+type Graph struct {
+	Name  string      `json:"name"`
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a Tile instance
+type GraphNode struct {
+	Id               string `json:"id"` // Id is Tile instance
+	TileName         string `json:"tileName"`
+	TileVersion      string `json:"tileVersion"`
+	TileCategory     string `json:"tileCategory,omitempty"`
+	RootTileInstance string `json:"rootTileInstance,omitempty"`
+	Status           string `json:"status,omitempty"`
+	Generated        bool   `json:"generated,omitempty"`
+}
+
+// GraphEdge indicates Tile instance 'To' depends on Tile instance 'From'
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// GenerateGraph returns dependency graph as per d-sid
+func GenerateGraph(dSid string) *Graph {
+	ts, ok := LoadTs(dSid)
+	if !ok {
+		return nil
+	}
+	return NewGraph(ts.DR.Name, SortedTilesGrid(dSid))
+}
+
+// NewGraph builds dependency graph from TilesGrid
+func NewGraph(name string, tgs []TilesGrid) *Graph {
+	g := &Graph{Name: name}
+	instances := make(map[string]bool)
+	for _, tg := range tgs {
+		instances[tg.TileInstance] = true
+		g.Nodes = append(g.Nodes, GraphNode{
+			Id:               tg.TileInstance,
+			TileName:         tg.TileName,
+			TileVersion:      tg.TileVersion,
+			TileCategory:     tg.TileCategory,
+			RootTileInstance: tg.RootTileInstance,
+			Status:           tg.Status,
+			Generated:        tg.Generated,
+		})
+	}
+	for _, tg := range tgs {
+		for _, p := range tg.ParentTileInstances {
+			if instances[p] {
+				g.Edges = append(g.Edges, GraphEdge{From: p, To: tg.TileInstance})
+			}
+		}
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	return g
+}
+
+// groups returns Tile instances grouped by root Tile instance.
+// Rendering is copied into mctl/cmd/graph for offline graph, keep both in sync.
+func (g *Graph) groups() ([]string, map[string][]GraphNode) {
+	var roots []string
+	groups := make(map[string][]GraphNode)
+	for _, n := range g.Nodes {
+		if _, ok := groups[n.RootTileInstance]; !ok {
+			roots = append(roots, n.RootTileInstance)
+		}
+		groups[n.RootTileInstance] = append(groups[n.RootTileInstance], n)
+	}
+	return roots, groups
+}
+
+// ToDot renders graph in Graphviz DOT, Tile instances in same group are put into same cluster
+func (g *Graph) ToDot() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("digraph %q {\n", g.Name))
+	sb.WriteString("  rankdir=LR;\n  node [shape=box, style=rounded];\n")
+	roots, groups := g.groups()
+	for i, root := range roots {
+		indent := "  "
+		if root != "" {
+			sb.WriteString(fmt.Sprintf("  subgraph cluster_%d {\n    label=%q;\n", i, root))
+			indent = "    "
+		}
+		for _, n := range groups[root] {
+			style := "rounded"
+			if n.Generated {
+				style = "rounded,dashed"
+			}
+			sb.WriteString(fmt.Sprintf("%s%q [label=%q, style=%q];\n", indent, n.Id, n.label("\n"), style))
+		}
+		if root != "" {
+			sb.WriteString("  }\n")
+		}
+	}
+	for _, e := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %q -> %q;\n", e.From, e.To))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// ToMermaid renders graph in Mermaid flowchart, which can be embedded into Markdown
+func (g *Graph) ToMermaid() string {
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	roots, groups := g.groups()
+	for _, root := range roots {
+		indent := "  "
+		if root != "" {
+			sb.WriteString(fmt.Sprintf("  subgraph %s\n", mermaidId(root)+"Group"))
+			indent = "    "
+		}
+		for _, n := range groups[root] {
+			sb.WriteString(fmt.Sprintf("%s%s[\"%s\"]\n", indent, mermaidId(n.Id), n.label("<br/>")))
+		}
+		if root != "" {
+			sb.WriteString("  end\n")
+		}
+	}
+	for _, e := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %s --> %s\n", mermaidId(e.From), mermaidId(e.To)))
+	}
+	for _, n := range g.Nodes {
+		if n.Status == Done.DSString() || n.Status == Interrupted.DSString() || n.Status == Progress.DSString() {
+			sb.WriteString(fmt.Sprintf("  class %s %s\n", mermaidId(n.Id), strings.ToLower(n.Status)))
+		}
+	}
+	sb.WriteString("  classDef done fill:#d4edda\n  classDef progress fill:#fff3cd\n  classDef interrupted fill:#f8d7da\n")
+	return sb.String()
+}
+
+func (n GraphNode) label(sep string) string {
+	label := n.Id + sep + n.TileName + " - " + n.TileVersion
+	if n.TileCategory != "" {
+		label = label + sep + n.TileCategory
+	}
+	if n.Status != "" {
+		label = label + sep + n.Status
+	}
+	return label
+}
+
+var mermaidIdRe = regexp.MustCompile(`[^[:alnum:]_]`)
+
+// mermaidId replaces characters which aren't allowed in Mermaid's id
+func mermaidId(id string) string {
+	return mermaidIdRe.ReplaceAllString(id, "_")
+}
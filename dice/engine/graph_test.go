@@ -0,0 +1,26 @@
+package engine
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestNewGraph(t *testing.T) {
+	g := NewGraph("eks-simple", []TilesGrid{tilesGrid2, tilesGrid1, tilesGrid3, tilesGrid4})
+	assert.Equal(t, 4, len(g.Nodes))
+	assert.Equal(t, []GraphEdge{
+		{From: "tileInstance01", To: "tileInstance02"},
+		{From: "tileInstance03", To: "tileInstance01"},
+	}, g.Edges)
+
+	dot := g.ToDot()
+	assert.True(t, strings.HasPrefix(dot, `digraph "eks-simple" {`))
+	assert.Contains(t, dot, `"tileInstance03" -> "tileInstance01";`)
+	assert.Equal(t, 2, strings.Count(dot, "subgraph cluster_"))
+
+	mermaid := g.ToMermaid()
+	assert.True(t, strings.HasPrefix(mermaid, "graph LR\n"))
+	assert.Contains(t, mermaid, "tileInstance01 --> tileInstance02")
+	assert.Contains(t, mermaid, `tileInstance04["tileInstance04<br/>Bumblebee - 0.14.0<br/>Application"]`)
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"dice/apis/v1alpha1"
+	"errors"
+	"github.com/gorilla/websocket"
+	"strings"
+	"time"
+)
+
+// GenerateLock returns lock of all resolved Tile instances as per d-sid
+func GenerateLock(dSid string) *v1alpha1.Lock {
+	ts, ok := LoadTs(dSid)
+	if !ok {
+		return nil
+	}
+	lock := &v1alpha1.Lock{
+		ApiVersion: "mahjong.io/v1alpha1",
+		Kind:       "Lock",
+		Metadata: v1alpha1.LockMetadata{
+			Name:      ts.DR.Name,
+			Generated: time.Now(),
+		},
+	}
+	for _, tg := range SortedTilesGrid(dSid) {
+		lock.Tiles = append(lock.Tiles, v1alpha1.LockedTile{
+			TileInstance:     tg.TileInstance,
+			TileReference:    tg.TileName,
+			TileVersion:      tg.TileVersion,
+			Digest:           tg.TileDigest,
+			RootTileInstance: tg.RootTileInstance,
+			Generated:        tg.Generated,
+		})
+	}
+	return lock
+}
+
+// VerifyLock refuses the deployment if resolved Tiles were different from submitted lock
+func (d *AssembleData) VerifyLock(ctx context.Context, out *websocket.Conn) error {
+	if d.Lock == nil {
+		return nil
+	}
+	dSid := ctx.Value("d-sid").(string)
+	SRf(out, "Verifying resolved Tiles against %s ...", v1alpha1.LockFileName)
+	if d.Lock.Metadata.Name != d.Deployment.Metadata.Name {
+		return errors.New("lock was generated for deployment: " + d.Lock.Metadata.Name)
+	}
+	if diffs := d.Lock.Differ(GenerateLock(dSid)); len(diffs) > 0 {
+		for _, diff := range diffs {
+			SRf(out, "Locked Tile was different : %s", diff)
+		}
+		return errors.New("resolved Tiles were different from " + v1alpha1.LockFileName + ": " + strings.Join(diffs, "; "))
+	}
+	SRf(out, "Verifying resolved Tiles against %s ... with success", v1alpha1.LockFileName)
+	return nil
+}
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+var (
+	// DeploymentsTotal counts finished deployments by status & mode
+	DeploymentsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dice_deployments_total",
+		Help: "Finished deployments by status & mode (deploy or dry-run).",
+	}, []string{"status", "mode"})
+	// RunningDeployments is the number of deployments in progress
+	RunningDeployments = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dice_running_deployments",
+		Help: "Deployments in progress.",
+	})
+	// QueuedDeployments is the number of deployments waiting for a slot of concurrency
+	QueuedDeployments = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dice_queued_deployments",
+		Help: "Deployments waiting for a slot of concurrency.",
+	})
+	// StagesTotal counts finished stages by status, Tile, category & kind
+	StagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dice_stages_total",
+		Help: "Finished stages by status, Tile, category & kind.",
+	}, []string{"status", "tile", "category", "kind"})
+	// StageDuration observes duration of stages
+	StageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dice_stage_duration_seconds",
+		Help:    "Duration of stages by status, Tile, category & kind.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}, []string{"status", "tile", "category", "kind"})
+	// TilePullDuration observes duration of pulling & unpacking Tiles from repo
+	TilePullDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dice_tile_pull_duration_seconds",
+		Help:    "Duration of pulling & unpacking Tiles from repo by Tile & category.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"tile", "category"})
+	// SweptRuns counts runs removed as per retention by reason
+	SweptRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dice_swept_runs_total",
+		Help: "Runs removed as per retention by reason (keep-runs, max-age or max-disk-usage).",
+	}, []string{"reason"})
+	// SweptBytes counts bytes of removed runs
+	SweptBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dice_swept_bytes_total",
+		Help: "Bytes of runs removed as per retention.",
+	})
+	// RunsDiskUsage is bytes of runs kept in work homes as of the last sweep
+	RunsDiskUsage = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "dice_runs_disk_usage_bytes",
+		Help: "Bytes of runs kept in work homes as of the last sweep.",
+	})
+)
+
+// finishStage sets status of stage and records metrics, labels are from ExecutionStage & TilesGrid
+func finishStage(dSid string, stage *ExecutionStage, status string, started time.Time) {
+	setStatus(dSid, stage.Name, status)
+	category := stageCategory(dSid, stage.Name)
+	StagesTotal.WithLabelValues(status, stage.TileName, category, stage.Kind).Inc()
+	StageDuration.WithLabelValues(status, stage.TileName, category, stage.Kind).Observe(time.Since(started).Seconds())
+}
+
+// stageCategory returns category of Tile instance from TilesGrid
+func stageCategory(dSid string, tileInstance string) string {
+	if tilesGrid, ok := AllTilesGrids[dSid]; ok {
+		if tg, ok := (*tilesGrid)[tileInstance]; ok {
+			return tg.TileCategory
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// nameLockKind is kind of locks of deployment names in Store
+const nameLockKind = "locks"
+
+var (
+	// ErrNotLocked tells the deployment name isn't locked
+	ErrNotLocked = errors.New("deployment isn't locked")
+	// ErrLockHeld tells the lock is held by a running deployment, which isn't stale
+	ErrLockHeld = errors.New("lock is held by a running deployment")
+)
+
+// NameLock is held by a deployment, so that only one deployment of the name runs in a workspace at a time
+type NameLock struct {
+	Name      string
+	Workspace string
+	SID       string // SID of the deployment holding the lock
+	Who       string `json:",omitempty"` // Who submitted the deployment
+	Host      string // Host & PID of Dice which the deployment is running in
+	PID       int
+	Acquired  time.Time
+}
+
+// nameLockKey returns key of lock in Store
+func nameLockKey(workspace string, name string) string {
+	return workspace + "." + name
+}
+
+// isRunning tells if the deployment is running in this Dice
+func isRunning(dSid string) bool {
+	runs.Lock()
+	defer runs.Unlock()
+	_, ok := runs.all[dSid]
+	return ok
+}
+
+// lockName acquires lock of the deployment name, holder of the lock is returned if it's held by another running
+// deployment. An error is returned if the lock is held by a deployment which isn't running in this Dice, queue must be locked.
+func lockName(r *Run) (string, error) {
+	if Store == nil || r.locked {
+		return "", nil
+	}
+	host, _ := os.Hostname()
+	lock := &NameLock{Name: r.Name, Workspace: r.Workspace, SID: r.SID, Who: r.Who, Host: host, PID: os.Getpid(), Acquired: time.Now()}
+	key := nameLockKey(r.Workspace, r.Name)
+	created, err := Store.Create(nameLockKind, key, lock)
+	if err != nil {
+		return "", err
+	} else if created {
+		r.locked = true
+		return "", nil
+	}
+	var held NameLock
+	if ok, err := Store.Get(nameLockKind, key, &held); err != nil {
+		return "", err
+	} else if !ok {
+		// Unlocked in the meantime, it's acquired on next dispatch
+		return r.Name, nil
+	}
+	if isRunning(held.SID) {
+		return held.SID, nil
+	}
+	return "", fmt.Errorf("deployment %s in workspace %s is locked by %s since %s on %s (pid %d), which isn't running in this Dice. "+
+		"Force unlock it if it's stale", r.Name, r.Workspace, held.SID, held.Acquired.Format(time.RFC3339), held.Host, held.PID)
+}
+
+// unlockName releases lock of the deployment name if it's still held by the deployment, queue must be locked
+func unlockName(r *Run) error {
+	if Store == nil || !r.locked {
+		return nil
+	}
+	r.locked = false
+	key := nameLockKey(r.Workspace, r.Name)
+	var held NameLock
+	if ok, err := Store.Get(nameLockKind, key, &held); err != nil || !ok || held.SID != r.SID {
+		return err
+	}
+	return Store.Delete(nameLockKind, key)
+}
+
+// NameLocks returns locks of deployment names in the workspace
+func NameLocks(workspace string) ([]NameLock, error) {
+	locks := []NameLock{}
+	if Store == nil {
+		return locks, nil
+	}
+	keys, err := Store.Keys(nameLockKind)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		var lock NameLock
+		if ok, err := Store.Get(nameLockKind, k, &lock); err != nil {
+			return nil, err
+		} else if ok && lock.Workspace == workspace {
+			locks = append(locks, lock)
+		}
+	}
+	return locks, nil
+}
+
+// ForceUnlock releases a stale lock of the deployment name, which was left by Dice exited unexpectedly.
+// Lock held by a running deployment isn't released, ErrLockHeld is returned then.
+func ForceUnlock(workspace string, name string) (*NameLock, error) {
+	if Store == nil {
+		return nil, ErrNotLocked
+	}
+	queue.Lock()
+	key := nameLockKey(workspace, name)
+	var held NameLock
+	if ok, err := Store.Get(nameLockKind, key, &held); err != nil {
+		queue.Unlock()
+		return nil, err
+	} else if !ok {
+		queue.Unlock()
+		return nil, ErrNotLocked
+	}
+	if isRunning(held.SID) {
+		queue.Unlock()
+		return &held, ErrLockHeld
+	}
+	if err := Store.Delete(nameLockKind, key); err != nil {
+		queue.Unlock()
+		return nil, err
+	}
+	moved := dispatch()
+	queue.Unlock()
+	notify(moved)
+	return &held, nil
+}
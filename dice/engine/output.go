@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"dice/apis/v1alpha1"
+	"dice/utils"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Outputs are summary outputs & outputs of all Tile instances in a deployment
+type Outputs struct {
+	SID     string        `json:"sid"`
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	Summary Summary       `json:"summary"`
+	Tiles   []TileOutputs `json:"tiles"`
+}
+
+// Summary is resolved deployment.spec.summary
+type Summary struct {
+	Description string        `json:"description,omitempty"`
+	Outputs     []OutputValue `json:"outputs"`
+	Notes       []string      `json:"notes,omitempty"`
+}
+
+// TileOutputs are captured outputs of a Tile instance
+type TileOutputs struct {
+	TileInstance string        `json:"tileInstance"`
+	TileName     string        `json:"tileName"`
+	TileVersion  string        `json:"tileVersion"`
+	Outputs      []OutputValue `json:"outputs"`
+}
+
+// OutputValue is a named value
+type OutputValue struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// Summary resolves description, outputs & notes of deployment.spec.summary
+func (ep *ExecutionPlan) Summary(dSid string) Summary {
+	kv := ep.ExtractAllEnv()
+	summary := Summary{Outputs: []OutputValue{}}
+	if ep.OriginDeployment.Spec.Summary.Description != "" {
+		summary.Description = ep.ReplaceAll(ep.OriginDeployment.Spec.Summary.Description, dSid, kv)
+	}
+	for _, ot := range ep.OriginDeployment.Spec.Summary.Outputs {
+		summary.Outputs = append(summary.Outputs, OutputValue{Name: ot.Name, Value: ep.ReplaceAll(ot.Value, dSid, kv)})
+	}
+	for _, n := range ep.OriginDeployment.Spec.Summary.Notes {
+		summary.Notes = append(summary.Notes, ep.ReplaceAll(n, dSid, kv))
+	}
+	return summary
+}
+
+// GenerateOutputs returns outputs as per d-sid or name of deployment in the workspace, the latest one would be chosen by name
+func GenerateOutputs(workspace string, sidOrName string) *Outputs {
+	dSid, ok := ResolveSID(workspace, sidOrName)
+	if !ok {
+		return nil
+	}
+	ts, _ := LoadTs(dSid)
+	outputs := &Outputs{SID: dSid, Name: ts.DR.Name, Status: ts.DR.Status, Summary: Summary{Outputs: []OutputValue{}}, Tiles: []TileOutputs{}}
+	if ep, ok := AllPlans[dSid]; ok && ep.OriginDeployment != nil {
+		outputs.Summary = ep.Summary(dSid)
+	}
+	if ts.AllOutputsN == nil {
+		return outputs
+	}
+	for _, tg := range SortedTilesGrid(dSid) {
+		to, ok := (*ts.AllOutputsN)[tg.TileInstance]
+		if !ok || to.TsOutputs == nil {
+			continue
+		}
+		tileOutputs := TileOutputs{TileInstance: tg.TileInstance, TileName: to.TileName, TileVersion: to.TileVersion, Outputs: []OutputValue{}}
+		// Keep order as per Tile specification
+		var others []string
+		for name := range *to.TsOutputs {
+			if !utils.Contains(to.OutputsOrder, name) {
+				others = append(others, name)
+			}
+		}
+		sort.Strings(others)
+		names := append(append([]string{}, to.OutputsOrder...), others...)
+		for _, name := range names {
+			if o, ok := (*to.TsOutputs)[name]; ok {
+				tileOutputs.Outputs = append(tileOutputs.Outputs, OutputValue{Name: name, Value: o.OutputValue, Description: o.Description})
+			}
+		}
+		outputs.Tiles = append(outputs.Tiles, tileOutputs)
+	}
+	return outputs
+}
+
+// Masked returns outputs where values of secret parameters are replaced by SecretMask,
+// summary is resolved from the deployment whose parameters were replaced by real values
+func (o *Outputs) Masked(secrets []string) (*Outputs, error) {
+	generic, err := v1alpha1.MaskSecrets(o, secrets)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := json.Marshal(generic)
+	if err != nil {
+		return nil, err
+	}
+	var masked Outputs
+	if err := json.Unmarshal(buf, &masked); err != nil {
+		return nil, err
+	}
+	return &masked, nil
+}
+
+// ToEnv renders outputs as dotenv, summary output as NAME, output of Tile instance as TILE_INSTANCE_NAME
+func (o *Outputs) ToEnv() string {
+	var sb strings.Builder
+	for _, t := range o.Tiles {
+		for _, v := range t.Outputs {
+			sb.WriteString(EnvKey(t.TileInstance+"_"+v.Name) + "=" + envValue(v.Value) + "\n")
+		}
+	}
+	for _, v := range o.Summary.Outputs {
+		sb.WriteString(EnvKey(v.Name) + "=" + envValue(v.Value) + "\n")
+	}
+	return sb.String()
+}
+
+// KeyToEnv renders one output as dotenv, key is name of summary output or <tile-instance>.<output>
+func (o *Outputs) KeyToEnv(key string) (string, bool) {
+	for _, v := range o.Summary.Outputs {
+		if v.Name == key {
+			return EnvKey(v.Name) + "=" + envValue(v.Value) + "\n", true
+		}
+	}
+	if i := strings.LastIndex(key, "."); i > 0 {
+		for _, t := range o.Tiles {
+			if t.TileInstance != key[:i] {
+				continue
+			}
+			for _, v := range t.Outputs {
+				if v.Name == key[i+1:] {
+					return EnvKey(t.TileInstance+"_"+v.Name) + "=" + envValue(v.Value) + "\n", true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+var envKeyCamelRe = regexp.MustCompile(`([[:lower:][:digit:]])([[:upper:]])`)
+var envKeyRe = regexp.MustCompile(`[^[:alnum:]]+`)
+
+// EnvKey converts name to be key of environment variable
+func EnvKey(name string) string {
+	name = envKeyCamelRe.ReplaceAllString(name, "${1}_${2}")
+	return strings.ToUpper(strings.Trim(envKeyRe.ReplaceAllString(name, "_"), "_"))
+}
+
+var envValueRe = regexp.MustCompile(`^[[:alnum:]_./:@%+,-]+$`)
+
+// envValue quotes value in POSIX single quotes unless it's safe as it is, so that nothing is expanded by eval "$(...)"
+func envValue(value string) string {
+	if envValueRe.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"container/list"
+	"dice/apis/v1alpha1"
+	"dice/utils"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGenerateOutputs(t *testing.T) {
+	allOutputs := map[string]*TsOutput{
+		"tileEks-us-west-2": {
+			TileName:     "Eks0",
+			TileVersion:  "0.0.5",
+			OutputsOrder: []string{"clusterName", "clusterEndpoint"},
+			TsOutputs: &map[string]*TsOutputDetail{
+				"clusterEndpoint": {Name: "clusterEndpoint", OutputValue: "https://eks.amazonaws.com"},
+				"clusterName":     {Name: "clusterName", OutputValue: "mahjong cluster"},
+			},
+		},
+	}
+	allTs := AllTs
+	AllTs = map[string]map[string]Ts{utils.DefaultWorkspace: {"output-sid": {
+		DR:          &DeploymentRecord{SID: "output-sid", Name: "eks-output", Created: time.Now(), Status: Done.DSString(), Workspace: utils.DefaultWorkspace},
+		AllOutputsN: &allOutputs,
+	}}}
+	AllTilesGrids["output-sid"] = &map[string]*TilesGrid{
+		"tileEks-us-west-2": {TileInstance: "tileEks-us-west-2", TileName: "Eks0", TileVersion: "0.0.5"},
+	}
+	defer func() {
+		AllTs = allTs
+		delete(AllTilesGrids, "output-sid")
+	}()
+
+	assert.Nil(t, GenerateOutputs(utils.DefaultWorkspace, "not-existed"))
+	assert.Nil(t, GenerateOutputs("team-a", "eks-output"))
+	assert.Nil(t, GenerateOutputs("team-a", "output-sid"))
+	outputs := GenerateOutputs(utils.DefaultWorkspace, "eks-output")
+	assert.Equal(t, "output-sid", outputs.SID)
+	assert.Equal(t, []TileOutputs{{
+		TileInstance: "tileEks-us-west-2",
+		TileName:     "Eks0",
+		TileVersion:  "0.0.5",
+		Outputs: []OutputValue{
+			{Name: "clusterName", Value: "mahjong cluster"},
+			{Name: "clusterEndpoint", Value: "https://eks.amazonaws.com"},
+		},
+	}}, outputs.Tiles)
+
+	outputs.Summary.Outputs = []OutputValue{{Name: "endpoint", Value: "https://eks.amazonaws.com"}}
+	assert.Equal(t, "TILE_EKS_US_WEST_2_CLUSTER_NAME='mahjong cluster'\n"+
+		"TILE_EKS_US_WEST_2_CLUSTER_ENDPOINT=https://eks.amazonaws.com\n"+
+		"ENDPOINT=https://eks.amazonaws.com\n", outputs.ToEnv())
+	env, ok := outputs.KeyToEnv("tileEks-us-west-2.clusterName")
+	assert.True(t, ok)
+	assert.Equal(t, "TILE_EKS_US_WEST_2_CLUSTER_NAME='mahjong cluster'\n", env)
+	env, ok = outputs.KeyToEnv("endpoint")
+	assert.True(t, ok)
+	assert.Equal(t, "ENDPOINT=https://eks.amazonaws.com\n", env)
+	_, ok = outputs.KeyToEnv("tileEks-us-west-2.notExisted")
+	assert.False(t, ok)
+}
+
+func TestOutputs_Masked(t *testing.T) {
+	allTs := AllTs
+	AllTs = map[string]map[string]Ts{utils.DefaultWorkspace: {"masked-sid": {
+		DR:      &DeploymentRecord{SID: "masked-sid", Name: "eks-masked", Created: time.Now(), Status: Done.DSString(), Workspace: utils.DefaultWorkspace},
+		secrets: []string{"ore-keypair"},
+	}}}
+	// Summary is resolved from the deployment, where $(params.keyPair) was replaced by the real value
+	deployment := &v1alpha1.Deployment{}
+	deployment.Spec.Summary.Description = "EKS with ore-keypair"
+	deployment.Spec.Summary.Outputs = []v1alpha1.DeploymentSummaryOutput{{Name: "keyPair", Value: "ore-keypair"}}
+	deployment.Spec.Summary.Notes = []string{"ssh -i ore-keypair.pem ec2-user@bastion"}
+	AllPlans["masked-sid"] = &ExecutionPlan{Plan: list.New(), OriginDeployment: deployment}
+	defer func() {
+		AllTs = allTs
+		delete(AllPlans, "masked-sid")
+	}()
+
+	outputs, err := GenerateOutputs(utils.DefaultWorkspace, "eks-masked").Masked(Secrets("masked-sid"))
+	assert.NoError(t, err)
+	assert.Equal(t, "EKS with "+v1alpha1.SecretMask, outputs.Summary.Description)
+	assert.Equal(t, []string{"ssh -i " + v1alpha1.SecretMask + ".pem ec2-user@bastion"}, outputs.Summary.Notes)
+	assert.Equal(t, "KEY_PAIR="+envValue(v1alpha1.SecretMask)+"\n", outputs.ToEnv())
+}
+
+func TestEnvValue(t *testing.T) {
+	var tests = []struct {
+		value    string
+		expected string
+	}{
+		{"https://eks.amazonaws.com", "https://eks.amazonaws.com"},
+		{"", "''"},
+		{"mahjong cluster", "'mahjong cluster'"},
+		{"$(whoami) `id` \\n", "'$(whoami) `id` \\n'"},
+		{"it's", `'it'\''s'`},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, envValue(test.value))
+	}
+}
@@ -0,0 +1,11 @@
+package engine
+
+import "os/exec"
+
+// setProcessGroup isn't supported on Windows
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup only kills the command on Windows
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
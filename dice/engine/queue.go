@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"context"
+	"dice/apis/v1alpha1"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCancelled tells a queued deployment was cancelled before it started
+var ErrCancelled = errors.New("deployment was cancelled while it was queued")
+
+// QueuedRun is a deployment waiting for a slot of concurrency
+type QueuedRun struct {
+	SID       string
+	Name      string
+	Workspace string
+	Accounts  []string
+	Position  int // Position starts from 1 across all workspaces
+	Queued    time.Time
+}
+
+// queue is deployments waiting in FIFO order, and running ones per AWS account
+var queue = struct {
+	sync.Mutex
+	waiting  []*Run
+	running  int
+	accounts map[string]int
+}{accounts: map[string]int{}}
+
+// Accounts returns AWS profile/region of Tiles in the deployment, profile of the workspace is used if it wasn't given,
+// and 'default' stands for the one from environment of Dice
+func Accounts(workspace string, deployment *v1alpha1.Deployment) []string {
+	profile := ""
+	if dc, ok := WorkspaceConfig(workspace); ok {
+		profile = dc.Profile
+	}
+	seen := make(map[string]bool)
+	var accounts []string
+	for _, tile := range deployment.Spec.Template.Tiles {
+		p, r := tile.Profile, tile.Region
+		if p == "" {
+			p = profile
+		}
+		if p == "" {
+			p = "default"
+		}
+		if r == "" {
+			r = "default"
+		}
+		if account := p + "/" + r; !seen[account] {
+			seen[account] = true
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Strings(accounts)
+	return accounts
+}
+
+// limits returns max running deployments in total & per account, zero is unlimited
+func limits() (int, int) {
+	if ServeConfig == nil {
+		return 0, 0
+	}
+	return ServeConfig.Concurrency.Deployments, ServeConfig.Concurrency.PerAccount
+}
+
+// admit tells why the deployment has to wait, it's empty if the deployment could run. Limits of concurrency aren't
+// applied to dry run, but lock of the deployment name is. An error is returned if the deployment can't run, queue must be locked.
+func admit(r *Run, ahead map[string]bool) (string, error) {
+	if ahead[nameLockKey(r.Workspace, r.Name)] {
+		return "an earlier deployment of " + r.Name + " is queued", nil
+	}
+	if !r.DryRun {
+		total, perAccount := limits()
+		if total > 0 && queue.running >= total {
+			return fmt.Sprintf("max %d deployments are running", total), nil
+		}
+		if perAccount > 0 {
+			for _, a := range r.Accounts {
+				if queue.accounts[a] >= perAccount {
+					return fmt.Sprintf("max %d deployments are running in AWS account %s", perAccount, a), nil
+				}
+			}
+		}
+	}
+	holder, err := lockName(r)
+	if err != nil {
+		return "", err
+	} else if holder != "" {
+		return fmt.Sprintf("%s is locked by running deployment %s", r.Name, holder), nil
+	}
+	return "", nil
+}
+
+// dispatch starts waiting deployments in order as long as they could run, a deployment blocked by its accounts doesn't
+// block ones deploying into other accounts. Deployments which can't run are cancelled.
+// It returns deployments whose position was changed, queue must be locked.
+func dispatch() []*Run {
+	var moved []*Run
+	waiting := queue.waiting[:0]
+	ahead := make(map[string]bool)
+	for _, r := range queue.waiting {
+		waitFor, err := admit(r, ahead)
+		if err != nil {
+			r.reason = err
+			r.cancel()
+			continue
+		}
+		if waitFor == "" {
+			if !r.DryRun {
+				queue.running++
+				for _, a := range r.Accounts {
+					queue.accounts[a]++
+				}
+			}
+			r.admitted = true
+			close(r.ready)
+			continue
+		}
+		waiting = append(waiting, r)
+		ahead[nameLockKey(r.Workspace, r.Name)] = true
+		if r.position != len(waiting) || r.waitFor != waitFor {
+			r.position, r.waitFor = len(waiting), waitFor
+			moved = append(moved, r)
+		}
+	}
+	queue.waiting = waiting
+	QueuedDeployments.Set(float64(len(waiting)))
+	return moved
+}
+
+// notify reports position to queued deployments
+func notify(moved []*Run) {
+	for _, r := range moved {
+		r.report("Deployment %s was queued at position %d, as %s.", r.SID, r.position, r.waitFor)
+	}
+}
+
+// Acquire waits in the queue until the deployment could run under limits of concurrency and holds lock of its name,
+// position in the queue is reported to the deployment. It returns an error if the deployment was cancelled, Dice is
+// shutting down, the name is locked by a deployment which isn't running in this Dice or ctx is done.
+// The slot & lock are released by FinishRun.
+func Acquire(ctx context.Context, r *Run) error {
+	queue.Lock()
+	r.queued, r.ready = time.Now(), make(chan struct{})
+	queue.waiting = append(queue.waiting, r)
+	moved := dispatch()
+	queue.Unlock()
+	notify(moved)
+
+	select {
+	case <-r.ready:
+		if r.position > 0 {
+			r.report("Deployment %s was started after queuing for %s.", r.SID, time.Since(r.queued).Round(time.Second))
+			r.position = 0
+		}
+		return nil
+	case <-ctx.Done():
+	}
+	queue.Lock()
+	if r.admitted {
+		// Started & cancelled at the same time, slot & lock are released by FinishRun
+		queue.Unlock()
+		return ctx.Err()
+	}
+	// It has left the queue already if it can't run
+	for i, w := range queue.waiting {
+		if w == r {
+			queue.waiting = append(queue.waiting[:i], queue.waiting[i+1:]...)
+			break
+		}
+	}
+	moved = dispatch()
+	reason := r.reason
+	queue.Unlock()
+	notify(moved)
+	if reason != nil {
+		return reason
+	}
+	return ctx.Err()
+}
+
+// release frees the slot & lock of the deployment, and starts next ones in the queue
+func release(r *Run) {
+	queue.Lock()
+	if !r.admitted {
+		queue.Unlock()
+		return
+	}
+	r.admitted = false
+	if !r.DryRun {
+		queue.running--
+		for _, a := range r.Accounts {
+			if queue.accounts[a]--; queue.accounts[a] <= 0 {
+				delete(queue.accounts, a)
+			}
+		}
+	}
+	if err := unlockName(r); err != nil {
+		log.Errorf("Failed to unlock %s of %s : %s\n", r.Name, r.SID, err)
+	}
+	moved := dispatch()
+	queue.Unlock()
+	notify(moved)
+}
+
+// QueuedRuns returns queued deployments in the workspace in order
+func QueuedRuns(workspace string) []QueuedRun {
+	queue.Lock()
+	defer queue.Unlock()
+	qs := []QueuedRun{}
+	for i, r := range queue.waiting {
+		if r.Workspace == workspace {
+			qs = append(qs, QueuedRun{SID: r.SID, Name: r.Name, Workspace: r.Workspace, Accounts: r.Accounts, Position: i + 1, Queued: r.queued})
+		}
+	}
+	return qs
+}
+
+// CancelQueued cancels the queued deployment in the workspace, and tells if it was queued.
+// sid could be name of deployment, the first queued one is cancelled then.
+func CancelQueued(workspace string, sid string) (*QueuedRun, bool) {
+	queue.Lock()
+	defer queue.Unlock()
+	for i, r := range queue.waiting {
+		if (r.SID == sid || r.Name == sid) && r.Workspace == workspace {
+			r.reason = ErrCancelled
+			r.cancel()
+			return &QueuedRun{SID: r.SID, Name: r.Name, Workspace: r.Workspace, Accounts: r.Accounts, Position: i + 1, Queued: r.queued}, true
+		}
+	}
+	return nil, false
+}
+
+// cancelQueued cancels all queued deployments for the reason
+func cancelQueued(reason error) {
+	queue.Lock()
+	defer queue.Unlock()
+	for _, r := range queue.waiting {
+		r.reason = reason
+		r.cancel()
+	}
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"dice/apis/v1alpha1"
+	"dice/utils"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAccounts(t *testing.T) {
+	d := &v1alpha1.Deployment{Spec: v1alpha1.DeploymentSpec{Template: v1alpha1.DeploymentTemplate{Tiles: map[string]v1alpha1.DeploymentTemplateDetail{
+		"network": {},
+		"eks":     {Region: "us-west-2"},
+		"app":     {Profile: "team-a", Region: "us-west-2"},
+		"db":      {Region: "us-west-2"},
+	}}}}
+	assert.Equal(t, []string{"default/default", "default/us-west-2", "team-a/us-west-2"}, Accounts(utils.DefaultWorkspace, d))
+}
+
+func TestQueue(t *testing.T) {
+	ServeConfig = &utils.ServeConfig{Concurrency: utils.ConcurrencyConfig{Deployments: 2, PerAccount: 1}}
+	defer func() { ServeConfig = nil }()
+
+	start := func(sid string, account string) (*Run, chan error) {
+		r := &Run{SID: sid, Name: sid, Workspace: utils.DefaultWorkspace, Accounts: []string{account}}
+		ctx, err := StartRun(context.Background(), r)
+		assert.NoError(t, err)
+		acquired := make(chan error, 1)
+		go func() { acquired <- Acquire(ctx, r) }()
+		return r, acquired
+	}
+	wait := func(acquired chan error) error {
+		select {
+		case err := <-acquired:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("deployment wasn't started")
+			return nil
+		}
+	}
+	queued := func() []string {
+		var sids []string
+		for _, q := range QueuedRuns(utils.DefaultWorkspace) {
+			sids = append(sids, q.SID)
+		}
+		return sids
+	}
+
+	a1, acquired := start("a1", "a/default")
+	assert.NoError(t, wait(acquired))
+	// a2 waits for a1 of the same account, b1 isn't blocked by a2
+	a2, a2Acquired := start("a2", "a/default")
+	assert.Eventually(t, func() bool { return len(queued()) == 1 }, time.Second, time.Millisecond)
+	b1, acquired := start("b1", "b/default")
+	assert.NoError(t, wait(acquired))
+	// c1 waits for the global limit
+	c1, c1Acquired := start("c1", "c/default")
+	assert.Eventually(t, func() bool { return len(queued()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"a2", "c1"}, queued())
+	for _, d := range AllTsDeployment(utils.DefaultWorkspace) {
+		if d.SID == "c1" {
+			assert.Equal(t, Queued.DSString(), d.Status)
+			assert.Equal(t, 2, d.Position)
+		}
+	}
+
+	// Cancelled c1 leaves the queue
+	q, ok := CancelQueued(utils.DefaultWorkspace, "c1")
+	assert.True(t, ok)
+	assert.Equal(t, 2, q.Position)
+	assert.Equal(t, ErrCancelled, wait(c1Acquired))
+	FinishRun(c1, ErrCancelled)
+	_, ok = CancelQueued(utils.DefaultWorkspace, "b1")
+	assert.False(t, ok)
+
+	// a2 is started after a1 finished
+	FinishRun(a1, nil)
+	assert.NoError(t, wait(a2Acquired))
+	assert.Empty(t, queued())
+	FinishRun(a2, nil)
+	FinishRun(b1, nil)
+	assert.Equal(t, 0, queue.running)
+	assert.Empty(t, queue.accounts)
+	assert.Empty(t, Running())
+}
+
+func TestNameLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	Store = &utils.FileStore{Dir: dir}
+	defer func() { Store = nil }()
+
+	acquire := func(sid string, dryRun bool) (*Run, chan error) {
+		r := &Run{SID: sid, Name: "eks-simple", Workspace: utils.DefaultWorkspace, DryRun: dryRun, Who: "ci"}
+		ctx, err := StartRun(context.Background(), r)
+		assert.NoError(t, err)
+		acquired := make(chan error, 1)
+		go func() { acquired <- Acquire(ctx, r) }()
+		return r, acquired
+	}
+
+	first, acquired := acquire("first", false)
+	assert.NoError(t, <-acquired)
+	locks, err := NameLocks(utils.DefaultWorkspace)
+	assert.NoError(t, err)
+	assert.Len(t, locks, 1)
+	assert.Equal(t, "first", locks[0].SID)
+	assert.Equal(t, "ci", locks[0].Who)
+	_, err = ForceUnlock(utils.DefaultWorkspace, "eks-simple")
+	assert.Equal(t, ErrLockHeld, err)
+
+	// Dry run of the same name waits for the first one as well
+	second, acquired := acquire("second", true)
+	assert.Eventually(t, func() bool { return len(QueuedRuns(utils.DefaultWorkspace)) == 1 }, time.Second, time.Millisecond)
+	FinishRun(first, nil)
+	assert.NoError(t, <-acquired)
+	locks, _ = NameLocks(utils.DefaultWorkspace)
+	assert.Equal(t, "second", locks[0].SID)
+	FinishRun(second, nil)
+	locks, _ = NameLocks(utils.DefaultWorkspace)
+	assert.Empty(t, locks)
+
+	// Lock left by Dice exited unexpectedly is rejected until it's unlocked
+	assert.NoError(t, Store.Put(nameLockKind, nameLockKey(utils.DefaultWorkspace, "eks-simple"), &NameLock{Name: "eks-simple", Workspace: utils.DefaultWorkspace, SID: "stale"}))
+	third, acquired := acquire("third", false)
+	err = <-acquired
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "locked by stale")
+	FinishRun(third, err)
+	lock, err := ForceUnlock(utils.DefaultWorkspace, "eks-simple")
+	assert.NoError(t, err)
+	assert.Equal(t, "stale", lock.SID)
+	_, err = ForceUnlock(utils.DefaultWorkspace, "eks-simple")
+	assert.Equal(t, ErrNotLocked, err)
+	assert.Empty(t, Running())
+}
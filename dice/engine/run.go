@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"context"
+	"dice/utils"
+	"errors"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// ErrShuttingDown rejects new deployments and stops running ones before their next stage
+var ErrShuttingDown = errors.New("Dice is shutting down")
+
+// Store persists state of deployments, such as checkpoints, which is set by Setup
+var Store *utils.FileStore
+
+// Run is a deployment in progress
+type Run struct {
+	SID       string
+	Name      string
+	Workspace string
+	DryRun    bool
+	Parallel  bool
+	Submitted []byte          // Submitted is the deployment as it was submitted, which is kept for resuming
+	Secrets   []string        // Secrets are values of secret parameters, which are masked in the checkpoint
+	Out       *websocket.Conn // Out is where the deployment is reported
+	Accounts  []string        // Accounts are AWS profile/region of the deployment, which are limited by concurrency per account
+	Who       string          // Who submitted the deployment
+
+	cancel   context.CancelFunc
+	queued   time.Time     // queued is when the deployment was queued
+	position int           // position in the queue, zero if it isn't waiting
+	waitFor  string        // waitFor tells why the deployment is queued
+	ready    chan struct{} // ready is closed when the deployment could run
+	admitted bool          // admitted tells if the deployment holds a slot of concurrency
+	locked   bool          // locked tells if the deployment holds lock of its name
+	reason   error         // reason why the queued deployment was cancelled
+}
+
+// report sends message to client of the deployment, or logs it if there's no client
+func (r *Run) report(format string, v ...interface{}) {
+	if r.Out != nil {
+		SRf(r.Out, format, v...)
+	} else {
+		log.Printf(format, v...)
+	}
+}
+
+// runs are deployments in progress by d-sid
+var runs = struct {
+	sync.Mutex
+	all      map[string]*Run
+	draining bool
+	wg       sync.WaitGroup
+}{all: map[string]*Run{}}
+
+// StartRun registers a deployment and persists its checkpoint, returned context is cancelled if grace period of shutdown expired.
+// ErrShuttingDown is returned if Dice is shutting down.
+func StartRun(ctx context.Context, r *Run) (context.Context, error) {
+	runs.Lock()
+	defer runs.Unlock()
+	if runs.draining {
+		return ctx, ErrShuttingDown
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	runs.all[r.SID] = r
+	runs.wg.Add(1)
+	if err := SaveCheckpoint(r, nil); err != nil {
+		log.Errorf("Failed to save checkpoint of %s : %s\n", r.SID, err)
+	}
+	return ctx, nil
+}
+
+// FinishRun persists checkpoint of the deployment with its result, and unregisters it
+func FinishRun(r *Run, result error) {
+	if err := SaveCheckpoint(r, result); err != nil {
+		log.Errorf("Failed to save checkpoint of %s : %s\n", r.SID, err)
+	}
+	r.cancel()
+	release(r)
+	runs.Lock()
+	delete(runs.all, r.SID)
+	runs.Unlock()
+	runs.wg.Done()
+}
+
+// Draining tells if Dice is shutting down
+func Draining() bool {
+	runs.Lock()
+	defer runs.Unlock()
+	return runs.draining
+}
+
+// Running returns deployments in progress
+func Running() []*Run {
+	runs.Lock()
+	defer runs.Unlock()
+	var all []*Run
+	for _, r := range runs.all {
+		all = append(all, r)
+	}
+	return all
+}
+
+// Drain rejects new deployments, and lets running deployments stop after their running stage.
+// Deployments still running after grace period are cancelled, so that commands are killed and their stages are Interrupted.
+// It returns after all deployments were checkpointed.
+func Drain(grace time.Duration) {
+	runs.Lock()
+	runs.draining = true
+	runs.Unlock()
+	cancelQueued(ErrShuttingDown)
+	for _, r := range Running() {
+		r.report("Dice is shutting down, deployment %s would be stopped after the running stage, or be interrupted in %s.", r.SID, grace)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		runs.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(grace):
+	}
+	for _, r := range Running() {
+		r.report("Interrupting deployment %s, grace period of shutdown expired.", r.SID)
+		r.cancel()
+	}
+	<-done
+}
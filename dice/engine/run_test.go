@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"context"
+	"dice/utils"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drain")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	Store = &utils.FileStore{Dir: dir}
+	defer func() {
+		Store = nil
+		runs.draining = false
+	}()
+
+	tg := map[string]*TilesGrid{
+		"network": {TileInstance: "network", TileName: "Network0", ExecutableOrder: 1, Status: Done.DSString()},
+		"eks":     {TileInstance: "eks", TileName: "Eks0", ExecutableOrder: 2, Status: Progress.DSString()},
+	}
+	AllTilesGrids["drain-sid"] = &tg
+	defer delete(AllTilesGrids, "drain-sid")
+
+	fast := &Run{SID: "fast-sid", Name: "fast", Workspace: utils.DefaultWorkspace}
+	_, err = StartRun(context.Background(), fast)
+	assert.NoError(t, err)
+	slow := &Run{SID: "drain-sid", Name: "eks-simple", Workspace: utils.DefaultWorkspace, Submitted: []byte("kind: Deployment\nspec:\n  key: ore-keypair"), Secrets: []string{"ore-keypair"}}
+	ctx, err := StartRun(context.Background(), slow)
+	assert.NoError(t, err)
+	cps, err := Checkpoints(utils.DefaultWorkspace)
+	assert.NoError(t, err)
+	assert.Len(t, cps, 2)
+
+	// fast one finishes in grace period, slow one is cancelled after grace period
+	go FinishRun(fast, nil)
+	go func() {
+		<-ctx.Done()
+		FinishRun(slow, errors.New("halted"))
+	}()
+	Drain(50 * time.Millisecond)
+	assert.Empty(t, Running())
+
+	_, err = StartRun(context.Background(), &Run{SID: "late-sid"})
+	assert.Equal(t, ErrShuttingDown, err)
+
+	var cp Checkpoint
+	found, err := Store.Get(checkpointKind, "drain-sid", &cp)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, Interrupted.DSString(), cp.Status)
+	assert.Equal(t, "halted", cp.Error)
+	assert.Equal(t, "eks", cp.ResumeFrom)
+	assert.Equal(t, "kind: Deployment\nspec:\n  key: '******'\n", cp.Submitted)
+	assert.Equal(t, utils.Digest(slow.Submitted), cp.Digest)
+	assert.Equal(t, []StageCheckpoint{
+		{TileInstance: "network", TileName: "Network0", Status: Done.DSString()},
+		{TileInstance: "eks", TileName: "Eks0", Status: Progress.DSString()},
+	}, cp.Stages)
+}
+
+func TestRecoverCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recover")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	Store = &utils.FileStore{Dir: dir}
+	defer func() { Store = nil }()
+
+	assert.NoError(t, Store.Put(checkpointKind, "running", &Checkpoint{SID: "running", Status: Progress.DSString()}))
+	assert.NoError(t, Store.Put(checkpointKind, "done", &Checkpoint{SID: "done", Status: Done.DSString()}))
+	recovered, err := RecoverCheckpoints()
+	assert.NoError(t, err)
+	assert.Len(t, recovered, 1)
+	assert.Equal(t, "running", recovered[0].SID)
+
+	var cp Checkpoint
+	Store.Get(checkpointKind, "running", &cp)
+	assert.Equal(t, Interrupted.DSString(), cp.Status)
+}
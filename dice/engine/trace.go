@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"dice/utils"
+	"strings"
+	"sync"
+)
+
+// startStageSpan starts span of stage, whose sub-steps are preparation, commands, output & postRun
+func startStageSpan(ctx context.Context, dSid string, stage *ExecutionStage) (context.Context, *utils.Span) {
+	return utils.StartSpan(ctx, "stage",
+		"dice.tile.instance", stage.Name,
+		"dice.tile", stage.TileName,
+		"dice.tile.category", stageCategory(dSid, stage.Name),
+		"dice.stage.kind", stage.Kind)
+}
+
+// stepTracer starts a span per command of script as per trace lines of `set -x`, such as '+ npm install',
+// so that time of npm, cdk, sleep & probe could be told apart. Commands are matched in order by the first word,
+// and labeled by up to 3 leading words without flags.
+type stepTracer struct {
+	ctx      context.Context
+	commands []string
+	current  *utils.Span
+	mu       sync.Mutex
+}
+
+// withSteps returns ctx with stepTracer of commands, which is fed by WsTail
+func withSteps(ctx context.Context, commands ...[]string) (context.Context, *stepTracer) {
+	st := &stepTracer{ctx: ctx}
+	for _, c := range commands {
+		st.commands = append(st.commands, c...)
+	}
+	return context.WithValue(ctx, "steps", st), st
+}
+
+// ctxSteps returns stepTracer in ctx, or nil
+func ctxSteps(ctx context.Context) *stepTracer {
+	st, _ := ctx.Value("steps").(*stepTracer)
+	return st
+}
+
+// Line ends the running command and starts the next one if line traces one of the remaining commands
+func (st *stepTracer) Line(line []byte) {
+	if !bytes.HasPrefix(line, []byte("+ ")) {
+		return
+	}
+	fields := strings.Fields(string(line[2:]))
+	if len(fields) == 0 {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for i, c := range st.commands {
+		words := strings.Fields(c)
+		if len(words) == 0 || words[0] != fields[0] {
+			continue
+		}
+		if st.current != nil {
+			st.current.End(nil)
+		}
+		name, label := "command", words[0]
+		if words[0] == "probe" {
+			name = "probe"
+		} else {
+			for _, w := range words[1:] {
+				if strings.HasPrefix(w, "-") || len(strings.Fields(label)) == 3 {
+					break
+				}
+				label += " " + w
+			}
+		}
+		_, st.current = utils.StartSpan(st.ctx, name, "dice.command", label)
+		st.commands = st.commands[i+1:]
+		return
+	}
+}
+
+// End ends the running command with the result of script
+func (st *stepTracer) End(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.current != nil {
+		st.current.End(err)
+		st.current = nil
+	}
+}
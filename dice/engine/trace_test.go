@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"context"
+	"dice/utils"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestStepTracer(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	utils.SetupTracing(exporter)
+	defer func() { utils.ShutdownTracing(context.Background()); utils.Tracing = nil }()
+
+	ctx, commands := utils.StartSpan(context.WithValue(context.Background(), "d-sid", "step-sid"), "commands")
+	_, st := withSteps(ctx, []string{"probe -command /tmp/dice-probe-1.sh -periodSeconds 5"}, []string{"cd $D_HOME", "npm install", "npm run build", "cdk deploy --require-approval never", "sleep 10"})
+	for _, line := range []string{
+		"+ export D_TBD_EKS_CLUSTER_NAME=eks",
+		"+ probe -command /tmp/dice-probe-1.sh -periodSeconds 5",
+		"waiting for probe ...",
+		"+ npm install",
+		"++ npm --version",
+		"+ npm run build",
+		"+ cdk deploy --require-approval never",
+		"+ sleep 10",
+		"+ echo 0",
+	} {
+		st.Line([]byte(line))
+	}
+	st.End(nil)
+	assert.NoError(t, utils.Tracing.ForceFlush(context.Background()))
+
+	var names, labels []string
+	for _, s := range exporter.GetSpans() {
+		names = append(names, s.Name)
+		for _, kv := range s.Attributes {
+			if kv.Key == "dice.command" {
+				labels = append(labels, kv.Value.AsString())
+			}
+		}
+		assert.Equal(t, commands.SpanContext().SpanID(), s.Parent.SpanID())
+	}
+	assert.Equal(t, []string{"probe", "command", "command", "command", "command"}, names)
+	assert.Equal(t, []string{"probe", "npm install", "npm run build", "cdk deploy", "sleep 10"}, labels)
+}
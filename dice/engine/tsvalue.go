@@ -18,10 +18,11 @@ const (
 	Progress                            // Progress indicate the deployment is running
 	Done                                // Done indicate the deployment is Done
 	Interrupted                         // Interrupted indicate the deployment stop at somewhere
+	Queued                              // Queued indicate the deployment is waiting for a slot of concurrency
 )
 
 func (c DeploymentStatus) DSString() string {
-	return [...]string{"Created", "Progress", "Done", "Interrupted"}[c]
+	return [...]string{"Created", "Progress", "Done", "Interrupted", "Queued"}[c]
 }
 
 // TilesGrid represents relationship table of all Tile for each deployment
@@ -34,6 +35,8 @@ type TilesGrid struct {
 	RootTileInstance    string   // RootTileInstance indicates Tiles are in the same group
 	ParentTileInstances []string // ParentTileInstances indicates who's dependent on Me - Tile
 	Status              string   // Status of deployment
+	TileDigest          string   // TileDigest is the digest of pulled Tile
+	Generated           bool     // Generated is true if TileInstance was generated as dependency
 }
 
 // DeploymentRecord is a record of each deployment
@@ -43,7 +46,9 @@ type DeploymentRecord struct {
 	Created     time.Time // Created time
 	Updated     time.Time // Updated time
 	SuperFolder string    // Main folder for all stuff per deployment
+	Workspace   string    // Workspace where the deployment is
 	Status      string    // Status of deployment
+	Position    int       `json:",omitempty"` // Position in the queue if it's Queued
 }
 
 // Ts represents all referred CDK resources
@@ -122,10 +127,11 @@ type Ts struct {
 	TsStacksMapN map[string]*TsStack       // TsStacksMap: TileInstance -> TsStack, all initialized values will be store here, include input, env, etc
 	AllTilesN    map[string]*v1alpha1.Tile // AllTiles: TileInstance -> Tile
 	AllOutputsN  *map[string]*TsOutput     // AllOutputs:  TileInstance ->TsOutput, all output values will be store here
+	secrets      []string                  // secrets are values of secret parameters, which are masked when Ts is shown
 }
 
-// AllTs represents all information about tiles, input, output, etc.,  id(uuid) -> Ts
-var AllTs = make(map[string]Ts)
+// AllTs represents all information about tiles, input, output, etc. partitioned by workspace,  workspace -> id(uuid) -> Ts
+var AllTs = make(map[string]map[string]Ts)
 
 // AllTilesGrid store all Tiles relationship, id(uuid) -> (tile-instance -> TilesGrid)
 var AllTilesGrids = make(map[string]*map[string]*TilesGrid)
@@ -155,7 +161,7 @@ func DependentEKSTile(dSid string, tileInstance string) *v1alpha1.Tile {
 	if allTG, ok := AllTilesGrids[dSid]; ok {
 		for _, v := range *allTG {
 			if utils.Contains(pTileInstance, v.TileInstance) {
-				if at, ok := AllTs[dSid]; ok {
+				if at, ok := LoadTs(dSid); ok {
 					if tile, ok := at.AllTilesN[v.TileInstance]; ok {
 						if tile.Metadata.VendorService == v1alpha1.EKS.VSString() {
 							return tile
@@ -175,7 +181,7 @@ func AllDependentTiles(dSid string, tileInstance string) []v1alpha1.Tile {
 		var tiles []v1alpha1.Tile
 		for _, v := range *allTG {
 			if utils.Contains(v.ParentTileInstances, tileInstance) {
-				if at, ok := AllTs[dSid]; ok {
+				if at, ok := LoadTs(dSid); ok {
 					if tile, ok := at.AllTilesN[v.TileInstance]; ok {
 						tiles = append(tiles, *tile)
 					}
@@ -229,7 +235,7 @@ func ReferencedTsStack(dSid string, rootTileInstance string, tileName string) *T
 		for _, v := range *allTG {
 			if v.RootTileInstance == rootTileInstance {
 				if v.TileName == tileName {
-					if ts, ok := AllTs[dSid]; ok {
+					if ts, ok := LoadTs(dSid); ok {
 						return ts.TsStacksMapN[v.TileInstance]
 					}
 				}
@@ -242,7 +248,7 @@ func ReferencedTsStack(dSid string, rootTileInstance string, tileName string) *T
 // ValueRef return actual value of referred input/output
 func ValueRef(dSid string, ref string, ti string) (string, error) {
 	if strings.Contains(ref, "$") {
-		re := regexp.MustCompile(`^\$\(([[:alnum:]]*\.[[:alnum:]]*\.[[:alnum:]]*)\)$`)
+		re := regexp.MustCompile(`^\$\(([[:alnum:]_-]*\.[[:alnum:]]*\.[[:alnum:]]*)\)$`)
 		ms := re.FindStringSubmatch(ref)
 		if len(ms) == 2 {
 			str := strings.Split(ms[1], ".")
@@ -252,7 +258,7 @@ func ValueRef(dSid string, ref string, ti string) (string, error) {
 			if tileInstance == "self" && ti != "" {
 				tileInstance = ti
 			}
-			if at, ok := AllTs[dSid]; ok {
+			if at, ok := LoadTs(dSid); ok {
 
 				switch where {
 				case "inputs":
@@ -319,7 +325,7 @@ func ParentTileInstance(dSid string, tileInstance string) []string {
 func CDKAllValueRef(dSid string, str string) (string, error) {
 	max := strings.Count(str, "$")
 	for {
-		re := regexp.MustCompile(`^.*\$cdk\(([[:alnum:]]*\.[[:alnum:]]*\.[[:alnum:]]*)\).*$`)
+		re := regexp.MustCompile(`^.*\$cdk\(([[:alnum:]_-]*\.[[:alnum:]]*\.[[:alnum:]]*)\).*$`)
 		s := re.FindStringSubmatch(str)
 		//
 		if len(s) == 2 {
@@ -397,25 +403,37 @@ func AllRootTileInstance(dSid string) []string {
 
 // TsContent returns content as per d-sid
 func TsContent(sid string) *Ts {
-	if ts, ok := AllTs[sid]; ok {
+	if ts, ok := LoadTs(sid); ok {
 		return &ts
 	}
 	return nil
 }
 
-// AllTsDeployment returns all records of deployment
-func AllTsDeployment() []DeploymentRecord {
+// Secrets returns values of secret parameters of the deployment, which are masked when Ts or plan is shown
+func Secrets(sid string) []string {
+	if ts, ok := LoadTs(sid); ok {
+		return ts.secrets
+	}
+	return nil
+}
+
+// AllTsDeployment returns all records of deployment in the workspace
+func AllTsDeployment(workspace string) []DeploymentRecord {
 	var ds []DeploymentRecord
-	for _, ts := range AllTs {
+	for _, ts := range AllTs[workspace] {
 		ds = append(ds, *ts.DR)
 	}
+	// Queued deployments haven't been assembled yet
+	for _, q := range QueuedRuns(workspace) {
+		ds = append(ds, DeploymentRecord{SID: q.SID, Name: q.Name, Created: q.Queued, Updated: q.Queued, Workspace: q.Workspace, Status: Queued.DSString(), Position: q.Position})
+	}
 	return ds
 }
 
-// IsRepeatedDeployment return flag of repeated deployment and sid if repeated
-func IsRepeatedDeployment(name string) (string, bool) {
-	tss := make([]Ts, 0, len(AllTs))
-	for _, ts := range AllTs {
+// IsRepeatedDeployment return flag of repeated deployment in the workspace and sid if repeated
+func IsRepeatedDeployment(workspace string, name string) (string, bool) {
+	tss := make([]Ts, 0, len(AllTs[workspace]))
+	for _, ts := range AllTs[workspace] {
 		tss = append(tss, ts)
 	}
 	// By create time (descending)
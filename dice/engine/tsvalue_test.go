@@ -2,6 +2,7 @@ package engine
 
 import (
 	"dice/apis/v1alpha1"
+	"dice/utils"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -48,30 +49,33 @@ var tilesGridMap2 = make(map[string]*TilesGrid)
 var tilesGridMap3 = make(map[string]*TilesGrid)
 
 func init() {
-	AllTs[dSid[0]] = Ts{
+	StoreTs(Ts{
+		DR: &DeploymentRecord{SID: dSid[0], Workspace: utils.DefaultWorkspace},
 		AllTilesN: map[string]*v1alpha1.Tile{
 			"tileInstance01": &v1alpha1.Tile{},
 			"tileInstance02": &v1alpha1.Tile{},
 			"tileInstance03": &v1alpha1.Tile{},
 			"tileInstance04": &v1alpha1.Tile{},
 		},
-	}
-	AllTs[dSid[1]] = Ts{
+	})
+	StoreTs(Ts{
+		DR: &DeploymentRecord{SID: dSid[1], Workspace: utils.DefaultWorkspace},
 		AllTilesN: map[string]*v1alpha1.Tile{
 			"tileInstance01": &v1alpha1.Tile{},
 			"tileInstance02": &v1alpha1.Tile{},
 			"tileInstance03": &v1alpha1.Tile{},
 			"tileInstance04": &v1alpha1.Tile{},
 		},
-	}
-	AllTs[dSid[2]] = Ts{
+	})
+	StoreTs(Ts{
+		DR: &DeploymentRecord{SID: dSid[2], Workspace: utils.DefaultWorkspace},
 		AllTilesN: map[string]*v1alpha1.Tile{
 			"tileInstance01": &v1alpha1.Tile{},
 			"tileInstance02": &v1alpha1.Tile{},
 			"tileInstance03": &v1alpha1.Tile{},
 			"tileInstance04": &v1alpha1.Tile{},
 		},
-	}
+	})
 	tilesGridMap1[tilesGrid1.TileInstance] = &tilesGrid1
 	tilesGridMap1[tilesGrid2.TileInstance] = &tilesGrid2
 	tilesGridMap1[tilesGrid3.TileInstance] = &tilesGrid3
@@ -0,0 +1,75 @@
+package engine
+
+import (
+	"context"
+	"dice/utils"
+)
+
+// Workspaces are configuration of all workspaces by name, including the default one
+var Workspaces = map[string]*utils.DiceConfig{}
+
+// WorkspaceConfig returns configuration of workspace, the default one if name is empty
+func WorkspaceConfig(name string) (*utils.DiceConfig, bool) {
+	if name == "" {
+		name = utils.DefaultWorkspace
+	}
+	dc, ok := Workspaces[name]
+	return dc, ok
+}
+
+// Config returns configuration of the workspace where the deployment is
+func (dr *DeploymentRecord) Config() *utils.DiceConfig {
+	if dc, ok := WorkspaceConfig(dr.Workspace); ok {
+		return dc
+	}
+	return DiceConfig
+}
+
+// CtxWorkspace returns name of workspace in context, the default one if it wasn't given
+func CtxWorkspace(ctx context.Context) string {
+	if w, ok := ctx.Value("workspace").(string); ok && w != "" {
+		return w
+	}
+	return utils.DefaultWorkspace
+}
+
+// InWorkspace tells if the deployment of d-sid is in the workspace
+func InWorkspace(workspace string, dSid string) bool {
+	_, ok := AllTs[workspace][dSid]
+	return ok
+}
+
+// LoadTs returns Ts of the deployment, d-sid is unique across workspaces
+func LoadTs(dSid string) (Ts, bool) {
+	for _, partition := range AllTs {
+		if ts, ok := partition[dSid]; ok {
+			return ts, true
+		}
+	}
+	return Ts{}, false
+}
+
+// StoreTs keeps Ts in partition of the workspace where the deployment is
+func StoreTs(ts Ts) {
+	partition, ok := AllTs[ts.DR.Workspace]
+	if !ok {
+		partition = make(map[string]Ts)
+		AllTs[ts.DR.Workspace] = partition
+	}
+	partition[ts.DR.SID] = ts
+}
+
+// DeleteTs removes Ts of the deployment
+func DeleteTs(dSid string) {
+	for _, partition := range AllTs {
+		delete(partition, dSid)
+	}
+}
+
+// ResolveSID returns d-sid of deployment in the workspace as per d-sid or name, the latest one would be chosen by name
+func ResolveSID(workspace string, sidOrName string) (string, bool) {
+	if InWorkspace(workspace, sidOrName) {
+		return sidOrName, true
+	}
+	return IsRepeatedDeployment(workspace, sidOrName)
+}
@@ -1,11 +1,19 @@
 package main
 
 import (
-	"context"
-	"dice/web"
-	log "github.com/sirupsen/logrus"
+	"dice/cmd/serve"
+	"dice/cmd/version"
+	"github.com/spf13/cobra"
+	"os"
 )
 
 func main() {
-	log.Fatal(web.Router(context.Background()).Run("0.0.0.0:9090"))
+	var cmd = &cobra.Command{
+		Use:   "dice",
+		Short: "Dice assembles Tiles into deployments and executes them",
+	}
+	cmd.AddCommand(serve.Serve, version.Version)
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }
@@ -3,6 +3,7 @@ new function() {
     var connected = false;
 
     var serverUrl;
+    var accessToken;
     var connectionStatus;
     var sendMessage;
 
@@ -12,6 +13,11 @@ new function() {
 
     var open = function() {
         var url = serverUrl.val();
+        // Browsers can't set header of WebSocket, so token goes with access_token query
+        var token = accessToken.val();
+        if (token) {
+            url += (url.indexOf('?') < 0 ? '?' : '&') + 'access_token=' + encodeURIComponent(token);
+        }
         ws = new WebSocket(url);
         ws.onopen = onOpen;
         ws.onclose = onClose;
@@ -20,6 +26,7 @@ new function() {
 
         connectionStatus.text('OPENING ...');
         serverUrl.attr('disabled', 'disabled');
+        accessToken.attr('disabled', 'disabled');
         connectButton.hide();
         disconnectButton.show();
     }
@@ -33,6 +40,7 @@ new function() {
         connectionStatus.text('CLOSED');
 
         serverUrl.removeAttr('disabled');
+        accessToken.removeAttr('disabled');
         connectButton.show();
         disconnectButton.hide();
         sendMessage.attr('disabled', 'disabled');
@@ -83,6 +91,7 @@ new function() {
     WebSocketClient = {
         init: function() {
             serverUrl = $('#serverUrl');
+            accessToken = $('#accessToken');
             connectionStatus = $('#connectionStatus');
             sendMessage = $('#sendMessage');
 
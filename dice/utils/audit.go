@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Audited actions, which change state of Dice or deployments
+const (
+	AuditDeploy       = "deploy"
+	AuditDryRun       = "dry-run"
+	AuditCancel       = "cancel"
+	AuditDiagnostic   = "diagnostic"
+	AuditPushTile     = "push-tile"
+	AuditPushHu       = "push-hu"
+	AuditRebuildIndex = "rebuild-index"
+	AuditUnlock       = "unlock"
+	AuditSweep        = "sweep"
+)
+
+// Results of audited actions
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry is a record of an audited action
+type AuditEntry struct {
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`
+	Who       string            `json:"who"`
+	Role      string            `json:"role,omitempty"`
+	Source    string            `json:"source"` // Source is address of caller
+	Workspace string            `json:"workspace"`
+	Name      string            `json:"name,omitempty"`   // Name is name of deployment, Tile or Hu
+	SID       string            `json:"sid,omitempty"`    // SID is d-sid of deployment
+	Digest    string            `json:"digest,omitempty"` // Digest is hash of submitted YAML or package
+	Result    string            `json:"result"`
+	Error     string            `json:"error,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// AuditFilter filters audit entries, empty fields match all
+type AuditFilter struct {
+	Workspace string    `form:"-"`
+	Action    string    `form:"action"`
+	Who       string    `form:"who"`
+	Name      string    `form:"name"`
+	SID       string    `form:"sid"`
+	Result    string    `form:"result"`
+	Since     time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until     time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit     int       `form:"limit"` // Limit is max number of the latest entries, 100 by default
+}
+
+// Match tells if the entry matches the filter
+func (af *AuditFilter) Match(e *AuditEntry) bool {
+	for _, f := range []struct{ want, value string }{
+		{af.Workspace, e.Workspace}, {af.Action, e.Action}, {af.Who, e.Who}, {af.Name, e.Name}, {af.SID, e.SID}, {af.Result, e.Result},
+	} {
+		if f.want != "" && f.want != f.value {
+			return false
+		}
+	}
+	return (af.Since.IsZero() || !e.Time.Before(af.Since)) && (af.Until.IsZero() || e.Time.Before(af.Until))
+}
+
+// AuditSink stores audit entries
+type AuditSink interface {
+	// Write appends an entry
+	Write(e *AuditEntry) error
+}
+
+// AuditQuerier is a sink which can be queried
+type AuditQuerier interface {
+	// Query returns matched entries, the latest one first
+	Query(filter *AuditFilter) ([]AuditEntry, error)
+}
+
+// AuditCloser is a sink which writes entries in background, and must be closed to flush them
+type AuditCloser interface {
+	// Close flushes queued entries until ctx is done
+	Close(ctx context.Context) error
+}
+
+// FileAuditSink appends entries as JSON lines into a file
+type FileAuditSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Write appends the entry as a line
+func (fs *FileAuditSink) Write(e *AuditEntry) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(fs.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fs.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(buf, '\n'))
+	return err
+}
+
+// Query scans the file and returns the latest matched entries
+func (fs *FileAuditSink) Query(filter *AuditFilter) ([]AuditEntry, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	entries := []AuditEntry{}
+	f, err := os.Open(fs.Path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			log.Warningf("Skipped broken audit entry: %s\n", err)
+			continue
+		}
+		if filter.Match(&e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// auditQueueSize is the limit of entries waiting for the HTTP sink, new entries are dropped beyond it
+const auditQueueSize = 1024
+
+// HTTPAuditSink posts each entry as a JSON line to URL in background, such as a log collector, so that actions aren't held by it
+type HTTPAuditSink struct {
+	URL    string
+	Client *http.Client
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan []byte
+	done   chan struct{}
+}
+
+// NewHTTPAuditSink creates the sink with a queue of size, it must be closed to flush the rest
+func NewHTTPAuditSink(url string, client *http.Client, size int) *HTTPAuditSink {
+	hs := &HTTPAuditSink{URL: url, Client: client, queue: make(chan []byte, size), done: make(chan struct{})}
+	go func() {
+		defer close(hs.done)
+		for buf := range hs.queue {
+			if err := hs.post(buf); err != nil {
+				log.Errorf("Failed to post audit entry: %s\n", err)
+			}
+		}
+	}()
+	return hs
+}
+
+// Write queues the entry, it's dropped if the queue is full or the sink was closed
+func (hs *HTTPAuditSink) Write(e *AuditEntry) error {
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	if hs.closed {
+		return errors.Errorf("audit sink %s was closed", hs.URL)
+	}
+	select {
+	case hs.queue <- append(buf, '\n'):
+		return nil
+	default:
+		return errors.Errorf("audit sink %s is full, %d entries are waiting", hs.URL, len(hs.queue))
+	}
+}
+
+func (hs *HTTPAuditSink) post(buf []byte) error {
+	resp, err := hs.Client.Post(hs.URL, "application/x-ndjson", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("audit sink %s responded %s", hs.URL, resp.Status)
+	}
+	return nil
+}
+
+// Close stops accepting entries and waits for queued ones to be posted until ctx is done
+func (hs *HTTPAuditSink) Close(ctx context.Context) error {
+	hs.mu.Lock()
+	if !hs.closed {
+		hs.closed = true
+		close(hs.queue)
+	}
+	hs.mu.Unlock()
+	select {
+	case <-hs.done:
+		return nil
+	case <-ctx.Done():
+		return errors.Errorf("audit sink %s was closed with %d entries not posted", hs.URL, len(hs.queue))
+	}
+}
+
+// Auditor writes entries into all sinks
+type Auditor struct {
+	Sinks []AuditSink
+}
+
+// NewAuditor creates Auditor with a file sink, and a HTTP sink if url isn't empty
+func NewAuditor(file string, url string) *Auditor {
+	a := &Auditor{Sinks: []AuditSink{&FileAuditSink{Path: file}}}
+	if url != "" {
+		a.Sinks = append(a.Sinks, NewHTTPAuditSink(url, &http.Client{Timeout: 5 * time.Second}, auditQueueSize))
+	}
+	return a
+}
+
+// Record writes the entry into all sinks, failures are logged without interrupting the action
+func (a *Auditor) Record(e *AuditEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	for _, s := range a.Sinks {
+		if err := s.Write(e); err != nil {
+			log.Errorf("Failed to write audit entry of %s by %s: %s\n", e.Action, e.Who, err)
+		}
+	}
+}
+
+// Close closes sinks which post entries in background, queued entries are flushed until ctx is done
+func (a *Auditor) Close(ctx context.Context) {
+	for _, s := range a.Sinks {
+		if c, ok := s.(AuditCloser); ok {
+			if err := c.Close(ctx); err != nil {
+				log.Errorf("Failed to close audit sink: %s\n", err)
+			}
+		}
+	}
+}
+
+// Query returns entries from the first sink which can be queried
+func (a *Auditor) Query(filter *AuditFilter) ([]AuditEntry, error) {
+	for _, s := range a.Sinks {
+		if q, ok := s.(AuditQuerier); ok {
+			return q.Query(filter)
+		}
+	}
+	return nil, errors.New("no audit sink can be queried")
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuditor(t *testing.T) {
+	var posted []AuditEntry
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e AuditEntry
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		mu.Lock()
+		posted = append(posted, e)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "audit")
+	a := NewAuditor(filepath.Join(dir, "audit", "audit.jsonl"), server.URL)
+	now := time.Now().UTC()
+	for _, e := range []AuditEntry{
+		{Action: AuditDeploy, Who: "alice", Workspace: DefaultWorkspace, Name: "jazz", SID: "sid-1", Result: AuditSuccess, Time: now.Add(-3 * time.Hour)},
+		{Action: AuditDryRun, Who: "bob", Workspace: DefaultWorkspace, Name: "jazz", SID: "sid-2", Result: AuditSuccess, Time: now.Add(-2 * time.Hour)},
+		{Action: AuditDeploy, Who: "bob", Workspace: DefaultWorkspace, Name: "jazz", SID: "sid-3", Result: AuditFailure, Time: now.Add(-time.Hour)},
+		{Action: AuditDeploy, Who: "alice", Workspace: "team-a", Name: "jazz", SID: "sid-4", Result: AuditSuccess},
+	} {
+		e := e
+		a.Record(&e)
+	}
+	// Entries are posted in background, and flushed by close
+	a.Close(context.Background())
+	assert.Len(t, posted, 4)
+	assert.Equal(t, "sid-4", posted[3].SID)
+	assert.False(t, posted[3].Time.IsZero())
+
+	sids := func(filter AuditFilter) []string {
+		entries, err := a.Query(&filter)
+		assert.NoError(t, err)
+		sids := []string{}
+		for _, e := range entries {
+			sids = append(sids, e.SID)
+		}
+		return sids
+	}
+	assert.Equal(t, []string{"sid-4", "sid-3", "sid-2", "sid-1"}, sids(AuditFilter{}))
+	assert.Equal(t, []string{"sid-3", "sid-2", "sid-1"}, sids(AuditFilter{Workspace: DefaultWorkspace}))
+	assert.Equal(t, []string{"sid-3", "sid-1"}, sids(AuditFilter{Workspace: DefaultWorkspace, Action: AuditDeploy}))
+	assert.Equal(t, []string{"sid-3", "sid-2"}, sids(AuditFilter{Who: "bob"}))
+	assert.Equal(t, []string{"sid-3"}, sids(AuditFilter{Result: AuditFailure}))
+	assert.Equal(t, []string{"sid-2", "sid-1"}, sids(AuditFilter{Until: now.Add(-time.Hour)}))
+	assert.Equal(t, []string{"sid-4", "sid-3"}, sids(AuditFilter{Since: now.Add(-time.Hour)}))
+	assert.Equal(t, []string{"sid-4", "sid-3"}, sids(AuditFilter{Limit: 2}))
+}
+
+func TestHTTPAuditSink(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	// Writing isn't held by a slow sink, entries beyond the queue are dropped
+	hs := NewHTTPAuditSink(server.URL, server.Client(), 2)
+	started := time.Now()
+	var errs int
+	for i := 0; i < 5; i++ {
+		if err := hs.Write(&AuditEntry{Action: AuditDeploy, SID: "sid"}); err != nil {
+			errs++
+		}
+	}
+	assert.True(t, time.Since(started) < time.Second)
+	assert.True(t, errs >= 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Error(t, hs.Close(ctx))
+	assert.Error(t, hs.Write(&AuditEntry{Action: AuditDeploy}))
+	close(release)
+	assert.NoError(t, hs.Close(context.Background()))
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/pkg/errors"
+	yamlv2 "gopkg.in/yaml.v2"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Enumeration for Role, a higher Role includes all permissions of lower ones
+type Role int
+
+const (
+	NoRole Role = iota
+	Viewer
+	Deployer
+	Admin
+)
+
+func (r Role) RString() string {
+	return [...]string{"", "viewer", "deployer", "admin"}[r]
+}
+
+// ParseRole returns Role as per name, NoRole if it's unknown
+func ParseRole(name string) Role {
+	for _, r := range []Role{Viewer, Deployer, Admin} {
+		if strings.EqualFold(name, r.RString()) {
+			return r
+		}
+	}
+	return NoRole
+}
+
+// AuthConfig is configuration of authentication & authorization, which is loaded from M_AUTH_CONFIG
+type AuthConfig struct {
+	Tokens         []StaticToken `yaml:"tokens"`         // Tokens are static bearer tokens
+	JWT            *JWTConfig    `yaml:"jwt"`            // JWT verifies OIDC/JWT bearer tokens
+	AllowedOrigins []string      `yaml:"allowedOrigins"` // AllowedOrigins of WebSocket besides same origin, '*' allows all
+	CookieSecret   string        `yaml:"cookieSecret"`   // CookieSecret signs session cookie
+}
+
+// StaticToken is a bearer token with Role
+type StaticToken struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+// JWTConfig verifies RS256 JWT against local JWKS file
+type JWTConfig struct {
+	JWKSFile  string            `yaml:"jwksFile"`
+	Issuer    string            `yaml:"issuer"`
+	Audience  string            `yaml:"audience"`
+	RoleClaim string            `yaml:"roleClaim"` // RoleClaim is claim of Role, string or array, default: roles
+	RoleMap   map[string]string `yaml:"roleMap"`   // RoleMap maps value of claim to Role, eg: platform-team -> admin
+	keys      map[string]*rsa.PublicKey
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// LoadAuthConfig loads configuration & JWKS
+func LoadAuthConfig(file string) (*AuthConfig, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var ac AuthConfig
+	if err := yamlv2.UnmarshalStrict(buf, &ac); err != nil {
+		return nil, errors.Wrap(err, "auth configuration was invalid")
+	}
+	for _, t := range ac.Tokens {
+		if t.Token == "" || ParseRole(t.Role) == NoRole {
+			return nil, errors.New("token or role of static token was invalid: " + t.Name)
+		}
+	}
+	if ac.JWT != nil {
+		if ac.JWT.RoleClaim == "" {
+			ac.JWT.RoleClaim = "roles"
+		}
+		if ac.JWT.keys, err = loadJWKS(ac.JWT.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	return &ac, nil
+}
+
+// Authenticate verifies bearer token, static tokens go first
+func (ac *AuthConfig) Authenticate(token string) (*Principal, error) {
+	if token == "" {
+		return nil, errors.New("bearer token is required")
+	}
+	for _, t := range ac.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return &Principal{Name: t.Name, Role: ParseRole(t.Role)}, nil
+		}
+	}
+	if ac.JWT != nil && strings.Count(token, ".") == 2 {
+		return ac.JWT.verify(token, time.Now())
+	}
+	return nil, errors.New("bearer token was invalid")
+}
+
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func loadJWKS(file string) (map[string]*rsa.PublicKey, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := json.Unmarshal(buf, &set); err != nil {
+		return nil, errors.Wrap(err, "JWKS was invalid")
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "modulus of key was invalid: "+k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "exponent of key was invalid: "+k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA key in JWKS: " + file)
+	}
+	return keys, nil
+}
+
+// verify verifies signature & claims of RS256 JWT
+func (jc *JWTConfig) verify(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, errors.New("algorithm of JWT was unsupported: " + header.Alg)
+	}
+	key, ok := jc.keys[header.Kid]
+	if !ok {
+		return nil, errors.New("key of JWT wasn't found: " + header.Kid)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "signature of JWT was invalid")
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig); err != nil {
+		return nil, errors.Wrap(err, "signature of JWT was invalid")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if exp, ok := claims["exp"].(float64); !ok || now.Unix() >= int64(exp) {
+		return nil, errors.New("JWT was expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return nil, errors.New("JWT isn't valid yet")
+	}
+	if jc.Issuer != "" && claims["iss"] != jc.Issuer {
+		return nil, errors.New("issuer of JWT was invalid")
+	}
+	if jc.Audience != "" && !claimContains(claims["aud"], jc.Audience) {
+		return nil, errors.New("audience of JWT was invalid")
+	}
+
+	p := &Principal{}
+	p.Name, _ = claims["sub"].(string)
+	var values []interface{}
+	switch v := claims[jc.RoleClaim].(type) {
+	case string:
+		values = []interface{}{v}
+	case []interface{}:
+		values = v
+	}
+	for _, v := range values {
+		name, _ := v.(string)
+		if mapped, ok := jc.RoleMap[name]; ok {
+			name = mapped
+		}
+		if r := ParseRole(name); r > p.Role {
+			p.Role = r
+		}
+	}
+	if p.Role == NoRole {
+		return nil, errors.New("JWT didn't include any role")
+	}
+	return p, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	buf, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return errors.Wrap(err, "JWT was invalid")
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return errors.Wrap(err, "JWT was invalid")
+	}
+	return nil
+}
+
+func claimContains(claim interface{}, value string) bool {
+	switch c := claim.(type) {
+	case string:
+		return c == value
+	case []interface{}:
+		for _, v := range c {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
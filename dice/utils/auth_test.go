@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signing))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	assert.NoError(t, err)
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestAuthConfig_Authenticate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "auth")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kid": "key-1",
+		"kty": "RSA",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "jwks.json"), jwks, 0644))
+	config := `tokens:
+  - name: ci
+    token: ci-token
+    role: deployer
+jwt:
+  jwksFile: ` + filepath.Join(dir, "jwks.json") + `
+  issuer: https://idp.example.com
+  audience: dice
+  roleMap:
+    platform-team: admin
+`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "auth.yaml"), []byte(config), 0644))
+	ac, err := LoadAuthConfig(filepath.Join(dir, "auth.yaml"))
+	assert.NoError(t, err)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	tests := []struct {
+		name  string
+		token string
+		want  *Principal
+	}{
+		{"static token", "ci-token", &Principal{Name: "ci", Role: Deployer}},
+		{"unknown token", "nothing", nil},
+		{"empty token", "", nil},
+		{"jwt", signJWT(t, key, "key-1", map[string]interface{}{
+			"sub": "alice", "iss": "https://idp.example.com", "aud": []string{"dice"}, "exp": exp, "roles": []string{"viewer", "platform-team"},
+		}), &Principal{Name: "alice", Role: Admin}},
+		{"jwt without role", signJWT(t, key, "key-1", map[string]interface{}{
+			"sub": "bob", "iss": "https://idp.example.com", "aud": "dice", "exp": exp,
+		}), nil},
+		{"expired jwt", signJWT(t, key, "key-1", map[string]interface{}{
+			"sub": "alice", "iss": "https://idp.example.com", "aud": "dice", "exp": time.Now().Add(-time.Hour).Unix(), "roles": "viewer",
+		}), nil},
+		{"wrong audience", signJWT(t, key, "key-1", map[string]interface{}{
+			"sub": "alice", "iss": "https://idp.example.com", "aud": "other", "exp": exp, "roles": "viewer",
+		}), nil},
+		{"unknown key", signJWT(t, key, "key-2", map[string]interface{}{
+			"sub": "alice", "iss": "https://idp.example.com", "aud": "dice", "exp": exp, "roles": "viewer",
+		}), nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := ac.Authenticate(test.token)
+			assert.Equal(t, test.want, p)
+			assert.Equal(t, test.want == nil, err != nil)
+		})
+	}
+}
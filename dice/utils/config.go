@@ -1,7 +1,9 @@
 package utils
 
-// DiceConfig includes all key configuration.
+// DiceConfig includes all key configuration, and each workspace has its own one.
 type DiceConfig struct {
+	Workspace string // Workspace is name of workspace
+
 	WorkHome   string // WorkHome is the main working folder for all activities
 	Region     string // Region is where the S3 bucket is
 	BucketName string // BucketName is the name S3 bucket
@@ -14,4 +16,20 @@ type DiceConfig struct {
 
 	LocalRepo string // LocalRepo is folder to store Tiles on 'dev' mode
 
+	Profile string          // Profile is default AWS profile of Tiles
+	Members map[string]Role // Members are roles in workspace by name of principal, all callers are allowed if it's empty
+
+	Auth *AuthConfig `json:"-"` // Auth is nil if authentication was disabled
+
+	Audit *Auditor `json:"-"` // Audit records state-changing actions
+
+	TLSCert     string // TLSCert is certificate file, Dice serves HTTPS if it's not empty
+	TLSKey      string // TLSKey is private key file of certificate
+	TLSClientCA string // TLSClientCA is CA file to verify client certificate, mTLS is enabled if it's not empty
+
+	Diagnostics bool // Diagnostics enables allowlisted kubectl commands against deployments for admin
+
+	TraceExporter string // TraceExporter is otlp or stdout, tracing is disabled if it's empty
+	TraceEndpoint string // TraceEndpoint is OTLP/HTTP endpoint, such as http://localhost:4318
+
 }
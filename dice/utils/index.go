@@ -0,0 +1,499 @@
+package utils
+
+import (
+	"context"
+	"dice/apis/v1alpha1"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IndexFile is the index of repo, which is placed on the root of repo
+const IndexFile = "index.yaml"
+
+// ErrIndexNotFound indicates there's no index in the repo
+var ErrIndexNotFound = errors.New("index of repo wasn't existed")
+
+// cachedIndex is cached index with ETag
+type cachedIndex struct {
+	etag  string
+	index *v1alpha1.RepoIndex
+}
+
+// indexCache caches index as per location: repo location -> cachedIndex
+var indexCache = struct {
+	sync.Mutex
+	entries map[string]*cachedIndex
+}{entries: make(map[string]*cachedIndex)}
+
+// indexMutex serializes updating index
+var indexMutex sync.Mutex
+
+// indexLocation returns where index is, either url or local file
+func (dc *DiceConfig) indexLocation() string {
+	if dc.Mode == "dev" {
+		return filepath.Join(dc.LocalRepo, IndexFile)
+	}
+	return fmt.Sprintf("https://%s.s3-%s.amazonaws.com/%s", dc.BucketName, dc.Region, IndexFile)
+}
+
+// LoadIndex returns index of repo and its ETag, index would be reloaded only if it was changed
+func (dc *DiceConfig) LoadIndex(ctx context.Context) (loaded *v1alpha1.RepoIndex, etag string, err error) {
+	defer dc.countRepoError("load-index", &err)
+	location := dc.indexLocation()
+	indexCache.Lock()
+	cached := indexCache.entries[location]
+	indexCache.Unlock()
+
+	var buf []byte
+	if dc.Mode == "dev" {
+		info, err := os.Stat(location)
+		if os.IsNotExist(err) {
+			return nil, "", ErrIndexNotFound
+		} else if err != nil {
+			return nil, "", err
+		}
+		etag = fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+		if cached != nil && cached.etag == etag {
+			return cached.index, cached.etag, nil
+		}
+		if buf, err = ioutil.ReadFile(location); err != nil {
+			return nil, "", err
+		}
+	} else {
+		if Client == nil {
+			initHttpClient()
+		}
+		req, err := http.NewRequest(http.MethodGet, location, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		if cached != nil {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		resp, err := Client.Do(req)
+		if err != nil {
+			log.Printf("API call was failed from %s with Err: %s. \n", location, err)
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		switch resp.StatusCode {
+		case http.StatusNotModified:
+			return cached.index, cached.etag, nil
+		case http.StatusOK:
+			etag = resp.Header.Get("ETag")
+			if buf, err = ioutil.ReadAll(resp.Body); err != nil {
+				return nil, "", err
+			}
+		case http.StatusNotFound, http.StatusForbidden:
+			return nil, "", ErrIndexNotFound
+		default:
+			return nil, "", fmt.Errorf("failed to load %s : %s", location, resp.Status)
+		}
+	}
+
+	var index v1alpha1.RepoIndex
+	if err := yaml.Unmarshal(buf, &index); err != nil {
+		return nil, "", err
+	}
+	if etag == "" {
+		etag = `"` + strings.TrimPrefix(Digest(buf), "sha256:") + `"`
+	}
+	indexCache.Lock()
+	indexCache.entries[location] = &cachedIndex{etag: etag, index: &index}
+	indexCache.Unlock()
+	return &index, etag, nil
+}
+
+// SaveIndex stores index into repo
+func (dc *DiceConfig) SaveIndex(index *v1alpha1.RepoIndex) error {
+	index.ApiVersion = "mahjong.io/v1alpha1"
+	index.Generated = time.Now()
+	buf, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if dc.Mode == "dev" {
+		err = ioutil.WriteFile(dc.indexLocation(), buf, 0644)
+	} else {
+		err = dc.PutObjectS3(IndexFile, buf)
+	}
+	if err == nil {
+		indexCache.Lock()
+		delete(indexCache.entries, dc.indexLocation())
+		indexCache.Unlock()
+	}
+	return err
+}
+
+// RebuildIndex scans & parses all Tiles and Hu in the repo, then stores new index
+func (dc *DiceConfig) RebuildIndex(ctx context.Context) (index *v1alpha1.RepoIndex, err error) {
+	defer dc.countRepoError("rebuild-index", &err)
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	if dc.Mode == "dev" {
+		index, err = dc.buildIndexDev(ctx)
+	} else {
+		index, err = dc.buildIndexS3(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return index, dc.SaveIndex(index)
+}
+
+// UpdateIndexTile adds or replaces a Tile in the index
+func (dc *DiceConfig) UpdateIndexTile(ctx context.Context, tile *v1alpha1.Tile, digest string) error {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	index, err := dc.loadOrNewIndex(ctx)
+	if err != nil {
+		return err
+	}
+	tm := TileMetadata(tile, digest)
+	tm.Dependencies = indexDependencies(index, tile.Spec.Dependencies)
+	var versions []v1alpha1.TileMetadata
+	for _, v := range index.Tiles[tm.Name] {
+		if v.Version != tm.Version {
+			versions = append(versions, v)
+		}
+	}
+	index.Tiles[tm.Name] = sortVersions(append(versions, tm))
+	return dc.SaveIndex(index)
+}
+
+// UpdateIndexHu adds or replaces a Hu in the index
+func (dc *DiceConfig) UpdateIndexHu(ctx context.Context, name string, deployment *v1alpha1.Deployment, digest string) error {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	index, err := dc.loadOrNewIndex(ctx)
+	if err != nil {
+		return err
+	}
+	hu := HuMetadata(deployment, digest)
+	var dependencies []v1alpha1.TileDependency
+	for _, t := range deployment.Spec.Template.Tiles {
+		dependencies = append(dependencies, v1alpha1.TileDependency{TileReference: t.TileReference, TileVersion: t.TileVersion})
+	}
+	hu.Dependencies = indexDependencies(index, dependencies)
+	index.Hus[name] = hu
+	return dc.SaveIndex(index)
+}
+
+func (dc *DiceConfig) loadOrNewIndex(ctx context.Context) (*v1alpha1.RepoIndex, error) {
+	index, _, err := dc.LoadIndex(ctx)
+	if err == ErrIndexNotFound {
+		return &v1alpha1.RepoIndex{
+			Tiles: make(map[string][]v1alpha1.TileMetadata),
+			Hus:   make(map[string]v1alpha1.HuMetadata),
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	// Copy in case of changing cached index
+	updated := &v1alpha1.RepoIndex{
+		Tiles: make(map[string][]v1alpha1.TileMetadata),
+		Hus:   make(map[string]v1alpha1.HuMetadata),
+	}
+	for k, v := range index.Tiles {
+		updated.Tiles[k] = append([]v1alpha1.TileMetadata{}, v...)
+	}
+	for k, v := range index.Hus {
+		updated.Hus[k] = v
+	}
+	return updated, nil
+}
+
+// buildIndexDev builds index from local repo
+func (dc *DiceConfig) buildIndexDev(ctx context.Context) (*v1alpha1.RepoIndex, error) {
+	tiles := make(map[string]v1alpha1.Tile)
+	digests := make(map[string]string)
+	specs, err := filepath.Glob(filepath.Join(dc.LocalRepo, "*", "*", "tile-spec.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, spec := range specs {
+		buf, err := ioutil.ReadFile(spec)
+		if err != nil {
+			return nil, err
+		}
+		d := v1alpha1.Data(buf)
+		tile, err := d.ParseTile(ctx)
+		if err != nil {
+			log.Errorf("parsing %s with error : %s\n", spec, err)
+			continue
+		}
+		digest, err := tileDigestDev(filepath.Dir(spec))
+		if err != nil {
+			return nil, err
+		}
+		tiles[tile.Metadata.Name+"-"+tile.Metadata.Version] = *tile
+		digests[tile.Metadata.Name+"-"+tile.Metadata.Version] = digest
+	}
+
+	hus := make(map[string][]byte)
+	files, err := filepath.Glob(dc.huSpecFileDev("*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		buf, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		hus[strings.TrimSuffix(filepath.Base(f), ".yaml")] = buf
+	}
+	return newIndex(ctx, tiles, digests, hus), nil
+}
+
+// buildIndexS3 builds index from S3 repo
+func (dc *DiceConfig) buildIndexS3(ctx context.Context) (*v1alpha1.RepoIndex, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(dc.Region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	svc := s3.New(sess)
+	getObject := func(key string) ([]byte, error) {
+		output, err := svc.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(dc.BucketName),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer output.Body.Close()
+		return ioutil.ReadAll(output.Body)
+	}
+
+	tiles := make(map[string]v1alpha1.Tile)
+	digests := make(map[string]string)
+	hus := make(map[string][]byte)
+	var keys []string
+	for _, prefix := range []string{"tiles-repo", "templates"} {
+		err = svc.ListObjectsPages(&s3.ListObjectsInput{
+			Bucket: aws.String(dc.BucketName),
+			Prefix: aws.String(prefix),
+		}, func(output *s3.ListObjectsOutput, b bool) bool {
+			for _, obj := range output.Contents {
+				keys = append(keys, *obj.Key)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		if strings.HasPrefix(key, "templates/") && strings.HasSuffix(key, ".yaml") {
+			buf, err := getObject(key)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			hus[strings.TrimSuffix(filepath.Base(key), ".yaml")] = buf
+		} else if strings.HasSuffix(key, "/tile-spec.yaml") {
+			buf, err := getObject(key)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			d := v1alpha1.Data(buf)
+			tile, err := d.ParseTile(ctx)
+			if err != nil {
+				log.Errorf("parsing %s with error : %s\n", key, err)
+				continue
+			}
+			// Digest of tarball, which was pulled while deploying
+			tgz, err := getObject(strings.TrimSuffix(key, "tile-spec.yaml") + strings.ToLower(tile.Metadata.Name) + ".tgz")
+			if err != nil {
+				log.Errorf("loading tarball of %s with error : %s\n", key, err)
+				continue
+			}
+			tiles[tile.Metadata.Name+"-"+tile.Metadata.Version] = *tile
+			digests[tile.Metadata.Name+"-"+tile.Metadata.Version] = Digest(tgz)
+		}
+	}
+	return newIndex(ctx, tiles, digests, hus), nil
+}
+
+// newIndex generates index with dependencies of Tiles & Hu
+func newIndex(ctx context.Context, tiles map[string]v1alpha1.Tile, digests map[string]string, hus map[string][]byte) *v1alpha1.RepoIndex {
+	index := &v1alpha1.RepoIndex{
+		Tiles: make(map[string][]v1alpha1.TileMetadata),
+		Hus:   make(map[string]v1alpha1.HuMetadata),
+	}
+	closure := func(tileName string, tileVersion string, td map[string]string) {
+		td[tileName] = tileVersion
+		for k, v := range dependentTiles(tiles, tileName, tileVersion) {
+			td[k] = v
+		}
+	}
+	dependencies := func(td map[string]string) []v1alpha1.TileMetadata {
+		var dtm []v1alpha1.TileMetadata
+		for k, v := range td {
+			if tile, ok := tiles[k+"-"+v]; ok {
+				dtm = append(dtm, TileMetadata(&tile, digests[k+"-"+v]))
+			}
+		}
+		sort.SliceStable(dtm, func(i, j int) bool {
+			return dtm[i].Name < dtm[j].Name
+		})
+		return dtm
+	}
+
+	for key, tile := range tiles {
+		tm := TileMetadata(&tile, digests[key])
+		tm.Dependencies = dependencies(dependentTiles(tiles, tile.Metadata.Name, tile.Metadata.Version))
+		index.Tiles[tm.Name] = append(index.Tiles[tm.Name], tm)
+	}
+	for name, versions := range index.Tiles {
+		index.Tiles[name] = sortVersions(versions)
+	}
+
+	for name, buf := range hus {
+		d := v1alpha1.Data(buf)
+		deployment, err := d.ParseHu(ctx)
+		if err != nil {
+			log.Errorf("parsing %s with error : %s\n", name, err)
+			continue
+		}
+		hu := HuMetadata(deployment, Digest(buf))
+		td := make(map[string]string)
+		for _, t := range deployment.Spec.Template.Tiles {
+			closure(t.TileReference, t.TileVersion, td)
+		}
+		hu.Dependencies = dependencies(td)
+		index.Hus[name] = hu
+	}
+	return index
+}
+
+// indexDependencies returns all dependent Tiles (includes dependencies of dependencies) from index
+func indexDependencies(index *v1alpha1.RepoIndex, dependencies []v1alpha1.TileDependency) []v1alpha1.TileMetadata {
+	var dtm []v1alpha1.TileMetadata
+	added := make(map[string]bool)
+	add := func(tm v1alpha1.TileMetadata) {
+		if !added[tm.Name+"-"+tm.Version] {
+			added[tm.Name+"-"+tm.Version] = true
+			tm.Dependencies = nil
+			dtm = append(dtm, tm)
+		}
+	}
+	for _, d := range dependencies {
+		for _, tm := range index.Tiles[d.TileReference] {
+			if tm.Version == d.TileVersion {
+				for _, sub := range tm.Dependencies {
+					add(sub)
+				}
+				add(tm)
+			}
+		}
+	}
+	sort.SliceStable(dtm, func(i, j int) bool {
+		return dtm[i].Name < dtm[j].Name
+	})
+	return dtm
+}
+
+// sortVersions sorts versions of Tile, the latest comes first
+func sortVersions(versions []v1alpha1.TileMetadata) []v1alpha1.TileMetadata {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return v1alpha1.CompareVersion(versions[i].Version, versions[j].Version) > 0
+	})
+	return versions
+}
+
+// dependentTiles returns map: TileName->TileVersion
+func dependentTiles(tiles map[string]v1alpha1.Tile, tileName string, tileVersion string) map[string]string {
+	var td = make(map[string]string)
+	if tile, ok := tiles[tileName+"-"+tileVersion]; ok {
+		for _, d := range tile.Spec.Dependencies {
+			td[d.TileReference] = d.TileVersion
+			if subTile, ok := tiles[d.TileReference+"-"+d.TileVersion]; ok {
+				if len(subTile.Spec.Dependencies) > 0 {
+					m := dependentTiles(tiles, subTile.Metadata.Name, subTile.Metadata.Version)
+					for k, v := range m {
+						td[k] = v
+					}
+				}
+			}
+		}
+	}
+	return td
+}
+
+// TileMetadata returns metadata of Tile
+func TileMetadata(tile *v1alpha1.Tile, digest string) v1alpha1.TileMetadata {
+	return v1alpha1.TileMetadata{
+		Name:                     tile.Metadata.Name,
+		Version:                  tile.Metadata.Version,
+		Category:                 tile.Metadata.Category,
+		VendorService:            tile.Metadata.VendorService,
+		DependentOnVendorService: tile.Metadata.DependentOnVendorService,
+		Description:              tile.Metadata.Description,
+		TileRepo:                 tile.Metadata.TileRepo,
+		VersionTag:               tile.Metadata.Version,
+		Author:                   tile.Metadata.Author,
+		Email:                    tile.Metadata.Email,
+		License:                  tile.Metadata.License,
+		Released:                 tile.Metadata.Released,
+		Digest:                   digest,
+	}
+}
+
+// HuMetadata returns metadata of Hu
+func HuMetadata(deployment *v1alpha1.Deployment, digest string) v1alpha1.HuMetadata {
+	return v1alpha1.HuMetadata{
+		Name:        deployment.Metadata.Name,
+		Version:     deployment.Metadata.Version,
+		Description: deployment.Metadata.Description,
+		RawUrl:      deployment.Metadata.TileRepo,
+		Author:      deployment.Metadata.Author,
+		Email:       deployment.Metadata.Email,
+		License:     deployment.Metadata.License,
+		Released:    deployment.Metadata.Released,
+		Digest:      digest,
+	}
+}
+
+// IndexedTiles returns all Tiles in the index
+func IndexedTiles(index *v1alpha1.RepoIndex) []v1alpha1.TileMetadata {
+	var tiles []v1alpha1.TileMetadata
+	for _, versions := range index.Tiles {
+		tiles = append(tiles, versions...)
+	}
+	sort.SliceStable(tiles, func(i, j int) bool {
+		return tiles[i].Name < tiles[j].Name
+	})
+	return tiles
+}
+
+// IndexedHus returns all Hu in the index
+func IndexedHus(index *v1alpha1.RepoIndex) []v1alpha1.HuMetadata {
+	var hus []v1alpha1.HuMetadata
+	for _, hu := range index.Hus {
+		hus = append(hus, hu)
+	}
+	sort.SliceStable(hus, func(i, j int) bool {
+		return hus[i].Name < hus[j].Name
+	})
+	return hus
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"context"
+	"dice/apis/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDiceConfig_LoadIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dc := &DiceConfig{Mode: "dev", LocalRepo: dir}
+
+	_, _, err = dc.LoadIndex(context.TODO())
+	assert.Equal(t, ErrIndexNotFound, err)
+
+	network := &v1alpha1.Tile{Metadata: v1alpha1.Metadata{Name: "Network0", Version: "0.0.1"}}
+	eks := &v1alpha1.Tile{Metadata: v1alpha1.Metadata{Name: "Eks0", Version: "0.0.5"}}
+	eks.Spec.Dependencies = []v1alpha1.TileDependency{{Name: "network", TileReference: "Network0", TileVersion: "0.0.1"}}
+	assert.NoError(t, dc.UpdateIndexTile(context.TODO(), network, "sha256:001"))
+	assert.NoError(t, dc.UpdateIndexTile(context.TODO(), eks, "sha256:002"))
+
+	index, etag, err := dc.LoadIndex(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(IndexedTiles(index)))
+	assert.Equal(t, "sha256:002", index.Tiles["Eks0"][0].Digest)
+	assert.Equal(t, "Network0", index.Tiles["Eks0"][0].Dependencies[0].Name)
+
+	cached, cachedEtag, err := dc.LoadIndex(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, etag, cachedEtag)
+	assert.True(t, index == cached)
+}
+
+func TestSortVersions(t *testing.T) {
+	versions := []v1alpha1.TileMetadata{{Version: "0.0.9"}, {Version: "0.0.10"}, {Version: "0.1.0"}, {Version: "0.0.2"}}
+	var sorted []string
+	for _, v := range sortVersions(versions) {
+		sorted = append(sorted, v.Version)
+	}
+	assert.Equal(t, []string{"0.1.0", "0.0.10", "0.0.9", "0.0.2"}, sorted)
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// RepoRequestErrors counts failed requests to repo by operation & mode
+var RepoRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "dice_repo_request_errors_total",
+	Help: "Failed requests to the repo of Tiles & Hu.",
+}, []string{"operation", "mode"})
+
+// countRepoError counts failed request to repo, index which wasn't existed isn't an error
+func (dc *DiceConfig) countRepoError(operation string, err *error) {
+	if *err != nil && *err != ErrIndexNotFound {
+		RepoRequestErrors.WithLabelValues(operation, dc.Mode).Inc()
+	}
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestCountRepoError(t *testing.T) {
+	dc := &DiceConfig{Mode: "test"}
+	for _, err := range []error{nil, ErrIndexNotFound, errors.New("access denied"), errors.New("timeout")} {
+		dc.countRepoError("pull", &err)
+	}
+	assert.Equal(t, float64(2), testutil.ToFloat64(RepoRequestErrors.WithLabelValues("pull", "test")))
+	assert.NoError(t, testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(`
+# HELP dice_repo_request_errors_total Failed requests to the repo of Tiles & Hu.
+# TYPE dice_repo_request_errors_total counter
+dice_repo_request_errors_total{mode="test",operation="pull"} 2
+`), "dice_repo_request_errors_total"))
+}
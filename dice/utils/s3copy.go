@@ -2,6 +2,7 @@ package utils
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/tls"
 	"fmt"
 	log "github.com/sirupsen/logrus"
@@ -13,21 +14,25 @@ import (
 )
 
 type S3Functions interface {
-	LoadTile(tile string, version string) (string, error)
-	LoadTileDev(tile string, version string) (string, error)
-	LoadTileS3(tile string, version string, folder string) (string, error)
+	LoadTile(tile string, version string, folder string) (string, string, error)
+	LoadTileDev(tile string, version string, folder string) (string, string, error)
+	LoadTileS3(tile string, version string, folder string) (string, string, error)
 	LoadSuper(folder string) (string, error)
 	LoadSuperDev(folder string) (string, error)
 	LoadSuperS3(folder string) (string, error)
 	Decompress(tile string, version string) error
 	LoadTestOutput(tile string) ([]byte, error)
-	CleanJunk()
 	LoadTileSpec(tile string, version string) ([]byte, error)
 	LoadTileSpecS3(tile string, version string) ([]byte, error)
 	LoadTileSpecDev(tile string, version string) ([]byte, error)
 	LoadHuSpec(hu string) ([]byte, error)
 	LoadHuSpecS3(hu string) ([]byte, error)
 	LoadHuSpecDev(hu string) ([]byte, error)
+	SaveTile(tile string, version string, tgz []byte, spec []byte) (string, error)
+	SaveTileDev(tile string, version string, tgz []byte) error
+	SaveTileS3(tile string, version string, tgz []byte, spec []byte) error
+	SaveHu(hu string, buf []byte) error
+	SaveHuDev(hu string, buf []byte) error
 }
 
 type HttpClient interface {
@@ -36,13 +41,15 @@ type HttpClient interface {
 
 var Client HttpClient
 
-func (dc *DiceConfig) LoadTile(tile string, version string, folder string) (string, error) {
+// LoadTile loads Tile into folder, and return path of tile-spec.yaml & digest of loaded Tile
+func (dc *DiceConfig) LoadTile(tile string, version string, folder string) (tileSpecFile string, digest string, err error) {
+	defer dc.countRepoError("load-tile", &err)
 	if dc.Mode == "dev" {
-		dest, err := dc.LoadTileDev(tile, version, folder)
+		dest, digest, err := dc.LoadTileDev(tile, version, folder)
 		if err != nil {
-			dest, err = dc.LoadTileS3(tile, version, folder)
+			dest, digest, err = dc.LoadTileS3(tile, version, folder)
 		}
-		return dest, err
+		return dest, digest, err
 	} else {
 		return dc.LoadTileS3(tile, version, folder)
 	}
@@ -57,7 +64,7 @@ func initHttpClient() {
 
 }
 
-func (dc *DiceConfig) LoadTileS3(tile string, version string, folder string) (string, error) {
+func (dc *DiceConfig) LoadTileS3(tile string, version string, folder string) (string, string, error) {
 	//https://<bucket-name>.s3-<region>.amazonaws.com/tiles-repo/<tile name>/<tile version>/<tile name>.tgz
 	tileUrl := fmt.Sprintf("https://%s.s3-%s.amazonaws.com/tiles-repo/%s/%s/%s.tgz",
 		dc.BucketName,
@@ -75,14 +82,20 @@ func (dc *DiceConfig) LoadTileS3(tile string, version string, folder string) (st
 	resp, err := Client.Do(req)
 	if err != nil {
 		log.Printf("API call was failed from %s with Err: %s. \n", tileUrl, err)
-		return tileSpecFile, err
+		return tileSpecFile, "", err
+	}
+	defer resp.Body.Close()
+	// Keep the whole tarball to figure out digest of pulled bits
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return tileSpecFile, "", err
 	}
 
-	return tileSpecFile, UnTarGz(destDir, bufio.NewReader(resp.Body))
+	return tileSpecFile, Digest(buf), UnTarGz(destDir, bytes.NewReader(buf))
 
 }
 
-func (dc *DiceConfig) LoadTileDev(tile string, version string, folder string) (string, error) {
+func (dc *DiceConfig) LoadTileDev(tile string, version string, folder string) (string, string, error) {
 
 	repoDir := dc.LocalRepo
 	srcDir := repoDir + "/" + strings.ToLower(tile) + "/" + strings.ToLower(version)
@@ -90,37 +103,55 @@ func (dc *DiceConfig) LoadTileDev(tile string, version string, folder string) (s
 	tileSpecFile := destDir + "/tile-spec.yaml"
 	log.Printf("Load Tile < %s - %s > ... from < %s >\n", tile, version, dc.LocalRepo)
 
-	return tileSpecFile, Copy(srcDir, destDir,
+	skip := func(src string) bool {
+		return strings.Contains(src, "node_modules")
+	}
+	err := Copy(srcDir, destDir,
 		Options{
 			OnSymlink: func(src string) SymlinkAction {
 				return Skip
 			},
-			Skip: func(src string) bool {
-				return strings.Contains(src, "node_modules")
-			},
+			Skip: skip,
 		})
+	if err != nil {
+		return tileSpecFile, "", err
+	}
+	digest, err := tileDigestDev(srcDir)
+	return tileSpecFile, digest, err
 
 }
 
-// CleanJunk removes all *.log / *.sh under super-*/
-func (dc *DiceConfig) CleanJunk(folder string) {
-	destDir := dc.WorkHome + folder
-	if f, err := os.Stat(destDir); err == nil && f.IsDir() {
-		for _, suffix := range []string{"*.sh", "*.log"} {
-			files, err := filepath.Glob(destDir + "/" + suffix)
-			if err == nil {
-				for _, f := range files {
-					os.Remove(f)
-				}
-			}
-		}
-		os.RemoveAll(destDir + "/lib")
+// tileDigestDev returns digest of Tile folder in local repo, which is digest of the pushed tarball kept next to the folder as <version>.tgz,
+// same as S3 repo. The folder's digest is returned if Tile was copied into local repo rather than pushed.
+func tileDigestDev(srcDir string) (string, error) {
+	if buf, err := ioutil.ReadFile(srcDir + ".tgz"); err == nil {
+		return Digest(buf), nil
+	} else if !os.IsNotExist(err) {
+		return "", err
 	}
+	return DirDigest(srcDir, func(src string) bool {
+		return strings.Contains(src, "node_modules")
+	})
+}
 
+// CurrentRun is the link to folder of the latest run in folder of a deployment
+const CurrentRun = "current"
+
+// LinkCurrent points <WorkHome>/<deployment>/current at folder of the run, which is /<deployment>/<d-sid>
+func (dc *DiceConfig) LinkCurrent(folder string) error {
+	link := filepath.Join(dc.WorkHome, filepath.Dir(folder), CurrentRun)
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	// Relative link keeps working if work home was mounted at another path
+	if err := os.Symlink(filepath.Base(folder), tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, link)
 }
-func (dc *DiceConfig) LoadSuper(folder string) (string, error) {
 
-	dc.CleanJunk(folder)
+func (dc *DiceConfig) LoadSuper(folder string) (dest string, err error) {
+	defer dc.countRepoError("load-super", &err)
+
 	if dc.Mode == "dev" {
 		dest, err := dc.LoadSuperDev(folder)
 		if err != nil {
@@ -171,7 +202,8 @@ func (dc *DiceConfig) LoadTestOutput(tile string, folder string) ([]byte, error)
 	return ioutil.ReadAll(f)
 }
 
-func (dc *DiceConfig) LoadTileSpec(tile string, version string) ([]byte, error) {
+func (dc *DiceConfig) LoadTileSpec(tile string, version string) (spec []byte, err error) {
+	defer dc.countRepoError("load-tile-spec", &err)
 	if dc.Mode == "dev" {
 		dest, err := dc.LoadTileSpecDev(tile, version)
 		if err != nil {
@@ -211,7 +243,8 @@ func (dc *DiceConfig) LoadTileSpecS3(tile string, version string) ([]byte, error
 	return ioutil.ReadAll(resp.Body)
 }
 
-func (dc *DiceConfig) LoadHuSpec(hu string) ([]byte, error) {
+func (dc *DiceConfig) LoadHuSpec(hu string) (spec []byte, err error) {
+	defer dc.countRepoError("load-hu-spec", &err)
 	if dc.Mode == "dev" {
 		dest, err := dc.LoadHuSpecDev(hu)
 		if err != nil {
@@ -223,11 +256,12 @@ func (dc *DiceConfig) LoadHuSpec(hu string) ([]byte, error) {
 	}
 }
 func (dc *DiceConfig) LoadHuSpecDev(hu string) ([]byte, error) {
-	repoDir := dc.LocalRepo
-	srcDir := repoDir + "../templates/"
-	huSpecFile := srcDir + strings.ToLower(hu) + ".yaml"
+	return ioutil.ReadFile(dc.huSpecFileDev(hu))
+}
 
-	return ioutil.ReadFile(huSpecFile)
+// huSpecFileDev returns where Hu is in local repo, which is next to local Tiles repo
+func (dc *DiceConfig) huSpecFileDev(hu string) string {
+	return filepath.Join(dc.LocalRepo, "..", "templates", strings.ToLower(hu)+".yaml")
 }
 func (dc *DiceConfig) LoadHuSpecS3(hu string) ([]byte, error) {
 	tileUrl := fmt.Sprintf("https://%s.s3-%s.amazonaws.com/templates/%s.yaml",
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"archive/tar"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLinkCurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dc := &DiceConfig{WorkHome: dir}
+
+	for _, sid := range []string{"sid-1", "sid-2"} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, "eks-simple", sid), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "eks-simple", sid, "super.ts"), []byte(sid), 0644))
+		assert.NoError(t, dc.LinkCurrent("/eks-simple/"+sid))
+		buf, err := ioutil.ReadFile(filepath.Join(dir, "eks-simple", CurrentRun, "super.ts"))
+		assert.NoError(t, err)
+		assert.Equal(t, sid, string(buf))
+	}
+	target, err := os.Readlink(filepath.Join(dir, "eks-simple", CurrentRun))
+	assert.NoError(t, err)
+	assert.Equal(t, "sid-2", target)
+}
+
+func TestLoadTileDev(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dc := &DiceConfig{Mode: "dev", LocalRepo: filepath.Join(dir, "repo"), WorkHome: filepath.Join(dir, "work")}
+
+	// Pushed Tile has digest of its tarball, same as S3 repo
+	tgz := tarGz(t, &tar.Header{Name: "tile-spec.yaml", Typeflag: tar.TypeReg}).Bytes()
+	digest, err := dc.SaveTile("Network0", "0.0.1", tgz, nil)
+	assert.NoError(t, err)
+	tileSpecFile, loaded, err := dc.LoadTileDev("Network0", "0.0.1", "/super")
+	assert.NoError(t, err)
+	assert.Equal(t, digest, loaded)
+	assert.FileExists(t, tileSpecFile)
+
+	// Tile copied into local repo has digest of its folder
+	copied := filepath.Join(dc.LocalRepo, "eks0", "0.0.5")
+	assert.NoError(t, os.MkdirAll(copied, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(copied, "tile-spec.yaml"), []byte("tile-spec.yaml"), 0644))
+	_, loaded, err = dc.LoadTileDev("Eks0", "0.0.5", "/super")
+	assert.NoError(t, err)
+	expected, err := DirDigest(copied, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, loaded)
+}
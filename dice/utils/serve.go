@@ -0,0 +1,345 @@
+package utils
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeConfig is configuration of `dice serve`, which is loaded from file and overridden by M_* environment variables & flags, eg:
+//
+//	listen: 0.0.0.0:9090
+//	logLevel: info
+//	workHome: /workspace
+//	mode: prod
+//	shutdownGracePeriod: 5m
+//	repo:
+//	  region: ap-southeast-1
+//	  bucket: cc-mahjong-0
+//	auth: /etc/dice/auth.yaml
+//	retention:
+//	  keepRuns: 10
+//	  maxAge: 720h
+//	  maxDiskUsage: 50Gi
+//	  interval: 1h
+//	concurrency:
+//	  deployments: 4
+//	  perAccount: 2
+type ServeConfig struct {
+	Listen   string `json:"listen,omitempty"`   // Listen is address of Dice, 0.0.0.0:9090 by default
+	LogLevel string `json:"logLevel,omitempty"` // LogLevel is level of logrus, info by default
+	WorkHome string `json:"workHome,omitempty"` // WorkHome is the main working folder for all activities
+	Mode     string `json:"mode,omitempty"`     // Mode is dev or prod, prod by default
+	State    string `json:"state,omitempty"`    // State is folder of persisted state, such as checkpoints of deployments, <workHome>-state by default
+
+	// ShutdownGracePeriod is how long running stages could take after SIGTERM, deployments are interrupted after it, 2m by default
+	ShutdownGracePeriod Duration `json:"shutdownGracePeriod,omitempty"`
+
+	Repo RepoConfig `json:"repo,omitempty"`
+
+	Auth       string `json:"auth,omitempty"`       // Auth is file of AuthConfig, it's required unless Insecure
+	Insecure   bool   `json:"insecure,omitempty"`   // Insecure serves without authentication, every caller is admin then
+	Workspaces string `json:"workspaces,omitempty"` // Workspaces is file of WorkspacesConfig
+
+	TLS         TLSFiles    `json:"tls,omitempty"`
+	Audit       AuditConfig `json:"audit,omitempty"`
+	Diagnostics bool        `json:"diagnostics,omitempty"` // Diagnostics enables allowlisted kubectl commands for admin
+	Trace       TraceConfig `json:"trace,omitempty"`
+
+	Retention   RetentionConfig   `json:"retention,omitempty"`
+	Concurrency ConcurrencyConfig `json:"concurrency,omitempty"`
+}
+
+// RepoConfig is repository backends of Tiles & Hu, S3 on 'prod' mode and local folder on 'dev' mode
+type RepoConfig struct {
+	Region string `json:"region,omitempty"` // Region is where the S3 bucket is
+	Bucket string `json:"bucket,omitempty"` // Bucket is name of S3 bucket
+	Local  string `json:"local,omitempty"`  // Local is folder of Tiles on 'dev' mode
+}
+
+// TLSFiles are files of certificate, Dice serves HTTPS if Cert is not empty
+type TLSFiles struct {
+	Cert     string `json:"cert,omitempty"`
+	Key      string `json:"key,omitempty"`
+	ClientCA string `json:"clientCA,omitempty"` // ClientCA enables mTLS
+}
+
+// AuditConfig tells where audit entries go
+type AuditConfig struct {
+	File string `json:"file,omitempty"` // File is <workHome>/audit.jsonl by default
+	HTTP string `json:"http,omitempty"` // HTTP is URL where entries are posted as well
+}
+
+// TraceConfig tells where spans go
+type TraceConfig struct {
+	Exporter string `json:"exporter,omitempty"` // Exporter is otlp or stdout, tracing is disabled if it's empty
+	Endpoint string `json:"endpoint,omitempty"` // Endpoint is OTLP/HTTP endpoint
+}
+
+// RetentionConfig limits runs kept in work home, zero means unlimited
+type RetentionConfig struct {
+	KeepRuns     int      `json:"keepRuns,omitempty"`     // KeepRuns is number of the latest runs kept per deployment
+	MaxAge       Duration `json:"maxAge,omitempty"`       // MaxAge is the longest time to keep a finished run, eg: 720h
+	MaxDiskUsage ByteSize `json:"maxDiskUsage,omitempty"` // MaxDiskUsage is limit of total size of runs, eg: 50Gi
+	Interval     Duration `json:"interval,omitempty"`     // Interval of sweeping runs in background, 1h by default, zero disables it
+}
+
+// ConcurrencyConfig limits running deployments, zero means unlimited
+type ConcurrencyConfig struct {
+	Deployments int `json:"deployments,omitempty"` // Deployments is max running deployments of Dice
+	PerAccount  int `json:"perAccount,omitempty"`  // PerAccount is max running deployments per AWS account/region/profile
+}
+
+// Duration is time.Duration in text, such as 90s or 720h
+type Duration time.Duration
+
+// UnmarshalJSON parses duration from text
+func (d *Duration) UnmarshalJSON(buf []byte) error {
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return errors.Errorf("invalid duration %s, should be such as 720h", buf)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON prints duration as text
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ByteSize is size in bytes, which could be with unit, such as 512Mi, 50Gi or 1T
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}}
+
+// ParseByteSize parses size with optional unit
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	multiple := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, multiple = strings.TrimSuffix(s, u.suffix), u.size
+			break
+		}
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || v < 0 {
+		return 0, errors.Errorf("invalid size %q, should be such as 50Gi", s)
+	}
+	return ByteSize(v * multiple), nil
+}
+
+// UnmarshalJSON parses size from number or text
+func (b *ByteSize) UnmarshalJSON(buf []byte) error {
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		s = string(buf)
+	}
+	v, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+// DefaultServeConfig returns configuration with defaults
+func DefaultServeConfig() *ServeConfig {
+	return &ServeConfig{Listen: "0.0.0.0:9090", LogLevel: "info", Mode: "prod", ShutdownGracePeriod: Duration(2 * time.Minute),
+		Retention: RetentionConfig{Interval: Duration(time.Hour)}}
+}
+
+// LoadServeConfig loads configuration file on top of defaults, unknown fields are rejected
+func LoadServeConfig(file string) (*ServeConfig, error) {
+	sc := DefaultServeConfig()
+	if file == "" {
+		return sc, nil
+	}
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(buf, sc); err != nil {
+		return nil, errors.Wrapf(err, "invalid configuration %s", file)
+	}
+	return sc, nil
+}
+
+// ApplyEnv overrides configuration with M_* environment variables, which are looked up by lookup, eg: os.LookupEnv
+func (sc *ServeConfig) ApplyEnv(lookup func(string) (string, bool)) error {
+	for env, field := range map[string]*string{
+		"M_LISTEN":           &sc.Listen,
+		"M_LOG_LEVEL":        &sc.LogLevel,
+		"M_WORK_HOME":        &sc.WorkHome,
+		"M_MODE":             &sc.Mode,
+		"M_STATE":            &sc.State,
+		"M_S3_BUCKET_REGION": &sc.Repo.Region,
+		"M_S3_BUCKET":        &sc.Repo.Bucket,
+		"M_LOCAL_TILE_REPO":  &sc.Repo.Local,
+		"M_AUTH_CONFIG":      &sc.Auth,
+		"M_WORKSPACES":       &sc.Workspaces,
+		"M_TLS_CERT":         &sc.TLS.Cert,
+		"M_TLS_KEY":          &sc.TLS.Key,
+		"M_TLS_CLIENT_CA":    &sc.TLS.ClientCA,
+		"M_AUDIT_FILE":       &sc.Audit.File,
+		"M_AUDIT_HTTP":       &sc.Audit.HTTP,
+		"M_TRACE_EXPORTER":   &sc.Trace.Exporter,
+		"M_TRACE_ENDPOINT":   &sc.Trace.Endpoint,
+	} {
+		if v, ok := lookup(env); ok {
+			*field = v
+		}
+	}
+	if v, ok := lookup("M_DIAGNOSTICS"); ok {
+		sc.Diagnostics = v == "true"
+	}
+	if v, ok := lookup("M_INSECURE"); ok {
+		sc.Insecure = v == "true"
+	}
+	for env, field := range map[string]*int{
+		"M_KEEP_RUNS":       &sc.Retention.KeepRuns,
+		"M_MAX_DEPLOYMENTS": &sc.Concurrency.Deployments,
+		"M_MAX_PER_ACCOUNT": &sc.Concurrency.PerAccount,
+	} {
+		if v, ok := lookup(env); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return errors.Errorf("invalid %s: %q", env, v)
+			}
+			*field = n
+		}
+	}
+	for env, field := range map[string]*Duration{
+		"M_MAX_AGE":               &sc.Retention.MaxAge,
+		"M_GC_INTERVAL":           &sc.Retention.Interval,
+		"M_SHUTDOWN_GRACE_PERIOD": &sc.ShutdownGracePeriod,
+	} {
+		if v, ok := lookup(env); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return errors.Errorf("invalid %s: %q", env, v)
+			}
+			*field = Duration(d)
+		}
+	}
+	if v, ok := lookup("M_MAX_DISK_USAGE"); ok {
+		size, err := ParseByteSize(v)
+		if err != nil {
+			return errors.Wrap(err, "invalid M_MAX_DISK_USAGE")
+		}
+		sc.Retention.MaxDiskUsage = size
+	}
+	return nil
+}
+
+// Validate checks configuration as a whole, all problems are returned together
+func (sc *ServeConfig) Validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, errors.Errorf(format, args...).Error())
+	}
+	if _, _, err := net.SplitHostPort(sc.Listen); err != nil {
+		problem("invalid listen address %q", sc.Listen)
+	}
+	if _, err := log.ParseLevel(sc.LogLevel); err != nil {
+		problem("invalid log level %q", sc.LogLevel)
+	}
+	if sc.WorkHome == "" {
+		problem("work home is required")
+	}
+	switch sc.Mode {
+	case "prod":
+		if sc.Repo.Region == "" || sc.Repo.Bucket == "" {
+			problem("region & bucket of S3 repo are required on 'prod' mode")
+		}
+	case "dev":
+		if sc.Repo.Local == "" {
+			problem("local repo is required on 'dev' mode")
+		}
+	default:
+		problem("invalid mode %q, should be dev or prod", sc.Mode)
+	}
+	if sc.Auth == "" && !sc.Insecure {
+		problem("auth is required, or set insecure to serve without authentication")
+	}
+	if (sc.TLS.Cert == "") != (sc.TLS.Key == "") || (sc.TLS.ClientCA != "" && sc.TLS.Cert == "") {
+		problem("certificate & key are both required for TLS")
+	}
+	if err := CheckTraceExporter(sc.Trace.Exporter, sc.Trace.Endpoint); err != nil {
+		problem("%s", err)
+	}
+	if sc.ShutdownGracePeriod < 0 {
+		problem("shutdown grace period can't be negative")
+	}
+	if sc.Retention.KeepRuns < 0 || sc.Retention.MaxAge < 0 || sc.Retention.MaxDiskUsage < 0 || sc.Retention.Interval < 0 {
+		problem("retention can't be negative")
+	}
+	if sc.Concurrency.Deployments < 0 || sc.Concurrency.PerAccount < 0 {
+		problem("concurrency can't be negative")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// StateDir returns folder of persisted state
+func (sc *ServeConfig) StateDir() string {
+	if sc.State != "" {
+		return sc.State
+	}
+	return siblingOf(sc.WorkHome, "state")
+}
+
+// siblingOf returns folder next to work home, such as <workHome>-state, which can't collide with folders of deployments
+func siblingOf(workHome string, suffix string) string {
+	return filepath.Clean(workHome) + "-" + suffix
+}
+
+// DiceConfig returns configuration of the default workspace, authentication is loaded as well
+func (sc *ServeConfig) DiceConfig() (*DiceConfig, error) {
+	var auth *AuthConfig
+	if sc.Auth != "" {
+		var err error
+		if auth, err = LoadAuthConfig(sc.Auth); err != nil {
+			return nil, errors.Wrapf(err, "failed to load auth %s", sc.Auth)
+		}
+	}
+	auditFile := sc.Audit.File
+	if auditFile == "" {
+		auditFile = filepath.Join(sc.WorkHome, "audit.jsonl")
+	}
+	return &DiceConfig{
+		WorkHome:   sc.WorkHome,
+		Region:     sc.Repo.Region,
+		BucketName: sc.Repo.Bucket,
+		Mode:       sc.Mode,
+		LocalRepo:  sc.Repo.Local,
+		Auth:       auth,
+		Audit:      NewAuditor(auditFile, sc.Audit.HTTP),
+
+		TLSCert:     sc.TLS.Cert,
+		TLSKey:      sc.TLS.Key,
+		TLSClientCA: sc.TLS.ClientCA,
+
+		Diagnostics: sc.Diagnostics,
+
+		TraceExporter: sc.Trace.Exporter,
+		TraceEndpoint: sc.Trace.Endpoint,
+	}, nil
+}
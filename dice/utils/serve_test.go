@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadServeConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "dice.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(`
+listen: 127.0.0.1:8080
+workHome: /var/dice
+mode: dev
+insecure: true
+repo:
+  local: /var/dice/repo
+retention:
+  keepRuns: 5
+  maxAge: 720h
+  maxDiskUsage: 50Gi
+concurrency:
+  deployments: 4
+`), 0644))
+
+	sc, err := LoadServeConfig(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:8080", sc.Listen)
+	assert.Equal(t, "info", sc.LogLevel)
+	assert.Equal(t, Duration(720*time.Hour), sc.Retention.MaxAge)
+	assert.Equal(t, ByteSize(50<<30), sc.Retention.MaxDiskUsage)
+	assert.Equal(t, Duration(time.Hour), sc.Retention.Interval)
+	assert.Equal(t, "/var/dice-state", sc.StateDir())
+	assert.NoError(t, sc.Validate())
+
+	env := map[string]string{"M_MODE": "prod", "M_S3_BUCKET_REGION": "ap-southeast-1", "M_S3_BUCKET": "tiles", "M_MAX_DEPLOYMENTS": "2"}
+	assert.NoError(t, sc.ApplyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }))
+	assert.Equal(t, "prod", sc.Mode)
+	assert.Equal(t, RepoConfig{Region: "ap-southeast-1", Bucket: "tiles", Local: "/var/dice/repo"}, sc.Repo)
+	assert.Equal(t, 2, sc.Concurrency.Deployments)
+	assert.NoError(t, sc.Validate())
+
+	dc, err := sc.DiceConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "tiles", dc.BucketName)
+	assert.Equal(t, "/var/dice/audit.jsonl", dc.Audit.Sinks[0].(*FileAuditSink).Path)
+
+	assert.NoError(t, ioutil.WriteFile(file, []byte("workHome: /var/dice\nunknown: true\n"), 0644))
+	_, err = LoadServeConfig(file)
+	assert.Error(t, err)
+	assert.Error(t, sc.ApplyEnv(func(k string) (string, bool) { return "many", k == "M_KEEP_RUNS" }))
+}
+
+func TestServeConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&ServeConfig{Listen: ":9090", LogLevel: "debug", WorkHome: "/w", Mode: "dev", Repo: RepoConfig{Local: "/r"}, Auth: "auth.yaml"}).Validate())
+	assert.NoError(t, (&ServeConfig{Listen: ":9090", LogLevel: "debug", WorkHome: "/w", Mode: "dev", Repo: RepoConfig{Local: "/r"}, Insecure: true}).Validate())
+	err := (&ServeConfig{Listen: "9090", LogLevel: "loud", Mode: "test", TLS: TLSFiles{Cert: "c.pem"},
+		Trace: TraceConfig{Exporter: "zipkin"}, Concurrency: ConcurrencyConfig{Deployments: -1}}).Validate()
+	assert.Error(t, err)
+	for _, problem := range []string{"listen address", "log level", "work home", "mode", "auth is required", "TLS", "zipkin", "concurrency"} {
+		assert.Contains(t, err.Error(), problem)
+	}
+	assert.Contains(t, DefaultServeConfig().Validate().Error(), "region & bucket")
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, want := range map[string]ByteSize{"1024": 1024, "512Mi": 512 << 20, "2G": 2e9, " 1Ti ": 1 << 40} {
+		got, err := ParseByteSize(s)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"", "-1", "1.5Gi", "10PB"} {
+		_, err := ParseByteSize(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FileStore keeps state as JSON files in <Dir>/<kind>/<key>.json, which survives restart of Dice
+type FileStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// path returns file of key, key must not escape folder of kind
+func (fs *FileStore) path(kind string, key string) (string, error) {
+	for _, k := range []string{kind, key} {
+		if k == "" || k == "." || k == ".." || strings.ContainsAny(k, `/\`) {
+			return "", errors.Errorf("invalid key of state: %q", k)
+		}
+	}
+	return filepath.Join(fs.Dir, kind, key+".json"), nil
+}
+
+// Put writes value of key atomically
+func (fs *FileStore) Put(kind string, key string, v interface{}) error {
+	file, err := fs.path(kind, key)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Create writes value of key only if key wasn't existed, and tells if it was created
+func (fs *FileStore) Create(kind string, key string, v interface{}) (bool, error) {
+	file, err := fs.path(kind, key)
+	if err != nil {
+		return false, err
+	}
+	buf, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return false, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return false, err
+	}
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0600); err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+	// Link fails if file exists, so that key is created exclusively with its whole value
+	if err := os.Link(tmp, file); os.IsExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Get reads value of key into v, and tells if it was found
+func (fs *FileStore) Get(kind string, key string, v interface{}) (bool, error) {
+	file, err := fs.path(kind, key)
+	if err != nil {
+		return false, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	buf, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal(buf, v)
+}
+
+// Keys returns sorted keys of kind
+func (fs *FileStore) Keys(kind string) ([]string, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	files, err := ioutil.ReadDir(filepath.Join(fs.Dir, kind))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			keys = append(keys, strings.TrimSuffix(f.Name(), ".json"))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete removes key, which isn't an error if key wasn't existed
+func (fs *FileStore) Delete(kind string, key string) error {
+	file, err := fs.path(kind, key)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
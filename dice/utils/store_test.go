@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fs := &FileStore{Dir: dir}
+
+	type value struct{ Status string }
+	var v value
+	found, err := fs.Get("checkpoints", "sid-1", &v)
+	assert.NoError(t, err)
+	assert.False(t, found)
+	keys, err := fs.Keys("checkpoints")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	assert.NoError(t, fs.Put("checkpoints", "sid-2", value{"Done"}))
+	assert.NoError(t, fs.Put("checkpoints", "sid-1", value{"Progress"}))
+	assert.NoError(t, fs.Put("checkpoints", "sid-1", value{"Interrupted"}))
+	found, err = fs.Get("checkpoints", "sid-1", &v)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Interrupted", v.Status)
+	keys, _ = fs.Keys("checkpoints")
+	assert.Equal(t, []string{"sid-1", "sid-2"}, keys)
+
+	assert.NoError(t, fs.Delete("checkpoints", "sid-1"))
+	assert.NoError(t, fs.Delete("checkpoints", "sid-1"))
+	keys, _ = fs.Keys("checkpoints")
+	assert.Equal(t, []string{"sid-2"}, keys)
+
+	created, err := fs.Create("locks", "eks-simple", value{"sid-1"})
+	assert.NoError(t, err)
+	assert.True(t, created)
+	created, err = fs.Create("locks", "eks-simple", value{"sid-2"})
+	assert.NoError(t, err)
+	assert.False(t, created)
+	fs.Get("locks", "eks-simple", &v)
+	assert.Equal(t, "sid-1", v.Status)
+	keys, _ = fs.Keys("locks")
+	assert.Equal(t, []string{"eks-simple"}, keys)
+
+	for _, key := range []string{"", "..", "../audit", `a\b`} {
+		assert.Error(t, fs.Put("checkpoints", key, v), key)
+	}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"sync"
+)
+
+// TLSReloader serves certificate & client CA, which can be reloaded without restarting, eg: on SIGHUP
+type TLSReloader struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // ClientCAFile enables mTLS if it's not empty
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+}
+
+// NewTLSReloader loads certificate & client CA at the first time
+func NewTLSReloader(certFile string, keyFile string, clientCAFile string) (*TLSReloader, error) {
+	r := &TLSReloader{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile}
+	return r, r.Reload()
+}
+
+// Reload loads certificate & client CA from files, the current ones are kept if failed
+func (r *TLSReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load certificate")
+	}
+	var pool *x509.CertPool
+	if r.ClientCAFile != "" {
+		buf, err := ioutil.ReadFile(r.ClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to load client CA")
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return errors.New("no certificate in client CA: " + r.ClientCAFile)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.clientCA = &cert, pool
+	return nil
+}
+
+// TLSConfig returns configuration for server, which always uses the latest certificate & client CA
+func (r *TLSReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			c := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.clientCA != nil {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+				c.ClientCAs = r.clientCA
+			}
+			return c, nil
+		},
+	}
+}
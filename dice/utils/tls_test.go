@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCert(t *testing.T, dir string, cn string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+func TestTLSReloader_Reload(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tls")
+	writeCert(t, dir, "dice-1")
+	r, err := NewTLSReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "tls.crt"))
+	assert.NoError(t, err)
+
+	commonName := func() string {
+		c, err := r.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		assert.NoError(t, err)
+		assert.Equal(t, tls.RequireAndVerifyClientCert, c.ClientAuth)
+		cert, _ := x509.ParseCertificate(c.Certificates[0].Certificate[0])
+		return cert.Subject.CommonName
+	}
+	assert.Equal(t, "dice-1", commonName())
+
+	writeCert(t, dir, "dice-2")
+	assert.NoError(t, r.Reload())
+	assert.Equal(t, "dice-2", commonName())
+
+	// The current certificate is kept if failed to reload
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "tls.key"), []byte("broken"), 0600))
+	assert.Error(t, r.Reload())
+	assert.Equal(t, "dice-2", commonName())
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// Tracing is the tracer provider of Dice, which is set by SetupTracing, spans are dropped without it
+var Tracing *sdktrace.TracerProvider
+
+// Exporters of spans
+const (
+	TraceExporterOTLP   = "otlp"   // TraceExporterOTLP posts spans to OTLP/HTTP endpoint
+	TraceExporterStdout = "stdout" // TraceExporterStdout prints spans in JSON, for local use
+)
+
+// DefaultTraceEndpoint is the OTLP/HTTP endpoint of a local collector
+const DefaultTraceEndpoint = "http://localhost:4318"
+
+// Span is a timed operation, spans of a deployment share the trace ID derived from its d-sid
+type Span struct {
+	trace.Span
+}
+
+// End ends the span with error if it's not nil. Only the first call takes effect.
+func (s *Span) End(err error) {
+	if err != nil && s.IsRecording() {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+	}
+	s.Span.End()
+}
+
+// SetAttributes sets attributes as key value pairs, empty values are skipped
+func (s *Span) SetAttributes(kv ...string) {
+	s.Span.SetAttributes(attributes(kv)...)
+}
+
+func attributes(kv []string) []attribute.KeyValue {
+	var kvs []attribute.KeyValue
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			kvs = append(kvs, attribute.String(kv[i], kv[i+1]))
+		}
+	}
+	return kvs
+}
+
+// StartSpan starts a span as child of the current one in ctx, and returns ctx with the new span, attributes are key value pairs.
+// The root span has trace ID derived from d-sid in ctx, so that all spans of a deployment could be found by d-sid.
+func StartSpan(ctx context.Context, name string, kv ...string) (context.Context, *Span) {
+	dSid, _ := ctx.Value("d-sid").(string)
+	kv = append([]string{"dice.sid", dSid}, kv...)
+	ctx, span := otel.Tracer("dice").Start(ctx, name, trace.WithAttributes(attributes(kv)...))
+	return ctx, &Span{span}
+}
+
+// TraceEnv returns environment variables of the current span in ctx, such as TRACEPARENT, so that child processes could join the trace
+func TraceEnv(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	var env []string
+	for _, k := range carrier.Keys() {
+		env = append(env, strings.ToUpper(k)+"="+carrier.Get(k))
+	}
+	return env
+}
+
+// TraceIDOf returns trace ID of deployment, which is d-sid without dashes if it's an UUID
+func TraceIDOf(dSid string) string {
+	id := strings.ReplaceAll(dSid, "-", "")
+	if _, err := hex.DecodeString(id); err == nil && len(id) == 32 {
+		return strings.ToLower(id)
+	}
+	sum := sha256.Sum256([]byte(dSid))
+	return hex.EncodeToString(sum[:16])
+}
+
+// dSidIDGenerator generates trace ID of root span from d-sid in ctx, and random IDs otherwise
+type dSidIDGenerator struct{}
+
+// NewIDs returns trace ID & span ID of root span
+func (dSidIDGenerator) NewIDs(ctx context.Context) (trace.TraceID, trace.SpanID) {
+	var tid trace.TraceID
+	if dSid, _ := ctx.Value("d-sid").(string); dSid != "" {
+		tid, _ = trace.TraceIDFromHex(TraceIDOf(dSid))
+	} else {
+		_, _ = rand.Read(tid[:])
+	}
+	return tid, dSidIDGenerator{}.NewSpanID(ctx, tid)
+}
+
+// NewSpanID returns random span ID
+func (dSidIDGenerator) NewSpanID(ctx context.Context, traceID trace.TraceID) trace.SpanID {
+	var sid trace.SpanID
+	_, _ = rand.Read(sid[:])
+	return sid
+}
+
+// CheckTraceExporter checks name & endpoint of exporter, name is otlp, stdout, or empty if tracing is disabled
+func CheckTraceExporter(name string, endpoint string) error {
+	switch name {
+	case "", TraceExporterStdout:
+	case TraceExporterOTLP:
+		if u, err := url.Parse(traceURL(endpoint)); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.Errorf("trace endpoint %q must be URL of http or https", endpoint)
+		}
+	default:
+		return errors.Errorf("unknown trace exporter %q, supported: otlp, stdout", name)
+	}
+	return nil
+}
+
+// traceURL returns URL where spans are posted, which is <endpoint>/v1/traces unless endpoint ends with the path
+func traceURL(endpoint string) string {
+	if endpoint == "" {
+		endpoint = DefaultTraceEndpoint
+	}
+	u := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(u, "/v1/traces") {
+		u += "/v1/traces"
+	}
+	return u
+}
+
+// NewExporter creates exporter by name: otlp or stdout, nil is returned if name is empty
+func NewExporter(ctx context.Context, name string, endpoint string, out io.Writer) (sdktrace.SpanExporter, error) {
+	if err := CheckTraceExporter(name, endpoint); err != nil {
+		return nil, err
+	}
+	switch name {
+	case TraceExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(out))
+	case TraceExporterOTLP:
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(traceURL(endpoint)))
+	}
+	return nil, nil
+}
+
+// SetupTracing exports spans in batch by exporter, and propagates W3C trace context to child processes
+func SetupTracing(exporter sdktrace.SpanExporter) {
+	Tracing = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithIDGenerator(dSidIDGenerator{}),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "dice"),
+			attribute.String("service.version", ServerVersion))))
+	otel.SetTracerProvider(Tracing)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// ShutdownTracing flushes pending spans and stops exporting
+func ShutdownTracing(ctx context.Context) error {
+	if Tracing == nil {
+		return nil
+	}
+	return Tracing.Shutdown(ctx)
+}
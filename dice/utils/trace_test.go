@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStartSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	SetupTracing(exporter)
+	defer func() { ShutdownTracing(context.Background()); Tracing = nil }()
+
+	ctx := context.WithValue(context.Background(), "d-sid", "0f8e2a4c-1b3d-4e5f-8a9b-0c1d2e3f4a5b")
+	ctx, root := StartSpan(ctx, "deployment", "dice.deployment", "eks-simple")
+	cctx, child := StartSpan(ctx, "PullTile", "dice.tile", "Eks", "dice.tile.category", "")
+	assert.Equal(t, []string{"TRACEPARENT=00-0f8e2a4c1b3d4e5f8a9b0c1d2e3f4a5b-" + child.SpanContext().SpanID().String() + "-01"}, TraceEnv(cctx))
+	child.End(errors.New("not found"))
+	child.End(nil)
+	root.End(nil)
+	assert.NoError(t, Tracing.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "PullTile", spans[0].Name)
+	assert.Equal(t, "0f8e2a4c1b3d4e5f8a9b0c1d2e3f4a5b", spans[1].SpanContext.TraceID().String())
+	assert.Equal(t, spans[1].SpanContext.TraceID(), spans[0].SpanContext.TraceID())
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.False(t, spans[1].Parent.IsValid())
+	assert.Equal(t, []attribute.KeyValue{attribute.String("dice.sid", "0f8e2a4c-1b3d-4e5f-8a9b-0c1d2e3f4a5b"), attribute.String("dice.tile", "Eks")}, spans[0].Attributes)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "not found", spans[0].Status.Description)
+	assert.Len(t, TraceIDOf("not-an-uuid"), 32)
+}
+
+func TestDisabledTracing(t *testing.T) {
+	ctx, span := StartSpan(context.Background(), "deployment")
+	span.End(nil)
+	assert.False(t, span.IsRecording())
+	assert.Empty(t, TraceEnv(ctx))
+	assert.NoError(t, ShutdownTracing(context.Background()))
+}
+
+func TestNewExporter(t *testing.T) {
+	exporter, err := NewExporter(context.Background(), "", "", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, exporter)
+	exporter, err = NewExporter(context.Background(), TraceExporterOTLP, "", nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, exporter)
+	_, err = NewExporter(context.Background(), "zipkin", "", nil)
+	assert.Error(t, err)
+	assert.Error(t, CheckTraceExporter(TraceExporterOTLP, "localhost:4318"))
+	assert.Equal(t, "http://collector:4318/v1/traces", traceURL("http://collector:4318/"))
+	assert.Equal(t, "http://collector:4318/v1/traces", traceURL("http://collector:4318/v1/traces"))
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter, err := NewExporter(context.Background(), TraceExporterStdout, "", &buf)
+	assert.NoError(t, err)
+	SetupTracing(exporter)
+	defer func() { Tracing = nil }()
+
+	_, span := StartSpan(context.Background(), "stage", "dice.tile", "Eks")
+	span.End(nil)
+	assert.NoError(t, ShutdownTracing(context.Background()))
+	var printed map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &printed))
+	assert.Equal(t, "stage", printed["Name"])
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var path, contentType string
+	var size int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		buf, _ := ioutil.ReadAll(r.Body)
+		size = len(buf)
+	}))
+	defer srv.Close()
+
+	exporter, err := NewExporter(context.Background(), TraceExporterOTLP, srv.URL+"/", nil)
+	assert.NoError(t, err)
+	SetupTracing(exporter)
+	defer func() { Tracing = nil }()
+
+	_, span := StartSpan(context.Background(), "commands")
+	span.End(errors.New("exit status 1"))
+	assert.NoError(t, ShutdownTracing(context.Background()))
+	assert.Equal(t, "/v1/traces", path)
+	assert.True(t, strings.HasPrefix(contentType, "application/x-protobuf"))
+	assert.NotZero(t, size)
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bytes"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SaveTile stores packaged Tile (tarball) & tile-spec.yaml into repo, and return digest of the tarball
+func (dc *DiceConfig) SaveTile(tile string, version string, tgz []byte, spec []byte) (digest string, err error) {
+	defer dc.countRepoError("save-tile", &err)
+	if dc.Mode == "dev" {
+		return Digest(tgz), dc.SaveTileDev(tile, version, tgz)
+	} else {
+		return Digest(tgz), dc.SaveTileS3(tile, version, tgz, spec)
+	}
+}
+
+// SaveTileDev unpacks Tile into local repo, and keeps the tarball next to it for digest, the existing one would be replaced
+func (dc *DiceConfig) SaveTileDev(tile string, version string, tgz []byte) error {
+	destDir := dc.LocalRepo + "/" + strings.ToLower(tile) + "/" + strings.ToLower(version)
+	log.Printf("Save Tile < %s - %s > ... into < %s >\n", tile, version, destDir)
+	if err := os.RemoveAll(destDir); err != nil {
+		return err
+	}
+	if err := UnTarGz(destDir, bytes.NewReader(tgz)); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(destDir+".tgz", tgz, 0644)
+}
+
+// SaveTileS3 uploads Tile into S3 repo, as same layout as hack/sync-tile.sh
+func (dc *DiceConfig) SaveTileS3(tile string, version string, tgz []byte, spec []byte) error {
+	prefix := "tiles-repo/" + strings.ToLower(tile) + "/" + version + "/"
+	if err := dc.PutObjectS3(prefix+strings.ToLower(tile)+".tgz", tgz); err != nil {
+		return err
+	}
+	return dc.PutObjectS3(prefix+"tile-spec.yaml", spec)
+}
+
+// SaveHu stores Hu into repo
+func (dc *DiceConfig) SaveHu(hu string, buf []byte) (err error) {
+	defer dc.countRepoError("save-hu", &err)
+	if dc.Mode == "dev" {
+		return dc.SaveHuDev(hu, buf)
+	} else {
+		return dc.PutObjectS3("templates/"+strings.ToLower(hu)+".yaml", buf)
+	}
+}
+
+// SaveHuDev stores Hu into local repo
+func (dc *DiceConfig) SaveHuDev(hu string, buf []byte) error {
+	huSpecFile := dc.huSpecFileDev(hu)
+	if err := os.MkdirAll(filepath.Dir(huSpecFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(huSpecFile, buf, 0644)
+}
+
+// PutObjectS3 uploads object into S3 repo with public read, so that it can be loaded through https
+func (dc *DiceConfig) PutObjectS3(key string, buf []byte) error {
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(dc.Region),
+	})
+	if err != nil {
+		return err
+	}
+	svc := s3.New(sess)
+	log.Printf("Uploading s3://%s/%s ...\n", dc.BucketName, key)
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(dc.BucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(buf),
+		ACL:    aws.String(s3.ObjectCannedACLPublicRead),
+	})
+	return err
+}
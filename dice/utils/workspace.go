@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultWorkspace is the workspace configured by M_* environment variables, which is used without a workspace
+const DefaultWorkspace = "default"
+
+// workspaceNameRe is valid name of workspace, which is used as folder name as well
+var workspaceNameRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// WorkspacesConfig is configuration file of workspaces, eg:
+//
+//	workspaces:
+//	  - name: team-a
+//	    workHome: /var/dice/team-a
+//	    localRepo: /var/dice/team-a-repo
+//	    profile: team-a
+//	    members:
+//	      alice: admin
+//	      "*": viewer
+type WorkspacesConfig struct {
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// Workspace has its own work folder, repo, default AWS profile & members, empty ones are inherited from the default workspace
+type Workspace struct {
+	Name       string            `json:"name"`
+	WorkHome   string            `json:"workHome,omitempty"` // WorkHome is <default work home>-workspaces/<name> if it's empty
+	Mode       string            `json:"mode,omitempty"`
+	Region     string            `json:"region,omitempty"`
+	BucketName string            `json:"bucketName,omitempty"`
+	LocalRepo  string            `json:"localRepo,omitempty"`
+	Profile    string            `json:"profile,omitempty"`
+	Members    map[string]string `json:"members,omitempty"` // Members are roles by name of principal, "*" for other callers
+}
+
+// LoadWorkspaces loads workspaces from file, and returns configuration of all workspaces including the default one
+func LoadWorkspaces(file string, base *DiceConfig) (map[string]*DiceConfig, error) {
+	workspaces := map[string]*DiceConfig{DefaultWorkspace: base}
+	base.Workspace = DefaultWorkspace
+	if file == "" {
+		return workspaces, nil
+	}
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var wc WorkspacesConfig
+	if err := yaml.UnmarshalStrict(buf, &wc); err != nil {
+		return nil, errors.Wrap(err, "invalid workspaces")
+	}
+	for _, w := range wc.Workspaces {
+		if !workspaceNameRe.MatchString(w.Name) {
+			return nil, errors.Errorf("invalid name of workspace: %q", w.Name)
+		}
+		dc := base
+		if w.Name != DefaultWorkspace {
+			if _, ok := workspaces[w.Name]; ok {
+				return nil, errors.Errorf("duplicated workspace: %s", w.Name)
+			}
+			copied := *base
+			dc = &copied
+			dc.Workspace = w.Name
+			dc.WorkHome = filepath.Join(siblingOf(base.WorkHome, "workspaces"), w.Name)
+			dc.Members = nil
+		}
+		for _, v := range []struct {
+			value string
+			field *string
+		}{{w.WorkHome, &dc.WorkHome}, {w.Mode, &dc.Mode}, {w.Region, &dc.Region}, {w.BucketName, &dc.BucketName}, {w.LocalRepo, &dc.LocalRepo}, {w.Profile, &dc.Profile}} {
+			if v.value != "" {
+				*v.field = v.value
+			}
+		}
+		if len(w.Members) > 0 {
+			dc.Members = make(map[string]Role)
+			for name, role := range w.Members {
+				r := ParseRole(role)
+				if r == NoRole {
+					return nil, errors.Errorf("invalid role %q of %s in workspace %s", role, name, w.Name)
+				}
+				dc.Members[name] = r
+			}
+		}
+		workspaces[w.Name] = dc
+	}
+	return workspaces, nil
+}
+
+// RoleOf returns Role of the principal in the workspace, which is never higher than the global one.
+// All principals have their global Role if the workspace has no member.
+func (dc *DiceConfig) RoleOf(p *Principal) Role {
+	if len(dc.Members) == 0 {
+		return p.Role
+	}
+	role, ok := dc.Members[p.Name]
+	if !ok {
+		role = dc.Members["*"]
+	}
+	if role > p.Role {
+		return p.Role
+	}
+	return role
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadWorkspaces(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "workspaces")
+	config := `workspaces:
+  - name: default
+    members:
+      ops: admin
+  - name: team-a
+    localRepo: /var/dice/team-a-repo
+    profile: team-a
+    members:
+      alice: admin
+      "*": viewer
+  - name: team-b
+    workHome: /var/dice/team-b
+`
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "workspaces.yaml"), []byte(config), 0644))
+	base := &DiceConfig{WorkHome: "/var/dice", Mode: "dev", LocalRepo: "/var/dice/repo"}
+	workspaces, err := LoadWorkspaces(filepath.Join(dir, "workspaces.yaml"), base)
+	assert.NoError(t, err)
+	assert.Len(t, workspaces, 3)
+
+	assert.Same(t, base, workspaces[DefaultWorkspace])
+	assert.Equal(t, DefaultWorkspace, base.Workspace)
+	assert.Equal(t, "/var/dice-workspaces/team-a", workspaces["team-a"].WorkHome)
+	assert.Equal(t, "/var/dice/team-a-repo", workspaces["team-a"].LocalRepo)
+	assert.Equal(t, "team-a", workspaces["team-a"].Profile)
+	assert.Equal(t, "dev", workspaces["team-a"].Mode)
+	assert.Equal(t, "/var/dice/team-b", workspaces["team-b"].WorkHome)
+	assert.Equal(t, "/var/dice/repo", workspaces["team-b"].LocalRepo)
+	assert.Empty(t, workspaces["team-b"].Members)
+
+	tests := []struct {
+		name      string
+		workspace string
+		principal *Principal
+		want      Role
+	}{
+		{"member of default", DefaultWorkspace, &Principal{Name: "ops", Role: Admin}, Admin},
+		{"not member of default", DefaultWorkspace, &Principal{Name: "alice", Role: Admin}, NoRole},
+		{"member", "team-a", &Principal{Name: "alice", Role: Admin}, Admin},
+		{"never higher than global role", "team-a", &Principal{Name: "alice", Role: Deployer}, Deployer},
+		{"others", "team-a", &Principal{Name: "bob", Role: Admin}, Viewer},
+		{"without members", "team-b", &Principal{Name: "bob", Role: Deployer}, Deployer},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, workspaces[test.workspace].RoleOf(test.principal))
+		})
+	}
+
+	for name, invalid := range map[string]string{
+		"invalid name": "workspaces:\n  - name: Team_A\n",
+		"invalid role": "workspaces:\n  - name: team-a\n    members:\n      alice: owner\n",
+		"duplicated":   "workspaces:\n  - name: team-a\n  - name: team-a\n",
+		"unknown":      "workspaces:\n  - name: team-a\n    bucket: repo\n",
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "invalid.yaml"), []byte(invalid), 0644))
+		_, err := LoadWorkspaces(filepath.Join(dir, "invalid.yaml"), &DiceConfig{WorkHome: "/var/dice"})
+		assert.Error(t, err, name)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -69,6 +70,7 @@ func Unzip(src string, dest string) ([]string, error) {
 	return filenames, nil
 }
 
+// UnTarGz decompresses the tarball into dst, entries out of dst & links are rejected
 func UnTarGz(dst string, r io.Reader) error {
 
 	if _, err := os.Stat(dst); err != nil {
@@ -110,6 +112,11 @@ func UnTarGz(dst string, r io.Reader) error {
 		// the target location where the dir/file should be created
 		target := filepath.Join(dst, header.Name)
 
+		// Check for ZipSlip, entries must stay under dst
+		if target != filepath.Clean(dst) && !strings.HasPrefix(target, filepath.Clean(dst)+string(os.PathSeparator)) {
+			return fmt.Errorf("%s: illegal file path", header.Name)
+		}
+
 		// the following switch could also be done using fi.Mode(), not sure if there
 		// a benefit of using one vs. the other.
 		// fi := header.FileInfo()
@@ -142,6 +149,33 @@ func UnTarGz(dst string, r io.Reader) error {
 			if err = f.Close(); err != nil {
 				return err
 			}
+
+		// links could point out of dst, which are written through by next entries
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("%s: links aren't allowed in the tarball", header.Name)
+		}
+	}
+}
+
+// ReadFileFromTarGz returns content of a file in the tarball, eg: tile-spec.yaml
+func ReadFileFromTarGz(r io.Reader, name string) ([]byte, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s wasn't existed in the tarball", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Clean(header.Name) == filepath.Clean(name) {
+			return ioutil.ReadAll(tr)
 		}
 	}
 }
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarGz returns a tarball of the entries, content of each file is its name
+func tarGz(t *testing.T, entries ...*tar.Header) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for _, h := range entries {
+		if h.Typeflag == tar.TypeReg {
+			h.Size, h.Mode = int64(len(h.Name)), 0644
+		}
+		assert.NoError(t, tw.WriteHeader(h))
+		if h.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(h.Name))
+			assert.NoError(t, err)
+		}
+	}
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gzw.Close())
+	return buf
+}
+
+func TestUnTarGz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tarball")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dst := filepath.Join(dir, "tile")
+
+	assert.NoError(t, UnTarGz(dst, tarGz(t,
+		&tar.Header{Name: "./", Typeflag: tar.TypeDir},
+		&tar.Header{Name: "lib/", Typeflag: tar.TypeDir},
+		&tar.Header{Name: "lib/main.ts", Typeflag: tar.TypeReg},
+		&tar.Header{Name: "lib/../tile-spec.yaml", Typeflag: tar.TypeReg})))
+	buf, err := ioutil.ReadFile(filepath.Join(dst, "lib", "main.ts"))
+	assert.NoError(t, err)
+	assert.Equal(t, "lib/main.ts", string(buf))
+	assert.FileExists(t, filepath.Join(dst, "tile-spec.yaml"))
+
+	var tests = []struct {
+		name  string
+		entry *tar.Header
+	}{
+		{name: "parent", entry: &tar.Header{Name: "../evil", Typeflag: tar.TypeReg}},
+		{name: "nested parent", entry: &tar.Header{Name: "lib/../../evil", Typeflag: tar.TypeReg}},
+		{name: "sibling prefix", entry: &tar.Header{Name: "../tile-evil/evil", Typeflag: tar.TypeReg}},
+		{name: "absolute", entry: &tar.Header{Name: "/../evil", Typeflag: tar.TypeReg}},
+		{name: "symlink", entry: &tar.Header{Name: "lib/evil", Linkname: "../../evil", Typeflag: tar.TypeSymlink}},
+		{name: "hardlink", entry: &tar.Header{Name: "lib/evil", Linkname: "/etc/passwd", Typeflag: tar.TypeLink}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Error(t, UnTarGz(dst, tarGz(t, test.entry)))
+			assert.NoFileExists(t, filepath.Join(dir, "evil"))
+			assert.NoFileExists(t, filepath.Join(dir, "tile-evil", "evil"))
+			assert.NoFileExists(t, filepath.Join(dst, "lib", "evil"))
+		})
+	}
+}
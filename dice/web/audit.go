@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"dice/engine"
+	"dice/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// newAuditEntry creates an audit entry of action by the caller
+func newAuditEntry(c *gin.Context, action string) *utils.AuditEntry {
+	p := principal(c)
+	return &utils.AuditEntry{
+		Action:    action,
+		Who:       p.Name,
+		Role:      p.Role.RString(),
+		Source:    c.ClientIP(),
+		Workspace: workspace(c).Workspace,
+	}
+}
+
+// record writes audit entry with result of the action
+func record(e *utils.AuditEntry, err error) {
+	e.Result = utils.AuditSuccess
+	if err != nil {
+		e.Result = utils.AuditFailure
+		e.Error = err.Error()
+	}
+	if engine.DiceConfig.Audit != nil {
+		engine.DiceConfig.Audit.Record(e)
+	}
+}
+
+// ctxAuditEntry returns audit entry in context, which is filled while processing
+func ctxAuditEntry(ctx context.Context) *utils.AuditEntry {
+	if e, ok := ctx.Value("audit").(*utils.AuditEntry); ok {
+		return e
+	}
+	return &utils.AuditEntry{}
+}
+
+// Audit returns the latest audit entries in the workspace, which can be filtered by utils.AuditFilter
+func Audit(ctx context.Context, c *gin.Context) {
+	var filter utils.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Workspace = workspace(c).Workspace
+	if engine.DiceConfig.Audit == nil {
+		c.JSON(http.StatusOK, []utils.AuditEntry{})
+		return
+	}
+	entries, err := engine.DiceConfig.Audit.Query(&filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
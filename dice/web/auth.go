@@ -0,0 +1,137 @@
+package web
+
+import (
+	"crypto/rand"
+	"dice/engine"
+	"dice/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// principalKey is key of authenticated caller in gin.Context
+const principalKey = "principal"
+
+// Authorize authenticates bearer token and requires the caller has the Role at least.
+// The Role is as per members of the selected workspace, which is never higher than the global one.
+func Authorize(role utils.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ws, ok := engine.WorkspaceConfig(requestedWorkspace(c))
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "workspace wasn't found: " + requestedWorkspace(c)})
+			return
+		}
+		c.Set(workspaceKey, ws)
+		auth := engine.DiceConfig.Auth
+		if auth == nil {
+			c.Set(principalKey, &utils.Principal{Name: "anonymous", Role: utils.Admin})
+			c.Next()
+			return
+		}
+		p, err := auth.Authenticate(bearerToken(c))
+		if err != nil {
+			log.Warningf("%s was unauthorized to %s %s : %s\n", c.Request.RemoteAddr, c.Request.Method, c.Request.URL.Path, err)
+			c.Header("WWW-Authenticate", `Bearer realm="dice"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if ws.RoleOf(p) < role {
+			log.Warningf("%s (%s) was forbidden to %s %s in workspace %s\n", p.Name, ws.RoleOf(p).RString(), c.Request.Method, c.Request.URL.Path, ws.Workspace)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "role " + role.RString() + " is required"})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// principal returns the authenticated caller
+func principal(c *gin.Context) *utils.Principal {
+	if p, ok := c.Get(principalKey); ok {
+		return p.(*utils.Principal)
+	}
+	return &utils.Principal{Name: "anonymous"}
+}
+
+// bearerToken retrieves token from Authorization header, or access_token query for WebSocket as browsers can't set header
+func bearerToken(c *gin.Context) string {
+	if h := c.GetHeader("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		return c.Query("access_token")
+	}
+	return ""
+}
+
+// redactToken hides access_token in query of the path
+func redactToken(path string) string {
+	u, err := url.Parse(path)
+	if err != nil {
+		return strings.SplitN(path, "?", 2)[0]
+	}
+	q := u.Query()
+	if _, ok := q["access_token"]; !ok {
+		return path
+	}
+	q.Set("access_token", "REDACTED")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// accessLog is as same as default log of gin, but access_token of WebSocket is redacted
+func accessLog(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor, methodColor, resetColor = param.StatusCodeColor(), param.MethodColor(), param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency - param.Latency%time.Second
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		redactToken(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+// checkOrigin allows clients without Origin, such as mctl, same origin and allowed origins
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	if auth := engine.DiceConfig.Auth; auth != nil {
+		for _, o := range auth.AllowedOrigins {
+			if o == "*" || strings.EqualFold(o, origin) {
+				return true
+			}
+		}
+	}
+	log.Warningf("Origin %s of WebSocket wasn't allowed\n", origin)
+	return false
+}
+
+// cookieSecret is from configuration, or generated randomly per process
+func cookieSecret() []byte {
+	if auth := engine.DiceConfig.Auth; auth != nil && auth.CookieSecret != "" {
+		return []byte(auth.CookieSecret)
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatalf("Failed to generate cookie secret: %s", err)
+	}
+	return secret
+}
@@ -0,0 +1,23 @@
+package web
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// WebSocketConnections is the number of open WebSocket connections
+var WebSocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "dice_websocket_connections",
+	Help: "Open WebSocket connections.",
+})
+
+// metricsHandler exposes metrics of the default registry, including Go runtime & process
+var metricsHandler = promhttp.Handler()
+
+// Metrics exposes all metrics in Prometheus text format
+func Metrics(ctx context.Context, c *gin.Context) {
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
@@ -12,87 +12,142 @@ import (
 	"runtime"
 )
 
-// Router tells all routing definition
+// Router tells all routing definition, Role of caller is checked per route if authentication was enabled.
+// Every API is scoped to the workspace in X-Dice-Workspace header or workspace query, the default one if it wasn't given.
 func Router(ctx context.Context) *gin.Engine {
-	r := gin.Default()
-	store := cookie.NewStore([]byte("secret"))
+	r := gin.New()
+	r.Use(gin.LoggerWithFormatter(accessLog), gin.Recovery())
+	store := cookie.NewStore(cookieSecret())
 	r.Use(sessions.Sessions("server-session", store))
 
 	// Deployment API through WebSocket
-	r.GET("/v1alpha1/ws", func(c *gin.Context) {
+	r.GET("/v1alpha1/ws", Authorize(utils.Deployer), func(c *gin.Context) {
 		WsHandler(ctx, c)
 	})
-	// Deployment API through WebSocket, but dry run only
-	r.GET("/v1alpha1/ws?dryRun=true", func(c *gin.Context) {
+	// Deployment API through WebSocket, but dry run only, which requires deployer too as it generates the app & locks the name
+	r.GET("/v1alpha1/ws?dryRun=true", Authorize(utils.Deployer), func(c *gin.Context) {
 		WsHandler(ctx, c)
 	})
 	// Parallel Deployment
-	r.GET("/v1alpha1/ws?parallel=true", func(c *gin.Context) {
+	r.GET("/v1alpha1/ws?parallel=true", Authorize(utils.Deployer), func(c *gin.Context) {
 		WsHandler(ctx, c)
 	})
-	r.GET("/v1alpha1/ws?parallel=true&dryRun=true", func(c *gin.Context) {
-		WsHandler(ctx, c)
-	})
-	// Run Linux commands for purpose
-	r.GET("/v1alpha1/ws?linuxCommand=true", func(c *gin.Context) {
+	r.GET("/v1alpha1/ws?parallel=true&dryRun=true", Authorize(utils.Deployer), func(c *gin.Context) {
 		WsHandler(ctx, c)
 	})
 
 	// Destroy API through WebSocket
-	r.GET("/v1alpha1/destroy", func(c *gin.Context) {
+	r.GET("/v1alpha1/destroy", Authorize(utils.Deployer), func(c *gin.Context) {
 		//TODO: delete local cache & print out guide
 		c.String(http.StatusOK, "Coming soon!")
 	})
 
 	// Return url of basic templates as per request
-	r.GET("/v1alpha1/template/:what", func(c *gin.Context) {
+	r.GET("/v1alpha1/template/:what", Authorize(utils.Viewer), func(c *gin.Context) {
 		Template(ctx, c)
 	})
 
 	// Retrieve metadata from tiles repo
-	r.GET("/v1alpha1/repo/:what", func(c *gin.Context) {
+	r.GET("/v1alpha1/repo/:what", Authorize(utils.Viewer), func(c *gin.Context) {
 		Metadata(ctx, c)
 	})
+	// Rebuild index of tiles repo
+	r.POST("/v1alpha1/repo/index", Authorize(utils.Admin), func(c *gin.Context) {
+		RebuildIndex(ctx, c)
+	})
+	// Push packaged Tile into tiles repo
+	r.POST("/v1alpha1/repo/tile/:name/:version", Authorize(utils.Admin), func(c *gin.Context) {
+		PushTile(ctx, c)
+	})
+	// Push Hu into tiles repo
+	r.POST("/v1alpha1/repo/hu/:name", Authorize(utils.Admin), func(c *gin.Context) {
+		PushHu(ctx, c)
+	})
 	// Retrieve detail of specification tile
-	r.GET("/v1alpha1/tile/:name/:version", func(c *gin.Context) {
+	r.GET("/v1alpha1/tile/:name/:version", Authorize(utils.Viewer), func(c *gin.Context) {
 		TileSpec(ctx, c)
 	})
-	// Retrieve detail of specification hu
-	r.GET("/v1alpha1/hu/:name", func(c *gin.Context) {
+	// Retrieve detail of specification hu, along with declared parameters in JSON if application/json is accepted
+	r.GET("/v1alpha1/hu/:name", Authorize(utils.Viewer), func(c *gin.Context) {
 		HuSpec(ctx, c)
 	})
 
 	// Validate Tile specification
-	r.POST("/v1alpha1/tile", func(c *gin.Context) {
+	r.POST("/v1alpha1/tile", Authorize(utils.Viewer), func(c *gin.Context) {
 		Tile(ctx, c)
 	})
 
 	// Validate Deployment specification
-	r.POST("/v1alpha1/deployment", func(c *gin.Context) {
+	r.POST("/v1alpha1/deployment", Authorize(utils.Viewer), func(c *gin.Context) {
 		Deployment(ctx, c)
 	})
 
 	// AllTs content in memory
-	r.GET("/v1alpha1/ts/:sid", func(c *gin.Context) {
+	r.GET("/v1alpha1/ts/:sid", Authorize(utils.Deployer), scopeSID, func(c *gin.Context) {
 		Ts(ctx, c)
 	})
-	r.GET("/v1alpha1/ts/:sid/plan", func(c *gin.Context) {
+	r.GET("/v1alpha1/ts/:sid/plan", Authorize(utils.Deployer), scopeSID, func(c *gin.Context) {
 		Plan(ctx, c)
 	})
-	r.GET("/v1alpha1/ts/:sid/plan/order", func(c *gin.Context) {
+	r.GET("/v1alpha1/ts/:sid/plan/order", Authorize(utils.Viewer), scopeSID, func(c *gin.Context) {
 		PlanOrder(ctx, c)
 	})
-	r.GET("/v1alpha1/ts/:sid/plan/order/parallel", func(c *gin.Context) {
+	r.GET("/v1alpha1/ts/:sid/plan/order/parallel", Authorize(utils.Viewer), scopeSID, func(c *gin.Context) {
 		ParallelOrder(ctx, c)
 	})
-	r.GET("/v1alpha1/ts/:sid/tg", func(c *gin.Context) {
+	r.GET("/v1alpha1/ts/:sid/tg", Authorize(utils.Viewer), scopeSID, func(c *gin.Context) {
 		TilesGrid(ctx, c)
 	})
+	// Dependency graph of Tiles
+	r.GET("/v1alpha1/ts/:sid/graph", Authorize(utils.Viewer), scopeSID, func(c *gin.Context) {
+		Graph(ctx, c)
+	})
+	// Summary outputs & outputs of Tiles, sid could be name of deployment
+	r.GET("/v1alpha1/ts/:sid/outputs", Authorize(utils.Viewer), scopeSID, func(c *gin.Context) {
+		Outputs(ctx, c)
+	})
+	// Allowlisted kubectl get/describe against kube.config of deployment, sid could be name of deployment
+	r.POST("/v1alpha1/ts/:sid/diagnostics", Authorize(utils.Admin), scopeSID, func(c *gin.Context) {
+		Diagnostic(ctx, c)
+	})
+	// Lock of resolved Tiles
+	r.GET("/v1alpha1/ts/:sid/lock", Authorize(utils.Viewer), scopeSID, func(c *gin.Context) {
+		Lock(ctx, c)
+	})
+
+	// Audit log in the workspace
+	r.GET("/v1alpha1/audit", Authorize(utils.Admin), func(c *gin.Context) {
+		Audit(ctx, c)
+	})
 
 	// List deployments in memory
-	r.GET("/v1alpha1/ts", func(c *gin.Context) {
+	r.GET("/v1alpha1/ts", Authorize(utils.Viewer), func(c *gin.Context) {
 		AllTsD(ctx, c)
 	})
+	// Queued deployments, which are waiting for a slot of concurrency
+	r.GET("/v1alpha1/queue", Authorize(utils.Viewer), func(c *gin.Context) {
+		Queue(ctx, c)
+	})
+	// Cancel a queued deployment, sid could be name of deployment
+	r.POST("/v1alpha1/queue/:sid/cancel", Authorize(utils.Deployer), func(c *gin.Context) {
+		CancelQueued(ctx, c)
+	})
+	// Locks of deployment names, only one deployment of a name runs at a time
+	r.GET("/v1alpha1/locks", Authorize(utils.Viewer), func(c *gin.Context) {
+		NameLocks(ctx, c)
+	})
+	// Force unlock a stale lock of deployment name
+	r.POST("/v1alpha1/locks/:name/unlock", Authorize(utils.Admin), func(c *gin.Context) {
+		ForceUnlock(ctx, c)
+	})
+	// Sweep runs as per retention, dryRun=true reports what would be removed
+	r.POST("/v1alpha1/gc", Authorize(utils.Admin), func(c *gin.Context) {
+		Sweep(ctx, c)
+	})
+	// Checkpoints of deployments, which are kept across restart
+	r.GET("/v1alpha1/checkpoints", Authorize(utils.Deployer), func(c *gin.Context) {
+		Checkpoints(ctx, c)
+	})
 
 	// Version of Dice
 	r.GET("/version", func(c *gin.Context) {
@@ -105,6 +160,11 @@ func Router(ctx context.Context) *gin.Engine {
 		c.String(http.StatusOK, version)
 	})
 
+	// Metrics in Prometheus text format
+	r.GET("/metrics", func(c *gin.Context) {
+		Metrics(ctx, c)
+	})
+
 	// Health API
 	r.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "pong")
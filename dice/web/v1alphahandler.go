@@ -1,11 +1,13 @@
 package web
 
 import (
+	"bytes"
 	"context"
 	"dice/apis/v1alpha1"
 	"dice/engine"
 	"dice/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,14 +15,16 @@ import (
 	log "github.com/sirupsen/logrus"
 	"net/http"
 	"sigs.k8s.io/yaml"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var upGrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+	CheckOrigin:     checkOrigin,
 }
 
 // WsBox is WebSocket struct with connection only so far
@@ -37,37 +41,76 @@ type WsWorker interface {
 
 // WsHandler handle all coming request from WebSocket
 func WsHandler(ctx context.Context, c *gin.Context) {
-	log.Printf("%s connected to %s \n", c.Request.RemoteAddr, c.Request.RequestURI)
+	log.Printf("%s connected to %s \n", c.Request.RemoteAddr, c.Request.URL.Path)
+	if engine.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": engine.ErrShuttingDown.Error()})
+		return
+	}
 	ws, err := upGrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Print("upgrade error:", err)
 		return
 	}
-	stx, cancel := context.WithCancel(ctx)
+	stx, cancel := context.WithCancelCause(context.WithValue(ctx, "workspace", workspace(c).Workspace))
 	ws.SetCloseHandler(func(code int, txt string) error {
 		return WsCloseHandler(cancel, code, txt)
 	})
 	//ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
 	defer ws.Close()
+	WebSocketConnections.Inc()
+	defer WebSocketConnections.Dec()
 
-	linuxCommand := c.Query("linuxCommand") == "true"
 	dryRun := c.Query("dryRun") == "true"
 	parallel := c.Query("parallel") == "true"
-	for {
-		mt, message, err := ws.ReadMessage()
-		if err != nil {
-			log.Println("read error:", err)
-			break
+	// Messages are read while deploying, so that close of connection could cancel the deployment in progress
+	defer cancel(nil)
+	messages := make(chan wsMessage, 16)
+	go func() {
+		defer close(messages)
+		for {
+			mt, message, err := ws.ReadMessage()
+			if err != nil {
+				log.Println("read error:", err)
+				return
+			}
+			select {
+			case messages <- wsMessage{mt, message}:
+			case <-stx.Done():
+				return
+			}
 		}
+	}()
+	for m := range messages {
+		mt, message := m.messageType, m.data
 		log.Printf("recv: %s\n", message)
 
 		wb := WsBox{out: ws}
-		if linuxCommand {
-			ep := &engine.ExecutionPlan{}
-			ep.LinuxCommandExecutor(stx, message, nil, wb.out)
-		} else {
-			err = wb.Processor(stx, mt, message, dryRun, parallel)
+		if engine.Draining() {
+			engine.SRf(wb.out, "%s, deployment was rejected.", engine.ErrShuttingDown)
+			engine.SR(wb.out, []byte("d-done"))
+			continue
+		}
+		action := utils.AuditDeploy
+		if dryRun {
+			action = utils.AuditDryRun
+		}
+		entry := newAuditEntry(c, action)
+		entry.Digest = utils.Digest(message)
+		if parallel {
+			entry.Details = map[string]string{"parallel": "true"}
 		}
+		engine.RunningDeployments.Inc()
+		err = wb.Processor(context.WithValue(stx, "audit", entry), mt, message, dryRun, parallel)
+		engine.RunningDeployments.Dec()
+		record(entry, err)
+		if err != nil && context.Cause(stx) == errWebSocketClosed {
+			recordClosed(entry)
+		}
+		status := engine.Done.DSString()
+		if err != nil {
+			status = engine.Interrupted.DSString()
+		}
+		engine.DeploymentsTotal.WithLabelValues(status, action).Inc()
 		if err != nil {
 			engine.SR(wb.out, []byte(err.Error()))
 		}
@@ -76,13 +119,30 @@ func WsHandler(ctx context.Context, c *gin.Context) {
 	}
 }
 
+// wsMessage is a message read from WebSocket connection
+type wsMessage struct {
+	messageType int
+	data        []byte
+}
+
+// errWebSocketClosed is the cause of cancelling the deployment when client closed the connection
+var errWebSocketClosed = errors.New("WebSocket connection was closed by client")
+
 // WsCloseHandler handle close connection
-func WsCloseHandler(cancel context.CancelFunc, code int, txt string) error {
+func WsCloseHandler(cancel context.CancelCauseFunc, code int, txt string) error {
 	log.Printf("WebSocket connection was closed...error: %d - %s\n", code, txt)
-	cancel()
+	cancel(errWebSocketClosed)
 	return nil
 }
 
+// recordClosed records the deployment was cancelled as its WebSocket connection was closed
+func recordClosed(deployment *utils.AuditEntry) {
+	entry := *deployment
+	entry.Action, entry.Time, entry.Digest, entry.Error = utils.AuditCancel, time.Time{}, "", ""
+	entry.Details = map[string]string{"reason": errWebSocketClosed.Error()}
+	record(&entry, nil)
+}
+
 // Processor handle full process of deployment request
 func (wb *WsBox) Processor(ctx context.Context, messageType int, p []byte, dryRun bool, parallel bool) error {
 	var ep *engine.ExecutionPlan
@@ -96,25 +156,96 @@ func (wb *WsBox) Processor(ctx context.Context, messageType int, p []byte, dryRu
 		return err
 	}
 	engine.SR(wb.out, []byte("Parsing Deployment was success."))
+	ctxAuditEntry(ctx).Name = deployment.Metadata.Name
+	for _, o := range deployment.AppliedOverlays {
+		engine.SRf(wb.out, "Applied overlay: %s", o)
+	}
+	for _, pv := range deployment.ResolvedParameters {
+		engine.SRf(wb.out, "Parameter %s = %s (%s)", pv.Name, pv.Value, pv.Source)
+	}
+	var expanded []string
+	for instance := range deployment.ExpandedTiles {
+		expanded = append(expanded, instance)
+	}
+	sort.Strings(expanded)
+	for _, instance := range expanded {
+		engine.SRf(wb.out, "Tile instance %s was expanded into: %s", instance, strings.Join(deployment.ExpandedTiles[instance], ", "))
+	}
+	for _, dt := range deployment.DisabledTiles {
+		engine.SRf(wb.out, "%s", dt)
+	}
+	if len(deployment.AppliedValues) > 0 {
+		engine.SR(wb.out, []byte("Applied values, order of precedence: Deployment < values in submitted order < set in submitted order"))
+		for _, av := range deployment.AppliedValues {
+			engine.SRf(wb.out, "  %s", av)
+		}
+	}
+	lock, err := dt.ParseLock(ctx)
+	if err != nil {
+		engine.SRf(wb.out, "Parsing Lock error : %s \n", err)
+		return err
+	}
+	if lock != nil {
+		engine.SRf(wb.out, "Deployment was submitted with %s, %d Tiles were locked.", v1alpha1.LockFileName, len(lock.Tiles))
+	}
 	//engine.SR(wb.out, []byte("--BO:-------------------------------------------------"))
 	//b, _ := yaml.Marshal(deployment)
 	//engine.SR(wb.out, b)
 	//engine.SR(wb.out, []byte("--EO:-------------------------------------------------"))
 
 	// 2. Looking for the dSid of last deployment
-	rdSid, isRepeated := engine.IsRepeatedDeployment(deployment.Metadata.Name)
+	rdSid, isRepeated := engine.IsRepeatedDeployment(engine.CtxWorkspace(ctx), deployment.Metadata.Name)
 	if isRepeated {
 		engine.SRf(wb.out, "Repeated deployment and last d-dSid = %s", rdSid)
 	}
 	dSid := uuid.New().String()
 	ctx = context.WithValue(ctx, "d-sid", dSid)
+	ctxAuditEntry(ctx).SID = dSid
 	engine.SRf(wb.out, "Created a new d-dSid = %s", dSid)
+	mode := utils.AuditDeploy
+	if dryRun {
+		mode = utils.AuditDryRun
+	}
+	ctx, span := utils.StartSpan(ctx, "deployment",
+		"dice.deployment", deployment.Metadata.Name,
+		"dice.workspace", engine.CtxWorkspace(ctx),
+		"dice.mode", mode,
+		"dice.parallel", strconv.FormatBool(parallel))
+	defer func() { span.End(err) }()
+
+	// Register the deployment, so that it could be drained & checkpointed on shutdown
+	run := &engine.Run{
+		SID:       dSid,
+		Name:      deployment.Metadata.Name,
+		Workspace: engine.CtxWorkspace(ctx),
+		DryRun:    dryRun,
+		Parallel:  parallel,
+		Submitted: p,
+		Secrets:   deployment.Secrets(),
+		Out:       wb.out,
+		Accounts:  engine.Accounts(engine.CtxWorkspace(ctx), deployment),
+		Who:       ctxAuditEntry(ctx).Who,
+	}
+	if ctx, err = engine.StartRun(ctx, run); err != nil {
+		return err
+	}
+	defer func() { engine.FinishRun(run, err) }()
+
+	// Wait for lock of the deployment name & a slot of concurrency, limits of concurrency aren't applied to dry run
+	_, queueSpan := utils.StartSpan(ctx, "queue", "dice.accounts", strings.Join(run.Accounts, ","))
+	err = engine.Acquire(ctx, run)
+	queueSpan.End(err)
+	if err != nil {
+		return err
+	}
 
 	//
 	// 3. assemble super app with base templates +
 	engine.SR(wb.out, []byte("Generating main app..."))
 	assemble := engine.AssembleData{
 		Deployment: deployment,
+		Lock:       lock,
+		DryRun:     dryRun,
 	}
 	ep, err = assemble.GenerateMainApp(ctx, wb.out)
 	if err != nil {
@@ -125,17 +256,19 @@ func (wb *WsBox) Processor(ctx context.Context, messageType int, p []byte, dryRu
 
 	//
 	// 4. execute cdk / manifest +
+	btx, brewSpan := utils.StartSpan(ctx, "brew")
 	if parallel {
-		err = ep.ExecuteParallelPlan(ctx, dryRun, wb.out)
+		err = ep.ExecuteParallelPlan(btx, dryRun, wb.out)
 	} else {
-		err = ep.ExecutePlan(ctx, dryRun, wb.out)
+		err = ep.ExecutePlan(btx, dryRun, wb.out)
 	}
+	brewSpan.End(err)
 	if err != nil {
-		if aTs, ok := engine.AllTs[dSid]; ok {
+		if aTs, ok := engine.LoadTs(dSid); ok {
 			engine.UpdateDR(aTs.DR, engine.Interrupted.DSString())
 		}
 	} else {
-		if aTs, ok := engine.AllTs[dSid]; ok {
+		if aTs, ok := engine.LoadTs(dSid); ok {
 			engine.UpdateDR(aTs.DR, engine.Done.DSString())
 		}
 	}
@@ -150,8 +283,8 @@ func Template(ctx context.Context, c *gin.Context) {
 	switch what {
 	case "sample-tile":
 		tileUrl := fmt.Sprintf("https://%s.s3-%s.amazonaws.com/tiles-repo/%s/%s/%s.tgz",
-			engine.DiceConfig.BucketName,
-			engine.DiceConfig.Region,
+			workspace(c).BucketName,
+			workspace(c).Region,
 			"sample-tile",
 			"0.1.0",
 			"sample-tile")
@@ -159,8 +292,8 @@ func Template(ctx context.Context, c *gin.Context) {
 	case "tile":
 		tileType := c.Query("type")
 		tileUrl := fmt.Sprintf("https://%s.s3-%s.amazonaws.com/tiles-repo/%s/%s-tile.tgz",
-			engine.DiceConfig.BucketName,
-			engine.DiceConfig.Region,
+			workspace(c).BucketName,
+			workspace(c).Region,
 			"tile",
 			tileType)
 		c.String(http.StatusOK, tileUrl)
@@ -173,30 +306,60 @@ func Template(ctx context.Context, c *gin.Context) {
 	}
 }
 
+// Metadata returns metadata of Tiles or Hu from index of repo, the index would be built if it wasn't existed.
+// Tiles can be filtered, sorted & paged by v1alpha1.TileQuery, and total number of matched Tiles is in X-Total-Count.
 func Metadata(ctx context.Context, c *gin.Context) {
 	what := c.Param("what")
+	index, etag, err := workspace(c).LoadIndex(ctx)
+	if err == utils.ErrIndexNotFound {
+		if index, err = workspace(c).RebuildIndex(ctx); err == nil {
+			index, etag, err = workspace(c).LoadIndex(ctx)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	switch what {
 	case "tile":
-		if meta, err := utils.TilesMetadata(ctx, "cc-mahjong-0"); err != nil {
-			c.JSON(http.StatusInternalServerError, err.Error())
-		} else {
-			c.JSON(http.StatusOK, meta)
+		var query v1alpha1.TileQuery
+		if err := c.ShouldBindQuery(&query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		tiles, total := query.Search(utils.IndexedTiles(index))
+		c.Header("X-Total-Count", strconv.Itoa(total))
+		c.JSON(http.StatusOK, tiles)
 	case "hu":
-		if meta, err := utils.HusMetadata(ctx, "cc-mahjong-0"); err != nil {
-			c.JSON(http.StatusInternalServerError, err.Error())
-		} else {
-			c.JSON(http.StatusOK, meta)
-		}
+		c.JSON(http.StatusOK, utils.IndexedHus(index))
+	case "index":
+		c.JSON(http.StatusOK, index)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown metadata: " + what})
 	}
+}
 
+// RebuildIndex scans the repo and regenerates index
+func RebuildIndex(ctx context.Context, c *gin.Context) {
+	index, err := workspace(c).RebuildIndex(ctx)
+	record(newAuditEntry(c, utils.AuditRebuildIndex), err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tiles": len(index.Tiles), "hus": len(index.Hus)})
 }
 
 func TileSpec(ctx context.Context, c *gin.Context) {
 	name := c.Param("name")
 	version := c.Param("version")
 
-	buf, err := engine.DiceConfig.LoadTileSpec(name, version)
+	buf, err := workspace(c).LoadTileSpec(name, version)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
 	} else {
@@ -204,22 +367,113 @@ func TileSpec(ctx context.Context, c *gin.Context) {
 	}
 }
 
+// HuSpec returns Hu in YAML, or declared parameters along with the Hu in JSON if application/json is accepted,
+// so that values could be collected by a form before deploying
 func HuSpec(ctx context.Context, c *gin.Context) {
 	name := c.Param("name")
 
-	buf, err := engine.DiceConfig.LoadHuSpec(name)
+	buf, err := workspace(c).LoadHuSpec(name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, err.Error())
-	} else {
+		return
+	}
+	if c.NegotiateFormat(gin.MIMEYAML, gin.MIMEJSON) != gin.MIMEJSON {
 		c.String(http.StatusOK, string(buf))
+		return
+	}
+	d := v1alpha1.Data(buf)
+	hu, err := d.ParseHu(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	parameters := hu.Spec.Parameters
+	if parameters == nil {
+		parameters = []v1alpha1.DeploymentParameter{}
+	}
+	c.JSON(http.StatusOK, gin.H{"name": name, "parameters": parameters, "spec": string(buf)})
+}
+
+// PushTile validates packaged Tile and stores into repo
+func PushTile(ctx context.Context, c *gin.Context) {
+	name := c.Param("name")
+	version := c.Param("version")
+	buf, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	spec, err := utils.ReadFileFromTarGz(bytes.NewReader(buf), "tile-spec.yaml")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d := v1alpha1.Data(spec)
+	tile, err := d.ParseTile(ctx)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !strings.EqualFold(tile.Metadata.Name, name) || tile.Metadata.Version != version {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("tile-spec.yaml was < %s - %s > but pushed as < %s - %s >",
+			tile.Metadata.Name, tile.Metadata.Version, name, version)})
+		return
+	}
+
+	entry := newAuditEntry(c, utils.AuditPushTile)
+	entry.Name, entry.Digest = tile.Metadata.Name+"@"+tile.Metadata.Version, utils.Digest(buf)
+	digest, err := workspace(c).SaveTile(tile.Metadata.Name, tile.Metadata.Version, buf, spec)
+	if err == nil {
+		err = workspace(c).UpdateIndexTile(ctx, tile, digest)
+	}
+	record(entry, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"name": tile.Metadata.Name, "version": tile.Metadata.Version, "digest": digest})
+}
+
+// PushHu validates Hu and stores into repo
+func PushHu(ctx context.Context, c *gin.Context) {
+	name := c.Param("name")
+	buf, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	d := v1alpha1.Data(buf)
+	deployment, err := d.ParseHu(ctx)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry := newAuditEntry(c, utils.AuditPushHu)
+	entry.Name, entry.Digest = name, utils.Digest(buf)
+	err = workspace(c).SaveHu(name, buf)
+	if err == nil {
+		err = workspace(c).UpdateIndexHu(ctx, strings.ToLower(name), deployment, utils.Digest(buf))
+	}
+	record(entry, err)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"name": name, "digest": utils.Digest(buf)})
 }
 
-// Ts shows key content in memory as per sid
+// Ts shows key content in memory as per sid, values of secret parameters are masked
 func Ts(ctx context.Context, c *gin.Context) {
 	sid := c.Param("sid")
 	if ts := engine.TsContent(sid); ts != nil {
-		if buf, err := yaml.Marshal(ts); err != nil {
+		masked, err := v1alpha1.MaskSecrets(ts, engine.Secrets(sid))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		if buf, err := yaml.Marshal(masked); err != nil {
 			c.String(http.StatusInternalServerError, err.Error())
 		} else {
 			c.String(http.StatusOK, string(buf))
@@ -230,10 +484,16 @@ func Ts(ctx context.Context, c *gin.Context) {
 	}
 }
 
+// Plan shows execution plan as per sid, values of secret parameters are masked
 func Plan(ctx context.Context, c *gin.Context) {
 	sid := c.Param("sid")
 	if plan, ok := engine.AllPlans[sid]; ok {
-		buf, err := json.Marshal(plan)
+		masked, err := v1alpha1.MaskSecrets(plan, engine.Secrets(sid))
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		buf, err := json.Marshal(masked)
 		if err != nil {
 			c.String(http.StatusInternalServerError, err.Error())
 		} else {
@@ -264,10 +524,212 @@ func TilesGrid(ctx context.Context, c *gin.Context) {
 	}
 }
 
+// Graph returns dependency graph of all Tile instances as per sid, format: dot, mermaid or json (default)
+func Graph(ctx context.Context, c *gin.Context) {
+	sid := c.Param("sid")
+	graph := engine.GenerateGraph(sid)
+	if graph == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deployment wasn't found: " + sid})
+		return
+	}
+	switch c.DefaultQuery("format", "json") {
+	case "dot":
+		c.String(http.StatusOK, graph.ToDot())
+	case "mermaid":
+		c.String(http.StatusOK, graph.ToMermaid())
+	case "json":
+		c.JSON(http.StatusOK, graph)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format: " + c.Query("format")})
+	}
+}
+
+// Outputs returns summary outputs & outputs of Tile instances as per sid or name of deployment, format: json (default), yaml or env.
+// Only the output of key is returned in env, which is name of summary output or <tile-instance>.<output>. Values of secret parameters are masked.
+func Outputs(ctx context.Context, c *gin.Context) {
+	sid := c.Param("sid")
+	outputs := engine.GenerateOutputs(workspace(c).Workspace, sid)
+	if outputs == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "deployment wasn't found: " + sid})
+		return
+	}
+	outputs, err := outputs.Masked(engine.Secrets(outputs.SID))
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, outputs)
+	case "yaml":
+		if buf, err := yaml.Marshal(outputs); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+		} else {
+			c.String(http.StatusOK, string(buf))
+		}
+	case "env":
+		if key := c.Query("key"); key != "" {
+			if env, ok := outputs.KeyToEnv(key); ok {
+				c.String(http.StatusOK, env)
+			} else {
+				c.JSON(http.StatusNotFound, gin.H{"error": "output wasn't found: " + key})
+			}
+			return
+		}
+		c.String(http.StatusOK, outputs.ToEnv())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format: " + c.Query("format")})
+	}
+}
+
+// Diagnostic runs an allowlisted kubectl command against the deployment, which must be enabled by M_DIAGNOSTICS
+func Diagnostic(ctx context.Context, c *gin.Context) {
+	if !engine.DiceConfig.Diagnostics {
+		c.JSON(http.StatusForbidden, gin.H{"error": "diagnostics were disabled, set M_DIAGNOSTICS=true to enable"})
+		return
+	}
+	var dr engine.DiagnosticRequest
+	if err := c.ShouldBindJSON(&dr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entry := newAuditEntry(c, utils.AuditDiagnostic)
+	entry.Details = map[string]string{"deployment": c.Param("sid"), "verb": dr.Verb, "resource": dr.Resource, "name": dr.Name, "namespace": dr.Namespace}
+	result, err := engine.RunDiagnostic(ctx, workspace(c).Workspace, c.Param("sid"), &dr)
+	if result != nil {
+		entry.SID = result.SID
+		if ts, ok := engine.LoadTs(result.SID); ok {
+			entry.Name = ts.DR.Name
+		}
+		entry.Details["tileInstance"] = result.TileInstance
+		entry.Details["command"] = strings.Join(result.Command, " ")
+		if result.Error != "" {
+			record(entry, errors.New(result.Error))
+		} else {
+			record(entry, nil)
+		}
+	} else {
+		record(entry, err)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Lock returns lock of resolved Tiles as per sid, which can be saved as mahjong.lock
+func Lock(ctx context.Context, c *gin.Context) {
+	sid := c.Param("sid")
+	if lock := engine.GenerateLock(sid); lock != nil {
+		if buf, err := yaml.Marshal(lock); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+		} else {
+			c.String(http.StatusOK, string(buf))
+		}
+	} else {
+		c.String(http.StatusNotFound, "Session ID : %s is not existed and checked out with CC.", sid)
+	}
+}
+
 // AllTsD shows all recorded deployment in memory
 func AllTsD(ctx context.Context, c *gin.Context) {
-	c.JSON(http.StatusOK, engine.AllTsDeployment())
+	c.JSON(http.StatusOK, engine.AllTsDeployment(workspace(c).Workspace))
+
+}
 
+// Checkpoints list persisted state of deployments in the workspace, including ones before Dice restarted
+func Checkpoints(ctx context.Context, c *gin.Context) {
+	cps, err := engine.Checkpoints(workspace(c).Workspace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// The deployment as submitted isn't listed, it's identified by digest
+	for i := range cps {
+		cps[i].Submitted = ""
+	}
+	c.JSON(http.StatusOK, cps)
+}
+
+// Queue lists queued deployments in the workspace in order
+func Queue(ctx context.Context, c *gin.Context) {
+	c.JSON(http.StatusOK, engine.QueuedRuns(workspace(c).Workspace))
+}
+
+// CancelQueued cancels a queued deployment, running ones can't be cancelled
+func CancelQueued(ctx context.Context, c *gin.Context) {
+	sid := c.Param("sid")
+	entry := newAuditEntry(c, utils.AuditCancel)
+	entry.SID = sid
+	q, ok := engine.CancelQueued(workspace(c).Workspace, sid)
+	if !ok {
+		err := fmt.Errorf("deployment %s isn't queued in workspace %s", sid, workspace(c).Workspace)
+		record(entry, err)
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	entry.Name = q.Name
+	record(entry, nil)
+	c.JSON(http.StatusOK, q)
+}
+
+// NameLocks lists locks of deployment names in the workspace
+func NameLocks(ctx context.Context, c *gin.Context) {
+	locks, err := engine.NameLocks(workspace(c).Workspace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, locks)
+}
+
+// ForceUnlock releases a stale lock of deployment name, lock held by a running deployment isn't released
+func ForceUnlock(ctx context.Context, c *gin.Context) {
+	name := c.Param("name")
+	entry := newAuditEntry(c, utils.AuditUnlock)
+	entry.Name = name
+	lock, err := engine.ForceUnlock(workspace(c).Workspace, name)
+	if lock != nil {
+		entry.SID = lock.SID
+	}
+	record(entry, err)
+	switch {
+	case errors.Is(err, engine.ErrNotLocked):
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s in workspace %s: %s", name, workspace(c).Workspace, err)})
+	case errors.Is(err, engine.ErrLockHeld):
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("%s of %s: %s", err, name, lock.SID)})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, lock)
+	}
+}
+
+// Sweep removes runs as per retention, or reports what would be removed on dry run.
+// Retention applies to all workspaces, so that it's allowed in the default workspace only.
+func Sweep(ctx context.Context, c *gin.Context) {
+	if workspace(c).Workspace != utils.DefaultWorkspace {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sweep applies to all workspaces, which is allowed in the default workspace only"})
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+	entry := newAuditEntry(c, utils.AuditSweep)
+	entry.Details = map[string]string{"dryRun": strconv.FormatBool(dryRun)}
+	report, err := engine.Sweep(dryRun)
+	if report != nil {
+		entry.Details["removed"] = strconv.Itoa(len(report.Removed))
+		entry.Details["freed"] = strconv.FormatInt(report.Freed, 10)
+		if len(report.Errors) > 0 && err == nil {
+			err = errors.New(strings.Join(report.Errors, "; "))
+		}
+	}
+	record(entry, err)
+	if report == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
 }
 
 // Deployment validate deployment yaml
@@ -279,13 +741,25 @@ func Deployment(ctx context.Context, c *gin.Context) {
 	}
 
 	d := v1alpha1.Data(buf)
-	deployment, err := d.ParseDeployment(ctx)
+	var deployment *v1alpha1.Deployment
+	// Validate as a template if parameters wouldn't be given
+	if c.Query("template") == "true" {
+		deployment, err = d.ParseHu(ctx)
+	} else {
+		deployment, err = d.ParseDeployment(ctx)
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	b, _ := yaml.Marshal(deployment)
+	// Parsed deployment has values of secret parameters in inputs
+	masked, err := v1alpha1.MaskSecrets(deployment, deployment.Secrets())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	b, _ := yaml.Marshal(masked)
 	c.String(http.StatusOK, string(b))
 }
 
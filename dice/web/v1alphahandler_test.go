@@ -3,13 +3,40 @@ package web
 import (
 	"bytes"
 	"context"
+	"dice/engine"
+	"dice/utils"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "dice-web")
+	if err != nil {
+		panic(err)
+	}
+	sc := utils.DefaultServeConfig()
+	sc.Mode, sc.WorkHome, sc.Repo.Local, sc.Insecure = "dev", dir, filepath.Join(dir, "repo"), true
+	if err := engine.Setup(sc); err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.RemoveAll(sc.StateDir())
+	os.Exit(code)
+}
+
 func TestPing(t *testing.T) {
 
 	tests := []struct {
@@ -47,24 +74,279 @@ func TestRouter(t *testing.T) {
 		output string
 	}{
 		{"ping", "/ping", "GET", "", 200, "pong"},
-		{"websocket", "/v1alpha1/ws", "GET", "", 200, ""},
-		{"websocket+dry-run", "/v1alpha1/ws?dryRun=true", "GET", "", 200, ""},
-		{"validate tile-spec", "/v1alpha1/tile", "POST", "nothing", 200, ""},
-		{"validate tile-spec", "/v1alpha1/deployment", "POST", "nothing", 200, ""},
-		{"generate tile with template", "/v1alpha1/tile", "GET", "", 200, ""},
-		{"generate deployment with template", "/v1alpha1/deployment", "GET", "", 200, ""},
+		{"metrics", "/metrics", "GET", "", 200, "dice_running_deployments 0"},
+		{"checkpoints", "/v1alpha1/checkpoints", "GET", "", 200, "[]"},
+		{"queue", "/v1alpha1/queue", "GET", "", 200, "[]"},
+		{"locks", "/v1alpha1/locks", "GET", "", 200, "[]"},
+		{"sweep dry run", "/v1alpha1/gc?dryRun=true", "POST", "", 200, `"DryRun":true`},
+		{"websocket", "/v1alpha1/ws", "GET", "", 400, ""},
+		{"websocket+dry-run", "/v1alpha1/ws?dryRun=true", "GET", "", 400, ""},
+		{"validate tile-spec", "/v1alpha1/tile", "POST", "nothing", 400, "tile specification was invalid"},
+		{"validate tile-spec", "/v1alpha1/deployment", "POST", "nothing", 400, "deployment specification was invalid"},
+		{"generate tile with template", "/v1alpha1/tile", "GET", "", 404, ""},
+		{"generate deployment with template", "/v1alpha1/deployment", "GET", "", 404, ""},
+	}
+
+	r := Router(context.TODO())
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req, _ := http.NewRequest(test.method, test.uri, bytes.NewReader([]byte(test.input)))
+			r.ServeHTTP(recorder, req)
+			assert.Equal(t, test.code, recorder.Code)
+			if test.output != "" {
+				assert.Contains(t, recorder.Body.String(), test.output)
+			}
+		})
 	}
 
+}
+
+func TestHuSpec(t *testing.T) {
+	// Schema of deployment is relative to dice
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(".."))
+	defer os.Chdir(wd)
+	hu := `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: simple
+spec:
+  parameters:
+    - name: keyPair
+      secret: true
+    - name: suffix
+      default: ""
+  template:
+    tiles:
+      tileEks0005:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+        inputs:
+          - name: keyPair4EC2
+            inputValue: $(params.keyPair)
+  summary:
+    description: EKS
+    outputs: []
+    notes: []
+`
+	templates := filepath.Join(engine.DiceConfig.LocalRepo, "..", "templates")
+	assert.NoError(t, os.MkdirAll(templates, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(templates, "simple.yaml"), []byte(hu), 0644))
 	r := Router(context.TODO())
+
 	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1alpha1/hu/simple", nil)
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, 200, recorder.Code)
+	assert.Equal(t, hu, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1alpha1/hu/simple", nil)
+	req.Header.Set("Accept", "application/json")
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, 200, recorder.Code)
+	var spec struct {
+		Name       string
+		Parameters []map[string]interface{}
+		Spec       string
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &spec))
+	assert.Equal(t, "simple", spec.Name)
+	assert.Equal(t, []map[string]interface{}{{"name": "keyPair", "secret": true}, {"name": "suffix", "default": ""}}, spec.Parameters)
+	assert.Equal(t, hu, spec.Spec)
+
+	// Value of secret parameter is masked in parsed deployment
+	recorder = httptest.NewRecorder()
+	values := "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nparams:\n  keyPair: ore-keypair\n"
+	req, _ = http.NewRequest("POST", "/v1alpha1/deployment", bytes.NewReader([]byte(hu+values)))
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, 200, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), "ore-keypair")
+	assert.Contains(t, recorder.Body.String(), "inputValue: '******'")
+}
+
+func TestAuthorize(t *testing.T) {
+	engine.DiceConfig.Auth = &utils.AuthConfig{Tokens: []utils.StaticToken{
+		{Name: "viewer", Token: "viewer-token", Role: "viewer"},
+		{Name: "deployer", Token: "deployer-token", Role: "deployer"},
+		{Name: "admin", Token: "admin-token", Role: "admin"},
+	}}
+	engine.StoreTs(engine.Ts{DR: &engine.DeploymentRecord{SID: "auth-sid", Workspace: utils.DefaultWorkspace}})
+	defer func() {
+		engine.DiceConfig.Auth = nil
+		engine.DeleteTs("auth-sid")
+	}()
+	tests := []struct {
+		name   string
+		uri    string
+		method string
+		token  string
+		code   int
+	}{
+		{"public", "/ping", "GET", "", 200},
+		{"without token", "/v1alpha1/ts", "GET", "", 401},
+		{"invalid token", "/v1alpha1/ts", "GET", "nothing", 401},
+		{"viewer", "/v1alpha1/ts", "GET", "viewer-token", 200},
+		{"viewer can't push", "/v1alpha1/repo/index", "POST", "viewer-token", 403},
+		{"viewer can't deploy", "/v1alpha1/ws", "GET", "viewer-token", 403},
+		{"viewer can't dry run", "/v1alpha1/ws?dryRun=true", "GET", "viewer-token", 403},
+		{"deployer can dry run", "/v1alpha1/ws?dryRun=true", "GET", "deployer-token", 400},
+		{"viewer can't diagnose", "/v1alpha1/ts/auth-sid/diagnostics", "POST", "viewer-token", 403},
+		{"admin can't diagnose if disabled", "/v1alpha1/ts/auth-sid/diagnostics", "POST", "admin-token", 403},
+		{"admin can't diagnose unknown deployment", "/v1alpha1/ts/nothing/diagnostics", "POST", "admin-token", 404},
+	}
+
+	r := Router(context.TODO())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req, _ := http.NewRequest(test.method, test.uri, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			r.ServeHTTP(recorder, req)
+			// 400 means WebSocket handshake after authorization
+			assert.Equal(t, test.code, recorder.Code)
+		})
+	}
+}
 
-	for i, test := range tests {
-		req, _ := http.NewRequest(test.method, test.uri, bytes.NewReader([]byte(test.input)))
-		r.ServeHTTP(recorder, req)
+func TestRedactToken(t *testing.T) {
+	assert.Equal(t, "/v1alpha1/ws?access_token=REDACTED&dryRun=true", redactToken("/v1alpha1/ws?dryRun=true&access_token=secret-token"))
+	assert.Equal(t, "/v1alpha1/ws?dryRun=true", redactToken("/v1alpha1/ws?dryRun=true"))
+	assert.Equal(t, "/v1alpha1/ts", redactToken("/v1alpha1/ts"))
+	assert.NotContains(t, accessLog(gin.LogFormatterParams{Path: "/v1alpha1/ws?access_token=secret-token", Method: "GET"}), "secret-token")
+}
 
-		t.Run(tests[i].name, func(t *testing.T) {
+func TestWorkspace(t *testing.T) {
+	engine.DiceConfig.Auth = &utils.AuthConfig{Tokens: []utils.StaticToken{
+		{Name: "alice", Token: "alice-token", Role: "admin"},
+		{Name: "bob", Token: "bob-token", Role: "admin"},
+	}}
+	engine.Workspaces["team-a"] = &utils.DiceConfig{Workspace: "team-a", Members: map[string]utils.Role{"alice": utils.Admin}}
+	engine.StoreTs(engine.Ts{DR: &engine.DeploymentRecord{SID: "default-sid", Name: "simple", Workspace: utils.DefaultWorkspace}})
+	defer func() {
+		engine.DiceConfig.Auth = nil
+		delete(engine.Workspaces, "team-a")
+		engine.DeleteTs("default-sid")
+	}()
+	tests := []struct {
+		name      string
+		uri       string
+		workspace string
+		token     string
+		code      int
+	}{
+		{"default workspace", "/v1alpha1/ts/default-sid/outputs", "", "bob-token", 200},
+		{"by name", "/v1alpha1/ts/simple/outputs", "", "bob-token", 200},
+		{"unknown workspace", "/v1alpha1/ts", "team-b", "alice-token", 404},
+		{"member", "/v1alpha1/ts", "team-a", "alice-token", 200},
+		{"not member", "/v1alpha1/ts", "team-a", "bob-token", 403},
+		{"deployment in other workspace", "/v1alpha1/ts/default-sid/outputs", "team-a", "alice-token", 404},
+		{"name in other workspace", "/v1alpha1/ts/simple/outputs", "team-a", "alice-token", 404},
+		{"workspace query", "/v1alpha1/ts/default-sid/outputs?workspace=team-a", "", "alice-token", 404},
+	}
+
+	r := Router(context.TODO())
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", test.uri, nil)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			if test.workspace != "" {
+				req.Header.Set(WorkspaceHeader, test.workspace)
+			}
+			r.ServeHTTP(recorder, req)
 			assert.Equal(t, test.code, recorder.Code)
 		})
 	}
+}
+
+func TestAudit(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "audit")
+	audit := engine.DiceConfig.Audit
+	engine.DiceConfig.Audit = utils.NewAuditor(filepath.Join(dir, "audit.jsonl"), "")
+	defer func() { engine.DiceConfig.Audit = audit }()
+
+	r := Router(context.TODO())
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1alpha1/repo/hu/jazz", bytes.NewReader([]byte("nothing")))
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	record(&utils.AuditEntry{Action: utils.AuditDeploy, Who: "alice", Workspace: "team-a", Name: "jazz"}, nil)
+	record(&utils.AuditEntry{Action: utils.AuditDeploy, Who: "bob", Workspace: utils.DefaultWorkspace, Name: "jazz"}, errors.New("failed"))
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1alpha1/audit?name=jazz", nil)
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var entries []utils.AuditEntry
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &entries))
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "bob", entries[0].Who)
+	assert.Equal(t, utils.AuditFailure, entries[0].Result)
+	assert.Equal(t, "failed", entries[0].Error)
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/v1alpha1/audit?since=yesterday", nil)
+	r.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestWsClose(t *testing.T) {
+	// Schema of deployment is relative to dice
+	wd, _ := os.Getwd()
+	assert.NoError(t, os.Chdir(".."))
+	defer os.Chdir(wd)
+	dir, _ := ioutil.TempDir("", "audit")
+	defer os.RemoveAll(dir)
+	audit := engine.DiceConfig.Audit
+	engine.DiceConfig.Audit = utils.NewAuditor(filepath.Join(dir, "audit.jsonl"), "")
+	defer func() { engine.DiceConfig.Audit = audit }()
+
+	// The deployment is queued behind another one of the same name
+	holder := &engine.Run{SID: "holder-sid", Name: "ws-close", Workspace: utils.DefaultWorkspace}
+	hctx, err := engine.StartRun(context.Background(), holder)
+	assert.NoError(t, err)
+	assert.NoError(t, engine.Acquire(hctx, holder))
+	defer engine.FinishRun(holder, nil)
+
+	srv := httptest.NewServer(Router(context.TODO()))
+	defer srv.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1alpha1/ws", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	deployment := `apiVersion: mahjong.io/v1alpha1
+kind: Deployment
+metadata:
+  name: ws-close
+spec:
+  template:
+    tiles:
+      tileEks0005:
+        tileReference: Eks0
+        tileVersion: 0.0.5
+  summary:
+    description: EKS
+    outputs: []
+    notes: []
+`
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(deployment)))
+	assert.Eventually(t, func() bool { return len(engine.QueuedRuns(utils.DefaultWorkspace)) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")))
 
+	var entries []utils.AuditEntry
+	assert.Eventually(t, func() bool {
+		entries, err = engine.DiceConfig.Audit.Query(&utils.AuditFilter{Name: "ws-close"})
+		return err == nil && len(entries) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, engine.QueuedRuns(utils.DefaultWorkspace))
+	assert.Equal(t, utils.AuditCancel, entries[0].Action)
+	assert.Equal(t, utils.AuditSuccess, entries[0].Result)
+	assert.Equal(t, errWebSocketClosed.Error(), entries[0].Details["reason"])
+	assert.Equal(t, utils.AuditDeploy, entries[1].Action)
+	assert.Equal(t, utils.AuditFailure, entries[1].Result)
+	assert.Equal(t, entries[1].SID, entries[0].SID)
 }
@@ -0,0 +1,39 @@
+package web
+
+import (
+	"dice/engine"
+	"dice/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// WorkspaceHeader selects workspace of request, or workspace query for WebSocket of browsers
+const WorkspaceHeader = "X-Dice-Workspace"
+
+// workspaceKey is key of selected workspace in gin.Context
+const workspaceKey = "workspace"
+
+// requestedWorkspace returns name of workspace in request, the default one if it wasn't given
+func requestedWorkspace(c *gin.Context) string {
+	if w := c.GetHeader(WorkspaceHeader); w != "" {
+		return w
+	}
+	return c.DefaultQuery("workspace", utils.DefaultWorkspace)
+}
+
+// workspace returns configuration of selected workspace
+func workspace(c *gin.Context) *utils.DiceConfig {
+	if w, ok := c.Get(workspaceKey); ok {
+		return w.(*utils.DiceConfig)
+	}
+	return engine.DiceConfig
+}
+
+// scopeSID only allows deployment in the selected workspace, :sid could be d-sid or name of deployment
+func scopeSID(c *gin.Context) {
+	if _, ok := engine.ResolveSID(workspace(c).Workspace, c.Param("sid")); !ok {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "deployment wasn't found in workspace " + workspace(c).Workspace + ": " + c.Param("sid")})
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,52 @@
+package cancel
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"mctl/cmd"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var Cancel = &cobra.Command{
+	Use:   "cancel <d-sid|name>",
+	Short: "\tCancel a queued deployment.",
+	Long: "\tCancel a deployment which is waiting for a slot of concurrency in Dice, running deployments can't be cancelled. " +
+		"The first queued one is cancelled if name of deployment was given, e.g.\n" +
+		"\t\tmctl cancel eks-simple",
+	Args: cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		if err := cancelFunc(c, args); err != nil {
+			logger.Warning("%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// queued is as same as QueuedRun of Dice
+type queued struct {
+	SID      string   `json:"SID"`
+	Name     string   `json:"Name"`
+	Accounts []string `json:"Accounts"`
+	Position int      `json:"Position"`
+}
+
+func cancelFunc(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	code, buf, err := cmd.RunPostWithBody(addr, "queue/"+args[0]+"/cancel", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("%d %s : %s", code, http.StatusText(code), buf)
+	}
+	var q queued
+	if err = json.Unmarshal(buf, &q); err != nil {
+		return err
+	}
+	logger.Info("Deployment %s (%s) at position %d was cancelled, AWS accounts: %s\n", q.Name, q.SID, q.Position, strings.Join(q.Accounts, ", "))
+	return nil
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// Config is configuration of mctl, which is stored in ~/.mctl/config.yaml or MCTL_CONFIG
+type Config struct {
+	Token      string `json:"token,omitempty"`      // Token is bearer token for Dice
+	Workspace  string `json:"workspace,omitempty"`  // Workspace of Dice, the default workspace is used if it's empty
+	TLS        bool   `json:"tls,omitempty"`        // TLS connects Dice with https:// & wss://
+	CAFile     string `json:"caFile,omitempty"`     // CAFile is CA bundle to verify Dice, system's CA is used if it's empty
+	ClientCert string `json:"clientCert,omitempty"` // ClientCert is certificate for mTLS
+	ClientKey  string `json:"clientKey,omitempty"`  // ClientKey is private key of ClientCert
+}
+
+var config *Config
+
+// LoadConfig loads configuration once, MCTL_TOKEN & MCTL_WORKSPACE override ones in the file
+func LoadConfig() *Config {
+	if config != nil {
+		return config
+	}
+	config = &Config{}
+	file, ok := os.LookupEnv("MCTL_CONFIG")
+	if !ok {
+		home, _ := os.UserHomeDir()
+		file = filepath.Join(home, ".mctl", "config.yaml")
+	}
+	if buf, err := ioutil.ReadFile(file); err == nil {
+		if err := yaml.Unmarshal(buf, config); err != nil {
+			logger.Warning("failed to load %s: %s\n", file, err)
+		}
+	}
+	if token, ok := os.LookupEnv("MCTL_TOKEN"); ok {
+		config.Token = token
+	}
+	if workspace, ok := os.LookupEnv("MCTL_WORKSPACE"); ok {
+		config.Workspace = workspace
+	}
+	return config
+}
+
+// authorize adds bearer token & workspace into header of request to Dice
+func authorize(header http.Header) http.Header {
+	if token := LoadConfig().Token; token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if workspace := LoadConfig().Workspace; workspace != "" {
+		header.Set("X-Dice-Workspace", workspace)
+	}
+	return header
+}
+
+// ApplyFlags overrides configuration with root flags, which is called before running any command
+func ApplyFlags(c *cobra.Command) {
+	conf := LoadConfig()
+	if tls, _ := c.Flags().GetBool("tls"); tls {
+		conf.TLS = true
+	}
+	for flag, value := range map[string]*string{"workspace": &conf.Workspace, "ca-file": &conf.CAFile, "client-cert": &conf.ClientCert, "client-key": &conf.ClientKey} {
+		if v, _ := c.Flags().GetString(flag); v != "" {
+			*value = v
+		}
+	}
+}
+
+// endpoint returns host & scheme of Dice, addr could be with scheme, eg: https://dice.example.com:9090
+func endpoint(addr string, websocket bool) (string, string) {
+	secure := LoadConfig().TLS
+	for _, scheme := range []string{"https://", "wss://", "http://", "ws://"} {
+		if strings.HasPrefix(addr, scheme) {
+			secure = scheme == "https://" || scheme == "wss://"
+			addr = strings.TrimPrefix(addr, scheme)
+		}
+	}
+	addr = strings.TrimSuffix(addr, "/")
+	switch {
+	case websocket && secure:
+		return addr, "wss"
+	case websocket:
+		return addr, "ws"
+	case secure:
+		return addr, "https"
+	}
+	return addr, "http"
+}
+
+var tlsConfig *tls.Config
+
+// clientTLSConfig returns TLS configuration with CA bundle & client certificate
+func clientTLSConfig() (*tls.Config, error) {
+	if tlsConfig != nil {
+		return tlsConfig, nil
+	}
+	conf := LoadConfig()
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conf.CAFile != "" {
+		buf, err := ioutil.ReadFile(conf.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(buf) {
+			return nil, fmt.Errorf("no certificate in CA bundle: %s", conf.CAFile)
+		}
+	}
+	if conf.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(conf.ClientCert, conf.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	tlsConfig = c
+	return c, nil
+}
+
+// client returns HTTP client for Dice
+func client() (*http.Client, error) {
+	c, err := clientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = c
+	return &http.Client{Transport: transport}, nil
+}
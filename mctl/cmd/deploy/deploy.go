@@ -19,6 +19,11 @@ var Deploy = &cobra.Command{
 func init() {
 	Deploy.PersistentFlags().StringP("filename", "f", "", "that contains the configuration to apply")
 	Deploy.MarkPersistentFlagRequired("filename")
+	Deploy.PersistentFlags().StringP("lock", "l", "", "mahjong.lock to pin versions & digests of resolved Tiles")
+	Deploy.PersistentFlags().StringP("env", "e", "", "environment, overlays/<env>.yaml next to the file would be applied")
+	Deploy.PersistentFlags().StringArray("values", nil, "values file to override inputs, can be specified multiple times and the latter wins")
+	Deploy.PersistentFlags().StringArray("param", nil, "value of parameter: <name>=<value>, missing parameters would be prompted")
+	Deploy.PersistentFlags().StringArray("set", nil, "override an input: <tile instance>.<input name>=<value>, array as {a,b,c}, wins over values files")
 }
 
 func deployFunc(c *cobra.Command, args []string) {
@@ -31,6 +36,43 @@ func deployFunc(c *cobra.Command, args []string) {
 	if err != nil {
 		logger.Info("%s\n", err)
 	}
+	// Submit lock along with deployment as another document
+	lock, _ := c.Flags().GetString("lock")
+	if lock != "" {
+		lockBuf, err := ioutil.ReadFile(lock)
+		if err != nil {
+			logger.Warning("%s\n", err)
+			return
+		}
+		buf = append(append(buf, []byte("\n---\n")...), lockBuf...)
+	}
+	// Submit overlay of environment along with deployment
+	env, _ := c.Flags().GetString("env")
+	if env != "" {
+		overlay, err := Overlay(filename, env)
+		if err != nil {
+			logger.Warning("%s\n", err)
+			return
+		}
+		buf = append(buf, overlay...)
+	}
+	// Submit values along with deployment as other documents
+	valuesFiles, _ := c.Flags().GetStringArray("values")
+	sets, _ := c.Flags().GetStringArray("set")
+	values, err := Values(valuesFiles, sets)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	buf = append(buf, values...)
+	// Parameters are the last, so that --param wins over values files, only missing ones are prompted
+	paramFlags, _ := c.Flags().GetStringArray("param")
+	params, err := Params(buf, paramFlags)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	buf = append(buf, params...)
 	cmd.Run(addr, dryRun, parallel, buf)
 
 }
@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+)
+
+// OverlayFile returns overlay of environment, which is overlays/<env>.yaml next to the Hu file
+func OverlayFile(filename string, env string) string {
+	return filepath.Join(filepath.Dir(filename), "overlays", env+".yaml")
+}
+
+// Overlay returns Overlay document of environment, which is appended to deployment.
+// An overlay file could be an Overlay document, or without apiVersion, kind & metadata
+func Overlay(filename string, env string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(OverlayFile(filename, env))
+	if err != nil {
+		return nil, err
+	}
+	var k struct {
+		Kind string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(buf, &k); err != nil {
+		return nil, fmt.Errorf("overlay of %s was invalid : %s", env, err)
+	}
+	if k.Kind == "Overlay" {
+		return append([]byte("\n---\n"), buf...), nil
+	}
+	doc := fmt.Sprintf("\n---\napiVersion: mahjong.io/v1alpha1\nkind: Overlay\nmetadata:\n  name: %s\n", env)
+	return append([]byte(doc), buf...), nil
+}
@@ -0,0 +1,110 @@
+package deploy
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/ssh/terminal"
+	"mctl/cmd"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// parameter is deployment.spec.parameters
+type parameter struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Secret      bool        `json:"secret,omitempty"`
+}
+
+// Params returns a Values document with parameters, buf is Hu along with other documents, such as values.
+// Parameters neither given nor in values would be prompted if it's interactive.
+func Params(buf []byte, params []string) ([]byte, error) {
+	given := make(map[string]string)
+	supplied := make(map[string]bool)
+	var names []string
+	for _, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.New("param should be <name>=<value> : " + p)
+		}
+		if _, ok := given[kv[0]]; !ok {
+			names = append(names, kv[0])
+		}
+		given[kv[0]] = kv[1]
+	}
+
+	// Nothing is prompted without Deployment, such as deploying a Tile
+	var d struct {
+		Spec struct {
+			Parameters []parameter `json:"parameters"`
+		} `json:"spec"`
+	}
+	if hu, ok := cmd.Document(buf, "Deployment"); ok {
+		if err := yaml.Unmarshal(hu, &d); err != nil {
+			return nil, err
+		}
+	}
+	// Parameters in values files are given as well, and they're not overridden by prompted ones
+	for _, doc := range cmd.Documents(buf) {
+		if cmd.Kind(doc) != "Values" {
+			continue
+		}
+		var v struct {
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := yaml.Unmarshal(doc, &v); err != nil {
+			return nil, err
+		}
+		for name := range v.Params {
+			supplied[name] = true
+		}
+	}
+	interactive := terminal.IsTerminal(int(os.Stdin.Fd()))
+	reader := bufio.NewReader(os.Stdin)
+	for _, p := range d.Spec.Parameters {
+		if _, ok := given[p.Name]; ok || supplied[p.Name] || p.Default != nil || !interactive {
+			continue
+		}
+		value, err := prompt(reader, p)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, p.Name)
+		given[p.Name] = value
+	}
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+	doc := "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nmetadata:\n  name: params\nparams:\n"
+	for _, name := range names {
+		k, _ := json.Marshal(name)
+		v, _ := json.Marshal(given[name])
+		doc = doc + "  " + string(k) + ": " + string(v) + "\n"
+	}
+	return []byte(doc), nil
+}
+
+func prompt(reader *bufio.Reader, p parameter) (string, error) {
+	t := p.Type
+	if t == "" {
+		t = "string"
+	}
+	if p.Description != "" {
+		fmt.Printf("%s (%s) - %s: ", p.Name, t, p.Description)
+	} else {
+		fmt.Printf("%s (%s): ", p.Name, t)
+	}
+	if p.Secret {
+		buf, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		return string(buf), err
+	}
+	value, err := reader.ReadString('\n')
+	return strings.TrimRight(value, "\r\n"), err
+}
@@ -0,0 +1,48 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// Values returns Values documents from values files & sets, which are appended to deployment.
+// A values file could be a Values document, or only values as <tile instance> -> <input name> -> <value>
+func Values(files []string, sets []string) ([]byte, error) {
+	var docs []byte
+	for _, f := range files {
+		buf, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var k struct {
+			Kind string `json:"kind"`
+		}
+		if err := yaml.Unmarshal(buf, &k); err != nil {
+			return nil, fmt.Errorf("values file %s was invalid : %s", f, err)
+		}
+		if k.Kind == "Values" {
+			docs = append(docs, []byte("\n---\n")...)
+			docs = append(docs, buf...)
+			continue
+		}
+		// Wrap as a Values document, keep values as they are
+		doc := fmt.Sprintf("\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nmetadata:\n  name: %s\nvalues:\n", filepath.Base(f))
+		for _, line := range strings.Split(strings.TrimRight(string(buf), "\n"), "\n") {
+			doc = doc + "  " + line + "\n"
+		}
+		docs = append(docs, []byte(doc)...)
+	}
+	if len(sets) > 0 {
+		doc := "\n---\napiVersion: mahjong.io/v1alpha1\nkind: Values\nmetadata:\n  name: set\nset:\n"
+		for _, set := range sets {
+			s, _ := json.Marshal(set)
+			doc = doc + "  - " + string(s) + "\n"
+		}
+		docs = append(docs, []byte(doc)...)
+	}
+	return docs, nil
+}
@@ -0,0 +1,303 @@
+package describe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"mctl/cmd"
+	"mctl/cmd/list"
+	"net/url"
+	"os"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var TileDescribe = &cobra.Command{
+	Use:   "tile <name> [version]",
+	Short: "\tDescribe a Tile in the Repo.",
+	Long:  "\tDescribe inputs, dependencies, outputs, manifests and notes of a Tile, the latest version would be used if version wasn't given.",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(c *cobra.Command, args []string) {
+		if err := describeTile(c, args); err != nil {
+			logger.Warning("%s\n", err)
+		}
+	},
+}
+
+var HuDescribe = &cobra.Command{
+	Use:   "hu [name]",
+	Short: "\tDescribe a Hu in the Repo.",
+	Long:  "\tDescribe Tile instances, effective inputs after defaults and summary outputs of a Hu, either in the Repo or a local file with -f.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		if err := describeHu(c, args); err != nil {
+			logger.Warning("%s\n", err)
+		}
+	},
+}
+
+var Describe = &cobra.Command{
+	Use:   "describe",
+	Short: "\tDescribe Tile or Hu in the Repo.",
+	Long:  "\tDescribe Tile or Hu in the Repo.",
+}
+
+func init() {
+	Describe.PersistentFlags().StringP("output", "o", "", "output format: yaml, json ")
+	HuDescribe.Flags().StringP("filename", "f", "", "describe Hu from a local file")
+	Describe.AddCommand(TileDescribe, HuDescribe)
+}
+
+func describeTile(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	output, _ := c.Flags().GetString("output")
+	name := args[0]
+	version := ""
+	if len(args) == 2 {
+		version = args[1]
+	} else {
+		latest, err := latestVersion(addr, name)
+		if err != nil {
+			return err
+		}
+		version = latest
+	}
+
+	buf, t, err := loadTile(addr, name, version)
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		return print(buf, output)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "Name:\t%s\n", t.Metadata.Name)
+	fmt.Fprintf(w, "Version:\t%s\n", t.Metadata.Version)
+	fmt.Fprintf(w, "Category:\t%s\n", t.Metadata.Category)
+	if t.Metadata.VendorService != "" {
+		fmt.Fprintf(w, "Vendor Service:\t%s\n", t.Metadata.VendorService)
+	}
+	if t.Metadata.DependentOnVendorService != "" {
+		fmt.Fprintf(w, "Dependent On:\t%s\n", t.Metadata.DependentOnVendorService)
+	}
+	if t.Metadata.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", t.Metadata.Description)
+	}
+
+	fmt.Fprintf(w, "\nDependencies:\n")
+	fmt.Fprintf(w, "  NAME\tTILE\tVERSION\n")
+	for _, d := range t.Spec.Dependencies {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", d.Name, d.TileReference, d.TileVersion)
+	}
+
+	fmt.Fprintf(w, "\nInputs:\n")
+	fmt.Fprintf(w, "  NAME\tTYPE\tREQUIRED\tDEFAULT\tDESCRIPTION\tFROM\n")
+	for _, input := range t.Spec.Inputs {
+		fmt.Fprintf(w, "  %s\t%s\t%t\t%s\t%s\t%s\n", input.Name, input.InputType, input.Require,
+			defaultValue(input), input.Description, from(input))
+	}
+
+	fmt.Fprintf(w, "\nOutputs:\n")
+	fmt.Fprintf(w, "  NAME\tTYPE\tDESCRIPTION\n")
+	for _, o := range t.Spec.Outputs {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", o.Name, o.OutputType, o.Description)
+	}
+
+	m := t.Spec.Manifests
+	if m.ManifestType != "" {
+		fmt.Fprintf(w, "\nManifests:\n")
+		fmt.Fprintf(w, "  Type:\t%s\n", m.ManifestType)
+		fmt.Fprintf(w, "  Namespace:\t%s\n", m.Namespace)
+		for _, f := range append(m.Files, m.Folders...) {
+			fmt.Fprintf(w, "  -\t%s\n", f)
+		}
+		if len(m.Flags) > 0 {
+			fmt.Fprintf(w, "  Flags:\t%s\n", strings.Join(m.Flags, " "))
+		}
+	}
+
+	if len(t.Spec.Notes) > 0 {
+		fmt.Fprintf(w, "\nNotes:\n")
+		for _, n := range t.Spec.Notes {
+			fmt.Fprintf(w, "  %s\n", n)
+		}
+	}
+	return nil
+}
+
+func describeHu(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	output, _ := c.Flags().GetString("output")
+	filename, _ := c.Flags().GetString("filename")
+
+	var buf []byte
+	var err error
+	if filename != "" {
+		buf, err = ioutil.ReadFile(filename)
+	} else if len(args) == 1 {
+		buf, err = cmd.RunGetByVersion(addr, "hu/"+url.PathEscape(args[0]))
+	} else {
+		return errors.New("either name or Hu file (-f) is required")
+	}
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		return print(buf, output)
+	}
+	// Hu could be stored along with other documents, such as Values
+	hu, ok := cmd.Document(buf, "Deployment")
+	if !ok {
+		return errors.New("no Deployment was found in Hu")
+	}
+	var d deployment
+	if err = yaml.Unmarshal(hu, &d); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintf(w, "Name:\t%s\n", d.Metadata.Name)
+	if d.Metadata.Version != "" {
+		fmt.Fprintf(w, "Version:\t%s\n", d.Metadata.Version)
+	}
+	if d.Spec.Summary.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", d.Spec.Summary.Description)
+	}
+
+	var instances []string
+	for ti := range d.Spec.Template.Tiles {
+		instances = append(instances, ti)
+	}
+	sort.Strings(instances)
+	for _, ti := range instances {
+		dt := d.Spec.Template.Tiles[ti]
+		fmt.Fprintf(w, "\nTile Instance: %s < %s - %s >\n", ti, dt.TileReference, dt.TileVersion)
+		if len(dt.DependsOn) > 0 {
+			fmt.Fprintf(w, "  Depends On:\t%s\n", strings.Join(dt.DependsOn, ", "))
+		}
+		if dt.Region != "" || dt.Profile != "" {
+			fmt.Fprintf(w, "  Region/Profile:\t%s / %s\n", dt.Region, dt.Profile)
+		}
+
+		given := make(map[string]tileInput)
+		for _, input := range dt.Inputs {
+			given[input.Name] = input
+		}
+		fmt.Fprintf(w, "  NAME\tVALUE\tSOURCE\n")
+		_, t, err := loadTile(addr, dt.TileReference, dt.TileVersion)
+		if err != nil {
+			// Show inputs as is if Tile wasn't available
+			logger.Warning("failed to load Tile < %s - %s > : %s\n", dt.TileReference, dt.TileVersion, err)
+			for _, input := range dt.Inputs {
+				fmt.Fprintf(w, "  %s\t%s\t%s\n", input.Name, value(input.InputValue, input.InputValues), "hu")
+			}
+			continue
+		}
+		for _, input := range t.Spec.Inputs {
+			if g, ok := given[input.Name]; ok {
+				fmt.Fprintf(w, "  %s\t%s\t%s\n", input.Name, value(g.InputValue, g.InputValues), "hu")
+				delete(given, input.Name)
+			} else if dv := defaultValue(input); dv != "" {
+				fmt.Fprintf(w, "  %s\t%s\t%s\n", input.Name, dv, "default")
+			} else if f := from(input); f != "" {
+				fmt.Fprintf(w, "  %s\t\t%s\n", input.Name, f)
+			} else if input.Require {
+				fmt.Fprintf(w, "  %s\t\t%s\n", input.Name, "missing, required")
+			}
+		}
+		for _, input := range dt.Inputs {
+			if _, ok := given[input.Name]; ok {
+				fmt.Fprintf(w, "  %s\t%s\t%s\n", input.Name, value(input.InputValue, input.InputValues), "unknown to Tile")
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "\nSummary Outputs:\n")
+	for _, o := range d.Spec.Summary.Outputs {
+		fmt.Fprintf(w, "  %s\t%s\n", o.Name, o.Value)
+	}
+	if len(d.Spec.Summary.Notes) > 0 {
+		fmt.Fprintf(w, "\nNotes:\n")
+		for _, n := range d.Spec.Summary.Notes {
+			fmt.Fprintf(w, "  %s\n", n)
+		}
+	}
+	return nil
+}
+
+// latestVersion returns the latest version of Tile from the Repo
+func latestVersion(addr string, name string) (string, error) {
+	buf, err := cmd.RunGetByVersion(addr, "repo/tile?latest=true&q="+url.QueryEscape(name))
+	if err != nil {
+		return "", err
+	}
+	var tms []list.TileMetadata
+	if err = json.Unmarshal(buf, &tms); err != nil {
+		return "", err
+	}
+	for _, tm := range tms {
+		if strings.EqualFold(tm.Name, name) {
+			return tm.Version, nil
+		}
+	}
+	return "", errors.New("Tile wasn't found in the Repo: " + name)
+}
+
+func loadTile(addr string, name string, version string) ([]byte, *tile, error) {
+	buf, err := cmd.RunGetByVersion(addr, "tile/"+url.PathEscape(name)+"/"+url.PathEscape(version))
+	if err != nil {
+		return nil, nil, err
+	}
+	var t tile
+	if err = yaml.Unmarshal(buf, &t); err != nil {
+		return nil, nil, err
+	}
+	return buf, &t, nil
+}
+
+func defaultValue(input tileInput) string {
+	return value(input.DefaultValue, input.DefaultValues)
+}
+
+func value(v string, vs []string) string {
+	if len(vs) > 0 {
+		return "[" + strings.Join(vs, ", ") + "]"
+	}
+	return v
+}
+
+// from tells where the value comes from other than the Hu
+func from(input tileInput) string {
+	var from []string
+	for _, d := range input.Dependencies {
+		from = append(from, "dependency "+d.Name+"."+d.Field)
+	}
+	if input.Override.Name != "" {
+		from = append(from, "overrides "+input.Override.Name+"."+input.Override.Field)
+	}
+	return strings.Join(from, ", ")
+}
+
+func print(buf []byte, output string) error {
+	switch output {
+	case "yaml":
+		fmt.Printf("%s\n", string(buf))
+	case "json":
+		js, err := yaml.YAMLToJSON(buf)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", string(js))
+	default:
+		return errors.New("unsupported format: " + output)
+	}
+	return nil
+}
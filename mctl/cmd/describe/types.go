@@ -0,0 +1,86 @@
+package describe
+
+// tile is the part of Tile specification for describing
+type tile struct {
+	Metadata struct {
+		Name                     string `json:"name"`
+		Category                 string `json:"category"`
+		VendorService            string `json:"vendorService,omitempty"`
+		DependentOnVendorService string `json:"dependentOnVendorService,omitempty"`
+		Version                  string `json:"version"`
+		Description              string `json:"description"`
+		Author                   string `json:"author,omitempty"`
+		License                  string `json:"license,omitempty"`
+	} `json:"metadata"`
+	Spec struct {
+		Dependencies []struct {
+			Name          string `json:"name"`
+			TileReference string `json:"tileReference"`
+			TileVersion   string `json:"tileVersion"`
+		} `json:"dependencies,omitempty"`
+		Inputs    []tileInput `json:"inputs"`
+		Manifests struct {
+			ManifestType string   `json:"manifestType"`
+			Namespace    string   `json:"namespace"`
+			Files        []string `json:"files,omitempty"`
+			Folders      []string `json:"folders,omitempty"`
+			Flags        []string `json:"flags,omitempty"`
+		} `json:"manifests,omitempty"`
+		Outputs []struct {
+			Name                string `json:"name"`
+			OutputType          string `json:"outputType"`
+			DefaultValue        string `json:"defaultValue"`
+			DefaultValueCommand string `json:"defaultValueCommand"`
+			Description         string `json:"description"`
+		} `json:"outputs"`
+		Notes []string `json:"notes,omitempty"`
+	} `json:"spec"`
+}
+
+type tileInput struct {
+	Name         string `json:"name"`
+	InputType    string `json:"inputType"`
+	Description  string `json:"description"`
+	Dependencies []struct {
+		Name  string `json:"name"`
+		Field string `json:"field"`
+	} `json:"dependencies,omitempty"`
+	DefaultValue  string   `json:"defaultValue"`
+	DefaultValues []string `json:"defaultValues,omitempty"`
+	InputValue    string   `json:"inputValue"`
+	InputValues   []string `json:"inputValues,omitempty"`
+	Require       bool     `json:"require"`
+	Override      struct {
+		Name  string `json:"name"`
+		Field string `json:"field"`
+	} `json:"override"`
+}
+
+// deployment is the part of Hu specification for describing
+type deployment struct {
+	Metadata struct {
+		Name        string `json:"name"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"metadata"`
+	Spec struct {
+		Template struct {
+			Tiles map[string]struct {
+				TileReference string      `json:"tileReference"`
+				TileVersion   string      `json:"tileVersion"`
+				DependsOn     []string    `json:"dependsOn,omitempty"`
+				Inputs        []tileInput `json:"inputs"`
+				Region        string      `json:"region,omitempty"`
+				Profile       string      `json:"profile,omitempty"`
+			} `json:"tiles"`
+		} `json:"template"`
+		Summary struct {
+			Description string `json:"description"`
+			Outputs     []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"outputs"`
+			Notes []string `json:"notes"`
+		} `json:"summary"`
+	} `json:"spec"`
+}
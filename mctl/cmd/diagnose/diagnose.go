@@ -0,0 +1,89 @@
+package diagnose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"mctl/cmd"
+	"net/http"
+	"os"
+)
+
+var Diagnose = &cobra.Command{
+	Use:   "diagnose <d-sid|name> <get|describe> <resource> [name]",
+	Short: "\tRun read only kubectl commands against a deployment.",
+	Long: "\tRun kubectl get or describe with kube.config of a deployment in Dice, which requires admin role and " +
+		"M_DIAGNOSTICS=true in Dice. Secrets are not allowed, e.g.\n" +
+		"\t\tmctl diagnose eks-simple get pods -n kube-system\n" +
+		"\t\tmctl diagnose eks-simple describe deployment nginx -n default -t tileEks0005",
+	Args: cobra.RangeArgs(3, 4),
+	Run: func(c *cobra.Command, args []string) {
+		if err := diagnoseFunc(c, args); err != nil {
+			logger.Warning("%s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	Diagnose.Flags().StringP("namespace", "n", "", "namespace of resources")
+	Diagnose.Flags().StringP("selector", "l", "", "label selector of resources")
+	Diagnose.Flags().StringP("output", "o", "", "output format of get: wide, yaml, json, name")
+	Diagnose.Flags().StringP("tile-instance", "t", "", "Tile instance providing kube.config, the first one by default")
+}
+
+// request is as same as DiagnosticRequest of Dice
+type request struct {
+	TileInstance string `json:"tileInstance,omitempty"`
+	Verb         string `json:"verb"`
+	Resource     string `json:"resource"`
+	Name         string `json:"name,omitempty"`
+	Namespace    string `json:"namespace,omitempty"`
+	Selector     string `json:"selector,omitempty"`
+	Output       string `json:"output,omitempty"`
+}
+
+// result is as same as DiagnosticResult of Dice
+type result struct {
+	TileInstance string `json:"tileInstance"`
+	Output       string `json:"output"`
+	Truncated    bool   `json:"truncated,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func diagnoseFunc(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	r := request{Verb: args[1], Resource: args[2]}
+	if len(args) == 4 {
+		r.Name = args[3]
+	}
+	r.Namespace, _ = c.Flags().GetString("namespace")
+	r.Selector, _ = c.Flags().GetString("selector")
+	r.Output, _ = c.Flags().GetString("output")
+	r.TileInstance, _ = c.Flags().GetString("tile-instance")
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	code, buf, err := cmd.RunPostWithBody(addr, "ts/"+args[0]+"/diagnostics", "application/json", body)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("%d %s : %s", code, http.StatusText(code), buf)
+	}
+	var res result
+	if err = json.Unmarshal(buf, &res); err != nil {
+		return err
+	}
+	fmt.Print(res.Output)
+	if res.Truncated {
+		logger.Warning("Output of %s was truncated\n", res.TileInstance)
+	}
+	if res.Error != "" {
+		return errors.New(res.Error)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"mctl/cmd"
+	"mctl/cmd/deploy"
+	"net/http"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+var Diff = &cobra.Command{
+	Use:   "diff",
+	Short: "\tShow differences of effective deployment for each environment.",
+	Long:  "\tShow differences between the base Hu and effective deployment with overlays/<env>.yaml, all environments would be shown if --env wasn't given.",
+	Run: func(c *cobra.Command, args []string) {
+		if err := diffFunc(c, args); err != nil {
+			logger.Warning("%s\n", err)
+		}
+	},
+}
+
+func init() {
+	Diff.Flags().StringP("filename", "f", "", "the base Hu")
+	Diff.MarkFlagRequired("filename")
+	Diff.Flags().StringArrayP("env", "e", nil, "environment, can be specified multiple times")
+}
+
+func diffFunc(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	filename, _ := c.Flags().GetString("filename")
+	envs, _ := c.Flags().GetStringArray("env")
+	if len(envs) == 0 {
+		files, err := filepath.Glob(deploy.OverlayFile(filename, "*"))
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			envs = append(envs, strings.TrimSuffix(filepath.Base(f), ".yaml"))
+		}
+		sort.Strings(envs)
+	}
+	if len(envs) == 0 {
+		return errors.New("there's no overlay in " + filepath.Dir(deploy.OverlayFile(filename, "")))
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	base, err := effective(addr, buf)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		overlay, err := deploy.Overlay(filename, env)
+		if err != nil {
+			return err
+		}
+		patched, err := effective(addr, append(append([]byte{}, buf...), overlay...))
+		if err != nil {
+			return fmt.Errorf("%s : %s", env, err)
+		}
+		fmt.Printf("--- %s\n+++ %s (%s)\n", filename, filename, env)
+		for _, line := range Hunks(Lines(base, patched), 3) {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// effective returns effective deployment from Dice, parameters are kept as they are
+func effective(addr string, buf []byte) ([]string, error) {
+	code, resp, err := cmd.RunPostWithBody(addr, "deployment?template=true", "text/yaml", buf)
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, errors.New(string(resp))
+	}
+	var d map[string]interface{}
+	if err := yaml.Unmarshal(resp, &d); err != nil {
+		return nil, err
+	}
+	delete(d, "appliedOverlays")
+	if resp, err = yaml.Marshal(prune(d)); err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(string(resp), "\n"), "\n"), nil
+}
+
+// prune removes empty values, which are filled by Dice
+func prune(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if val = prune(val); val == nil {
+				delete(t, k)
+			} else {
+				t[k] = val
+			}
+		}
+		if len(t) == 0 {
+			return nil
+		}
+	case []interface{}:
+		var l []interface{}
+		for _, val := range t {
+			if val = prune(val); val != nil {
+				l = append(l, val)
+			}
+		}
+		if len(l) == 0 {
+			return nil
+		}
+		return l
+	case string:
+		if t == "" {
+			return nil
+		}
+	case bool:
+		if !t {
+			return nil
+		}
+	}
+	return v
+}
+
+// Lines returns differences between lines as ' ', '-' & '+' prefixed lines
+func Lines(a []string, b []string) []string {
+	// Longest common subsequence
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "- "+a[i])
+			i++
+		default:
+			lines = append(lines, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+ "+b[j])
+	}
+	return lines
+}
+
+// Hunks keeps changed lines with lines of context around, and separates hunks by '@@'
+func Hunks(lines []string, context int) []string {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "  ") {
+			for j := i - context; j <= i+context; j++ {
+				if j >= 0 && j < len(lines) {
+					keep[j] = true
+				}
+			}
+		}
+	}
+	var hunks []string
+	for i, line := range lines {
+		if !keep[i] {
+			continue
+		}
+		if i == 0 || !keep[i-1] {
+			hunks = append(hunks, "@@")
+		}
+		hunks = append(hunks, line)
+	}
+	return hunks
+}
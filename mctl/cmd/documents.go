@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// Documents splits YAML into documents by lines of '---', as same as Dice
+func Documents(buf []byte) [][]byte {
+	var docs [][]byte
+	var doc []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			if len(doc) > 0 {
+				docs = append(docs, []byte(strings.Join(doc, "\n")))
+			}
+			doc = nil
+			continue
+		}
+		doc = append(doc, line)
+	}
+	if len(doc) > 0 {
+		docs = append(docs, []byte(strings.Join(doc, "\n")))
+	}
+	return docs
+}
+
+// Kind returns kind of the document, such as Deployment, Values or Overlay
+func Kind(doc []byte) string {
+	var k struct {
+		Kind string `json:"kind"`
+	}
+	if err := yaml.Unmarshal(doc, &k); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(k.Kind)
+}
+
+// Document returns the first document as per kind
+func Document(buf []byte, kind string) ([]byte, bool) {
+	for _, doc := range Documents(buf) {
+		if Kind(doc) == kind {
+			return doc, true
+		}
+	}
+	return nil, false
+}
@@ -15,13 +15,33 @@ import (
 var apiVersion = "v1alpha1"
 
 func RunGet(addr string, uri string) ([]byte, error) {
+	buf, _, err := RunGetWithHeader(addr, uri)
+	return buf, err
+}
+
+// RunGetWithHeader retrieves content & response headers from Dice
+func RunGetWithHeader(addr string, uri string) ([]byte, http.Header, error) {
 
-	resp, err := http.Get(fmt.Sprintf("http://%s/%s", addr, uri))
+	host, scheme := endpoint(addr, false)
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s://%s/%s", scheme, host, uri), nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	authorize(req.Header)
+	hc, err := client()
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err == nil && resp.StatusCode >= http.StatusBadRequest {
+		err = fmt.Errorf("%s : %s", resp.Status, buf)
+	}
+	return buf, resp.Header, err
 }
 
 func RunGetByVersion(addr string, uri string) ([]byte, error) {
@@ -31,18 +51,44 @@ func RunGetByVersion(addr string, uri string) ([]byte, error) {
 }
 
 func RunPostByVersion(addr string, uri string, body []byte) (int, error) {
+	code, resp, err := RunPostWithBody(addr, uri, "text/yaml", body)
+	if err != nil {
+		logger.Warning("%s\n", err)
+	} else {
+		logger.Warning("%s\n", resp)
+	}
+	return code, err
+}
+
+// RunPostWithBody posts content to Dice and return status code & response, uri could include query
+func RunPostWithBody(addr string, uri string, contentType string, body []byte) (int, []byte, error) {
+	host, scheme := endpoint(addr, false)
 	u := &url.URL{
-		Scheme: "http",
-		Host:   addr,
+		Scheme: scheme,
+		Host:   host,
 		Path:   fmt.Sprintf("/%s/%s", apiVersion, uri),
 	}
-	resp, err := http.Post(u.String(), "text/yaml", bytes.NewReader(body))
+	if i := strings.Index(uri, "?"); i >= 0 {
+		u.Path = fmt.Sprintf("/%s/%s", apiVersion, uri[:i])
+		u.RawQuery = uri[i+1:]
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
 	if err != nil {
-		logger.Warning("%s\n", err)
-	} else {
-		logger.Warning("%s\n", resp.Body)
+		return 0, nil, err
 	}
-	return resp.StatusCode, err
+	req.Header.Set("Content-Type", contentType)
+	authorize(req.Header)
+	hc, err := client()
+	if err != nil {
+		return 0, nil, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	buf, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, buf, err
 }
 
 func Run(addr string, dryRun bool, parallel bool, cmd []byte) error {
@@ -55,9 +101,10 @@ func Run(addr string, dryRun bool, parallel bool, cmd []byte) error {
 }
 
 func Connect2Dice(addr string, dryRun bool, parallel bool) (*websocket.Conn, error) {
+	host, scheme := endpoint(addr, true)
 	u := &url.URL{
-		Scheme: "ws",
-		Host:   addr,
+		Scheme: scheme,
+		Host:   host,
 		Path:   fmt.Sprintf("/%s/%s", apiVersion, "ws"),
 	}
 	if dryRun {
@@ -73,8 +120,19 @@ func Connect2Dice(addr string, dryRun bool, parallel bool) (*websocket.Conn, err
 		}
 	}
 	logger.Info("Connecting to %s\n", u.String())
-	c, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	tc, err := clientTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tc
+	c, resp, err := dialer.Dial(u.String(), authorize(http.Header{}))
 	if err != nil {
+		// Show the reason if Dice refused, eg: unauthorized
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			buf, _ := ioutil.ReadAll(resp.Body)
+			err = fmt.Errorf("%s : %s", resp.Status, buf)
+		}
 		return c, err
 	}
 	return c, nil
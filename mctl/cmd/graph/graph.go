@@ -0,0 +1,286 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"mctl/cmd"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+)
+
+var Graph = &cobra.Command{
+	Use:   "graph [d-sid]",
+	Short: "\tExport dependency graph of Tiles.",
+	Long: "\tExport dependency graph of Tiles in DOT, Mermaid or JSON, either from a deployment in Dice, " +
+		"or offline from a Hu file with -f. The offline graph is rendered from the Hu as it is, so it doesn't include generated dependent Tiles, " +
+		"expand Tile instances with forEach, or drop Tile instances by when, as the graph of a deployment does.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		if err := graphFunc(c, args); err != nil {
+			logger.Warning("%s\n", err)
+		}
+	},
+}
+
+func init() {
+	Graph.Flags().StringP("filename", "f", "", "Hu file to generate graph offline")
+	Graph.Flags().String("format", "mermaid", "format of graph: dot, mermaid, json")
+}
+
+// deployment is the part of Hu specification for graph
+type deployment struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Template struct {
+			Tiles map[string]struct {
+				TileReference string   `json:"tileReference"`
+				TileVersion   string   `json:"tileVersion"`
+				DependsOn     []string `json:"dependsOn"`
+				Inputs        []struct {
+					InputValue  string   `json:"inputValue"`
+					InputValues []string `json:"inputValues"`
+				} `json:"inputs"`
+			} `json:"tiles"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// graph is as same as the one from Dice
+type graph struct {
+	Name  string `json:"name"`
+	Nodes []node `json:"nodes"`
+	Edges []edge `json:"edges"`
+}
+
+type node struct {
+	Id               string `json:"id"`
+	TileName         string `json:"tileName"`
+	TileVersion      string `json:"tileVersion"`
+	TileCategory     string `json:"tileCategory,omitempty"`
+	RootTileInstance string `json:"rootTileInstance,omitempty"`
+	Status           string `json:"status,omitempty"`
+	Generated        bool   `json:"generated,omitempty"`
+}
+
+type edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+var refRe = regexp.MustCompile(`\$(?:cdk)?\(([[:alnum:]_-]+)\.[[:alnum:]]+\.[[:alnum:]]+\)`)
+
+func graphFunc(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	filename, _ := c.Flags().GetString("filename")
+	format, _ := c.Flags().GetString("format")
+
+	var g *graph
+	var err error
+	if filename != "" {
+		g, err = fromHu(filename)
+	} else if len(args) == 1 {
+		if format == "dot" || format == "mermaid" {
+			buf, err := cmd.RunGetByVersion(addr, "ts/"+args[0]+"/graph?format="+format)
+			if err == nil {
+				fmt.Print(string(buf))
+			}
+			return err
+		}
+		var buf []byte
+		if buf, err = cmd.RunGetByVersion(addr, "ts/"+args[0]+"/graph"); err == nil {
+			g = &graph{}
+			err = json.Unmarshal(buf, g)
+		}
+	} else {
+		return errors.New("either d-sid or Hu file (-f) is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "dot":
+		fmt.Print(g.toDot())
+	case "mermaid":
+		fmt.Print(g.toMermaid())
+	case "json":
+		buf, err := json.MarshalIndent(g, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", string(buf))
+	default:
+		return errors.New("unsupported format: " + format)
+	}
+	return nil
+}
+
+// fromHu generates graph with dependsOn and references, such as $(tile.outputs.field) & $cdk(tile.Tile.field)
+func fromHu(filename string) (*graph, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// Only Deployment is needed from multiple documents, such as Values & Overlay, which could be in any order
+	doc, ok := cmd.Document(buf, "Deployment")
+	if !ok {
+		return nil, errors.New("no Deployment was found in " + filename)
+	}
+	var d deployment
+	if err = yaml.Unmarshal(doc, &d); err != nil {
+		return nil, err
+	}
+
+	g := &graph{Name: d.Metadata.Name}
+	tiles := d.Spec.Template.Tiles
+	var instances []string
+	for ti := range tiles {
+		instances = append(instances, ti)
+	}
+	sort.Strings(instances)
+
+	dependencies := make(map[string][]string)
+	for _, ti := range instances {
+		deps := make(map[string]bool)
+		for _, dp := range tiles[ti].DependsOn {
+			deps[dp] = true
+		}
+		for _, input := range tiles[ti].Inputs {
+			for _, v := range append(input.InputValues, input.InputValue) {
+				for _, m := range refRe.FindAllStringSubmatch(v, -1) {
+					if _, ok := tiles[m[1]]; ok && m[1] != ti {
+						deps[m[1]] = true
+					}
+				}
+			}
+		}
+		for dp := range deps {
+			dependencies[ti] = append(dependencies[ti], dp)
+			g.Edges = append(g.Edges, edge{From: dp, To: ti})
+		}
+		sort.Strings(dependencies[ti])
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+
+	// Root Tile instance is as same as Dice, which follows the first dependency
+	var root func(ti string, visited map[string]bool) string
+	root = func(ti string, visited map[string]bool) string {
+		if len(tiles[ti].DependsOn) == 0 || visited[ti] {
+			return ti
+		}
+		visited[ti] = true
+		return root(tiles[ti].DependsOn[0], visited)
+	}
+	for _, ti := range instances {
+		g.Nodes = append(g.Nodes, node{
+			Id:               ti,
+			TileName:         tiles[ti].TileReference,
+			TileVersion:      tiles[ti].TileVersion,
+			RootTileInstance: root(ti, make(map[string]bool)),
+		})
+	}
+	return g, nil
+}
+
+// groups, label, toDot, mermaidId & toMermaid are copied from dice/engine/graph.go, since mctl is a separate module,
+// keep them in sync so that offline graph is rendered as same as the one from Dice, statuses are DeploymentStatus of Dice.
+func (g *graph) groups() ([]string, map[string][]node) {
+	var roots []string
+	groups := make(map[string][]node)
+	for _, n := range g.Nodes {
+		if _, ok := groups[n.RootTileInstance]; !ok {
+			roots = append(roots, n.RootTileInstance)
+		}
+		groups[n.RootTileInstance] = append(groups[n.RootTileInstance], n)
+	}
+	return roots, groups
+}
+
+func (n node) label(sep string) string {
+	label := n.Id + sep + n.TileName + " - " + n.TileVersion
+	if n.TileCategory != "" {
+		label = label + sep + n.TileCategory
+	}
+	if n.Status != "" {
+		label = label + sep + n.Status
+	}
+	return label
+}
+
+func (g *graph) toDot() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("digraph %q {\n", g.Name))
+	sb.WriteString("  rankdir=LR;\n  node [shape=box, style=rounded];\n")
+	roots, groups := g.groups()
+	for i, root := range roots {
+		indent := "  "
+		if root != "" {
+			sb.WriteString(fmt.Sprintf("  subgraph cluster_%d {\n    label=%q;\n", i, root))
+			indent = "    "
+		}
+		for _, n := range groups[root] {
+			style := "rounded"
+			if n.Generated {
+				style = "rounded,dashed"
+			}
+			sb.WriteString(fmt.Sprintf("%s%q [label=%q, style=%q];\n", indent, n.Id, n.label("\n"), style))
+		}
+		if root != "" {
+			sb.WriteString("  }\n")
+		}
+	}
+	for _, e := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %q -> %q;\n", e.From, e.To))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+var mermaidIdRe = regexp.MustCompile(`[^[:alnum:]_]`)
+
+// mermaidId replaces characters which aren't allowed in Mermaid's id
+func mermaidId(id string) string {
+	return mermaidIdRe.ReplaceAllString(id, "_")
+}
+
+func (g *graph) toMermaid() string {
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	roots, groups := g.groups()
+	for _, root := range roots {
+		indent := "  "
+		if root != "" {
+			sb.WriteString(fmt.Sprintf("  subgraph %s\n", mermaidId(root)+"Group"))
+			indent = "    "
+		}
+		for _, n := range groups[root] {
+			sb.WriteString(fmt.Sprintf("%s%s[\"%s\"]\n", indent, mermaidId(n.Id), n.label("<br/>")))
+		}
+		if root != "" {
+			sb.WriteString("  end\n")
+		}
+	}
+	for _, e := range g.Edges {
+		sb.WriteString(fmt.Sprintf("  %s --> %s\n", mermaidId(e.From), mermaidId(e.To)))
+	}
+	for _, n := range g.Nodes {
+		if n.Status == "Done" || n.Status == "Interrupted" || n.Status == "Progress" {
+			sb.WriteString(fmt.Sprintf("  class %s %s\n", mermaidId(n.Id), strings.ToLower(n.Status)))
+		}
+	}
+	sb.WriteString("  classDef done fill:#d4edda\n  classDef progress fill:#fff3cd\n  classDef interrupted fill:#f8d7da\n")
+	return sb.String()
+}
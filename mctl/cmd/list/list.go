@@ -17,6 +17,7 @@ type deployment struct {
 	Updated     time.Time `json:"Updated"`     // Last update
 	SuperFolder string    `json:"SuperFolder"` // Main folder for all stuff per deployment
 	Status      string    `json:"Status"`      // Status of deployment
+	Position    int       `json:"Position"`    // Position in the queue if it's Queued
 }
 
 var TilesInRepo = &cobra.Command{
@@ -119,10 +120,14 @@ var Deployment = &cobra.Command{
 				logger.Info("--------- Deployment Records --------- \n")
 				logger.Info("Name\t\t Created time\t\t Last update\t\t Folder\t\t Status\n")
 				for _, d := range dr {
+					status := d.Status
+					if d.Position > 0 {
+						status = fmt.Sprintf("%s #%d", d.Status, d.Position)
+					}
 					logger.Info("%s\t\t %s\t\t %s\t\t %s\t\t %s\n", d.Name,
 						d.Created.Local().Format("2006-01-02 15:04:05"),
 						d.Updated.Local().Format("2006-01-02 15:04:05"),
-						d.SuperFolder, d.Status)
+						d.SuperFolder, status)
 				}
 
 				logger.Info("--------- ---------------- -----------\n")
@@ -14,17 +14,20 @@ func (l License) LicenseString() string {
 }
 
 type TileMetadata struct {
-	Name         string         `json:"name"`
-	Version      string         `json:"version"`
-	Category     string         `json:"category"`
-	Description  string         `json:"description"`
-	TileRepo     string         `json:"tileRepo"`
-	VersionTag   string         `json:"versionTag"`
-	Author       string         `json:"author,omitempty"`
-	Email        string         `json:"email,omitempty"`
-	License      string         `json:"license,omitempty"`
-	Dependencies []TileMetadata `json:"dependencies,omitempty"`
-	Released     time.Time      `json:"released"`
+	Name                     string         `json:"name"`
+	Version                  string         `json:"version"`
+	Category                 string         `json:"category"`
+	VendorService            string         `json:"vendorService,omitempty"`
+	DependentOnVendorService string         `json:"dependentOnVendorService,omitempty"`
+	Description              string         `json:"description"`
+	TileRepo                 string         `json:"tileRepo"`
+	VersionTag               string         `json:"versionTag"`
+	Author                   string         `json:"author,omitempty"`
+	Email                    string         `json:"email,omitempty"`
+	License                  string         `json:"license,omitempty"`
+	Dependencies             []TileMetadata `json:"dependencies,omitempty"`
+	Released                 time.Time      `json:"released"`
+	Digest                   string         `json:"digest,omitempty"`
 }
 
 type HuMetadata struct {
@@ -37,4 +40,5 @@ type HuMetadata struct {
 	License      string         `json:"license,omitempty"`
 	Dependencies []TileMetadata `json:"dependencies"`
 	Released     time.Time      `json:"released"`
+	Digest       string         `json:"digest,omitempty"`
 }
@@ -0,0 +1,37 @@
+package lock
+
+import (
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"mctl/cmd"
+)
+
+var Lock = &cobra.Command{
+	Use:   "lock <d-sid>",
+	Short: "\tGenerate mahjong.lock for a deployment.",
+	Long:  "\tGenerate mahjong.lock for a deployment, which pins versions & digests of all resolved Tiles.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		lockFunc(c, args)
+	},
+}
+
+func init() {
+	Lock.PersistentFlags().StringP("filename", "f", "mahjong.lock", "where to save the lock")
+}
+
+func lockFunc(c *cobra.Command, args []string) {
+	addr, _ := c.Flags().GetString("addr")
+	filename, _ := c.Flags().GetString("filename")
+	buf, err := cmd.RunGetByVersion(addr, "ts/"+args[0]+"/lock")
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	if err = ioutil.WriteFile(filename, buf, 0644); err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	logger.Info("Saved lock of %s into %s\n", args[0], filename)
+}
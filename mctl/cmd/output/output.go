@@ -0,0 +1,155 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"mctl/cmd"
+	"net/url"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+var Output = &cobra.Command{
+	Use:   "output <d-sid|name> [key]",
+	Short: "\tShow outputs of a deployment.",
+	Long: "\tShow summary outputs & outputs of Tile instances of a deployment, the latest deployment would be chosen by name. " +
+		"Key is name of summary output or <tile-instance>.<output>, and only the value would be printed out, e.g.\n" +
+		"\t\tmctl output eks-simple tileEks0005.clusterEndpoint\n" +
+		"\t\teval \"$(mctl output eks-simple -o env)\"",
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(c *cobra.Command, args []string) {
+		if err := outputFunc(c, args); err != nil {
+			logger.Warning("%s\n", err)
+			// Fail CI jobs
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	Output.Flags().StringP("output", "o", "text", "output format: text, json, yaml, env")
+}
+
+// outputs is as same as the one from Dice
+type outputs struct {
+	SID     string `json:"sid"`
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Summary struct {
+		Description string        `json:"description,omitempty"`
+		Outputs     []outputValue `json:"outputs"`
+		Notes       []string      `json:"notes,omitempty"`
+	} `json:"summary"`
+	Tiles []struct {
+		TileInstance string        `json:"tileInstance"`
+		TileName     string        `json:"tileName"`
+		TileVersion  string        `json:"tileVersion"`
+		Outputs      []outputValue `json:"outputs"`
+	} `json:"tiles"`
+}
+
+type outputValue struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+func outputFunc(c *cobra.Command, args []string) error {
+	addr, _ := c.Flags().GetString("addr")
+	format, _ := c.Flags().GetString("output")
+
+	// Values in env are quoted by Dice, so that they're safe for eval
+	if format == "env" || (format == "yaml" && len(args) == 1) {
+		uri := "ts/" + args[0] + "/outputs?format=" + format
+		if len(args) == 2 {
+			uri += "&key=" + url.QueryEscape(args[1])
+		}
+		buf, err := cmd.RunGetByVersion(addr, uri)
+		if err == nil {
+			fmt.Print(string(buf))
+		}
+		return err
+	}
+	buf, err := cmd.RunGetByVersion(addr, "ts/"+args[0]+"/outputs")
+	if err != nil {
+		return err
+	}
+	var o outputs
+	if err = json.Unmarshal(buf, &o); err != nil {
+		return err
+	}
+
+	if len(args) == 2 {
+		key := args[1]
+		value, ok := o.lookup(key)
+		if !ok {
+			return errors.New("output wasn't found: " + key)
+		}
+		switch format {
+		case "json", "yaml":
+			buf, _ := json.Marshal(map[string]string{key: value})
+			if format == "yaml" {
+				buf, _ = yaml.JSONToYAML(buf)
+			}
+			fmt.Println(strings.TrimSuffix(string(buf), "\n"))
+		default:
+			fmt.Println(value)
+		}
+		return nil
+	}
+
+	switch format {
+	case "json":
+		buf, err := json.MarshalIndent(o, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", string(buf))
+	case "text":
+		fmt.Printf("Deployment: %s (%s) - %s\n", o.Name, o.SID, o.Status)
+		if o.Summary.Description != "" {
+			fmt.Printf("\n%s\n", o.Summary.Description)
+		}
+		if len(o.Summary.Outputs) > 0 {
+			fmt.Println("\nSummary:")
+			for _, v := range o.Summary.Outputs {
+				fmt.Printf("  %s = %s\n", v.Name, v.Value)
+			}
+		}
+		for _, t := range o.Tiles {
+			fmt.Printf("\n%s < %s - %s >:\n", t.TileInstance, t.TileName, t.TileVersion)
+			for _, v := range t.Outputs {
+				fmt.Printf("  %s = %s\n", v.Name, v.Value)
+			}
+		}
+	default:
+		return errors.New("unsupported output format: " + format)
+	}
+	return nil
+}
+
+// lookup finds value by name of summary output or <tile-instance>.<output>
+func (o *outputs) lookup(key string) (string, bool) {
+	for _, v := range o.Summary.Outputs {
+		if v.Name == key {
+			return v.Value, true
+		}
+	}
+	if i := strings.LastIndex(key, "."); i > 0 {
+		for _, t := range o.Tiles {
+			if t.TileInstance != key[:i] {
+				continue
+			}
+			for _, v := range t.Outputs {
+				if v.Name == key[i+1:] {
+					return v.Value, true
+				}
+			}
+		}
+	}
+	return "", false
+}
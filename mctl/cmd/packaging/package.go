@@ -0,0 +1,102 @@
+package packaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"mctl/cmd"
+	"net/http"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+var Package = &cobra.Command{
+	Use:   "package <tile directory>",
+	Short: "\tPackage Tile as a reproducible tarball.",
+	Long:  "\tValidate Tile specification and package Tile as a reproducible tarball with digest, excluding node_modules.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		packageFunc(c, args)
+	},
+}
+
+// tileSpec is the minimal part of Tile specification for packaging
+type tileSpec struct {
+	Metadata struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"metadata"`
+}
+
+func init() {
+	Package.PersistentFlags().StringP("directory", "d", ".", "Where to place the packaged Tile")
+}
+
+func packageFunc(c *cobra.Command, args []string) {
+	addr, _ := c.Flags().GetString("addr")
+	oDir, _ := c.Flags().GetString("directory")
+	file, digest, err := PackTile(addr, args[0], oDir)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	logger.Info("Packaged Tile into %s\n", file)
+	logger.Info("Digest: %s\n", digest)
+}
+
+// PackTile validates Tile through Dice and packs into <tile name>-<version>.tgz under oDir
+func PackTile(addr string, tileDir string, oDir string) (string, string, error) {
+	name, version, err := ValidateTile(addr, tileDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	buf, err := cmd.TarGz(tileDir, func(path string) bool {
+		base := filepath.Base(path)
+		return base == "node_modules" || base == ".DS_Store" || base == "role.arn"
+	})
+	if err != nil {
+		return "", "", err
+	}
+	file := filepath.Join(oDir, fmt.Sprintf("%s-%s.tgz", strings.ToLower(name), version))
+	if err := ioutil.WriteFile(file, buf, 0644); err != nil {
+		return "", "", err
+	}
+	return file, Digest(buf), nil
+}
+
+// ValidateTile validates tile-spec.yaml under tileDir through Dice, return name & version of Tile
+func ValidateTile(addr string, tileDir string) (string, string, error) {
+	spec, err := ioutil.ReadFile(filepath.Join(tileDir, "tile-spec.yaml"))
+	if err != nil {
+		return "", "", err
+	}
+	code, resp, err := cmd.RunPostWithBody(addr, "tile", "text/yaml", spec)
+	if err != nil {
+		return "", "", err
+	}
+	if code != http.StatusOK {
+		return "", "", fmt.Errorf("tile-spec.yaml was invalid : %s", resp)
+	}
+
+	return NameVersion(spec)
+}
+
+// NameVersion returns name & version of Tile from tile-spec.yaml
+func NameVersion(spec []byte) (string, string, error) {
+	var ts tileSpec
+	if err := yaml.Unmarshal(spec, &ts); err != nil {
+		return "", "", err
+	}
+	return ts.Metadata.Name, ts.Metadata.Version, nil
+}
+
+// Digest returns digest of content as sha256:<hex>
+func Digest(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
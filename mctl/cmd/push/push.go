@@ -0,0 +1,139 @@
+package push
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"mctl/cmd"
+	"mctl/cmd/packaging"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var Push = &cobra.Command{
+	Use:   "push",
+	Short: "\tPush Tile/Hu into the Repo.",
+	Long:  "\tPush packaged Tile or Hu into the Repo through Dice.",
+}
+
+var Tile = &cobra.Command{
+	Use:   "tile <tile directory | packaged tile>",
+	Short: "\tPush Tile into the Repo.",
+	Long:  "\tPush Tile into the Repo, Tile directory would be packaged before pushing.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		tileFunc(c, args)
+	},
+}
+
+var Hu = &cobra.Command{
+	Use:   "hu <hu file>",
+	Short: "\tPush Hu into the Repo.",
+	Long:  "\tPush Hu into the Repo, Hu will be named after file name unless --name was given.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		huFunc(c, args)
+	},
+}
+
+// pushed is response from Dice after pushing
+type pushed struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Digest  string `json:"digest"`
+	Error   string `json:"error"`
+}
+
+func init() {
+	Hu.PersistentFlags().StringP("name", "n", "", "The name of Hu in the Repo")
+	Push.AddCommand(Tile, Hu)
+}
+
+func tileFunc(c *cobra.Command, args []string) {
+	addr, _ := c.Flags().GetString("addr")
+	file := args[0]
+	if info, err := os.Stat(file); err != nil {
+		logger.Warning("%s\n", err)
+		return
+	} else if info.IsDir() {
+		dir, err := ioutil.TempDir("", "mctl-")
+		if err != nil {
+			logger.Warning("%s\n", err)
+			return
+		}
+		defer os.RemoveAll(dir)
+		if file, _, err = packaging.PackTile(addr, args[0], dir); err != nil {
+			logger.Warning("%s\n", err)
+			return
+		}
+	}
+
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	spec, err := cmd.ReadFileFromTarGz(bytes.NewReader(buf), "tile-spec.yaml")
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	name, version, err := packaging.NameVersion(spec)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+
+	logger.Info("Pushing Tile < %s - %s > ...\n", name, version)
+	p, err := push(addr, fmt.Sprintf("repo/tile/%s/%s", name, version), "application/gzip", buf)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	if p.Digest != packaging.Digest(buf) {
+		logger.Warning("Digest was inconsistent, local: %s, pushed: %s\n", packaging.Digest(buf), p.Digest)
+		return
+	}
+	logger.Info("Pushed Tile < %s - %s > with digest: %s\n", p.Name, p.Version, p.Digest)
+}
+
+func huFunc(c *cobra.Command, args []string) {
+	addr, _ := c.Flags().GetString("addr")
+	name, _ := c.Flags().GetString("name")
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	}
+	buf, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+
+	logger.Info("Pushing Hu < %s > ...\n", name)
+	p, err := push(addr, "repo/hu/"+name, "text/yaml", buf)
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	logger.Info("Pushed Hu < %s > with digest: %s\n", p.Name, p.Digest)
+}
+
+func push(addr string, uri string, contentType string, buf []byte) (*pushed, error) {
+	code, resp, err := cmd.RunPostWithBody(addr, uri, contentType, buf)
+	if err != nil {
+		return nil, err
+	}
+	var p pushed
+	if err := json.Unmarshal(resp, &p); err != nil {
+		return nil, fmt.Errorf("%d : %s", code, resp)
+	}
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("%d : %s", code, p.Error)
+	}
+	return &p, nil
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kris-nova/logger"
+	"github.com/spf13/cobra"
+	"mctl/cmd"
+	"mctl/cmd/list"
+	"net/url"
+	"sigs.k8s.io/yaml"
+	"strconv"
+)
+
+var Search = &cobra.Command{
+	Use:   "search [term]",
+	Short: "\tSearch Tiles in the Repo.",
+	Long:  "\tSearch Tiles in the Repo by name, description, category, vendor service or author.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		searchFunc(c, args)
+	},
+}
+
+func init() {
+	Search.Flags().String("category", "", "category of Tile, e.g. ContainerApplication")
+	Search.Flags().String("vendor-service", "", "vendor service of Tile or Tile depends on, e.g. EKS")
+	Search.Flags().String("author", "", "author of Tile")
+	Search.Flags().String("sort", "name", "sort by: name, version, released")
+	Search.Flags().Bool("latest", false, "only show the latest version of each Tile")
+	Search.Flags().Int("page", 1, "page of result")
+	Search.Flags().Int("page-size", 0, "size of page, all matched Tiles would be shown if it's 0")
+	Search.Flags().StringP("output", "o", "table", "output format: table, json, yaml")
+}
+
+func searchFunc(c *cobra.Command, args []string) {
+	addr, _ := c.Flags().GetString("addr")
+	output, _ := c.Flags().GetString("output")
+	category, _ := c.Flags().GetString("category")
+	vendorService, _ := c.Flags().GetString("vendor-service")
+	author, _ := c.Flags().GetString("author")
+	sort, _ := c.Flags().GetString("sort")
+	latest, _ := c.Flags().GetBool("latest")
+	page, _ := c.Flags().GetInt("page")
+	pageSize, _ := c.Flags().GetInt("page-size")
+
+	query := url.Values{}
+	if len(args) > 0 {
+		query.Set("q", args[0])
+	}
+	for k, v := range map[string]string{"category": category, "vendorService": vendorService, "author": author, "sort": sort} {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	query.Set("latest", strconv.FormatBool(latest))
+	query.Set("page", strconv.Itoa(page))
+	query.Set("pageSize", strconv.Itoa(pageSize))
+
+	buf, header, err := cmd.RunGetWithHeader(addr, "v1alpha1/repo/tile?"+query.Encode())
+	if err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+	var tms []list.TileMetadata
+	if err = json.Unmarshal(buf, &tms); err != nil {
+		logger.Warning("%s\n", err)
+		return
+	}
+
+	switch output {
+	case "json":
+		fmt.Printf("%s\n", string(buf))
+	case "yaml":
+		if buf, err = yaml.Marshal(tms); err != nil {
+			logger.Warning("%s\n", err)
+			return
+		}
+		fmt.Printf("%s", string(buf))
+	case "table":
+		logger.Info("%s\t\t %s\t\t %s\t\t %s\t\t %s\n", "Name", "Version", "Category", "Vendor Service", "Released")
+		for _, tm := range tms {
+			vs := tm.VendorService
+			if vs == "" {
+				vs = tm.DependentOnVendorService
+			}
+			logger.Info("%s\t\t %s\t\t %s\t\t %s\t\t %s\n", tm.Name, tm.Version, tm.Category, vs, tm.Released.Local().Format("2006-01-02 15:04:05"))
+		}
+		logger.Info("%d of %s Tiles matched\n", len(tms), header.Get("X-Total-Count"))
+	default:
+		logger.Warning("unsupported format")
+	}
+}
@@ -3,12 +3,16 @@ package cmd
 import (
 	"archive/tar"
 	"archive/zip"
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Unzip will decompress a zip archive, moving all files and folders
@@ -69,6 +73,7 @@ func Unzip(src string, dest string) ([]string, error) {
 	return filenames, nil
 }
 
+// UnTarGz decompresses the tarball into dst, entries out of dst & links are rejected
 func UnTarGz(dst string, r io.Reader) error {
 
 	if _, err := os.Stat(dst); err != nil {
@@ -111,6 +116,11 @@ func UnTarGz(dst string, r io.Reader) error {
 		// the target location where the dir/file should be created
 		target := filepath.Join(dst, header.Name)
 
+		// Check for ZipSlip, entries must stay under dst
+		if target != filepath.Clean(dst) && !strings.HasPrefix(target, filepath.Clean(dst)+string(os.PathSeparator)) {
+			return fmt.Errorf("%s: illegal file path", header.Name)
+		}
+
 		// the following switch could also be done using fi.Mode(), not sure if there
 		// a benefit of using one vs. the other.
 		// fi := header.FileInfo()
@@ -144,7 +154,113 @@ func UnTarGz(dst string, r io.Reader) error {
 				return err
 			}
 			fmt.Print("#")
+
+		// links could point out of dst, which are written through by next entries
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("%s: links aren't allowed in the tarball", header.Name)
+		}
+	}
+
+}
+
+// TarGz packs all files under src into a reproducible tarball, which has sorted entries, normalized
+// permission & owner and zero timestamp, so that same content always produces same digest.
+func TarGz(src string, skip func(path string) bool) ([]byte, error) {
+	var paths []string
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == src {
+			return nil
+		}
+		if skip != nil && skip(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || info.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	gzw.ModTime = time.Unix(0, 0)
+	tw := tar.NewWriter(gzw)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return nil, err
+		}
+		header := &tar.Header{
+			Name:    "./" + filepath.ToSlash(rel),
+			ModTime: time.Unix(0, 0),
 		}
+		if info.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name = header.Name + "/"
+			header.Mode = 0755
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Size = info.Size()
+			header.Mode = 0644
+			if info.Mode()&0111 != 0 {
+				header.Mode = 0755
+			}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
 	}
+	if err := gzw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
 
+// ReadFileFromTarGz returns content of a file in the tarball, eg: tile-spec.yaml
+func ReadFileFromTarGz(r io.Reader, name string) ([]byte, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("%s wasn't existed in the tarball", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Clean(header.Name) == filepath.Clean(name) {
+			return ioutil.ReadAll(tr)
+		}
+	}
 }
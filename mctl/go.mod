@@ -9,4 +9,6 @@ require (
 	github.com/kris-nova/logger v0.0.0-20181127235838-fd0d87064b06
 	github.com/kris-nova/lolgopher v0.0.0-20180921204813-313b3abb0d9b // indirect
 	github.com/spf13/cobra v1.0.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	sigs.k8s.io/yaml v1.2.0
 )
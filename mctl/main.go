@@ -3,9 +3,20 @@ package main
 import (
 	"github.com/kris-nova/logger"
 	"github.com/spf13/cobra"
+	mcmd "mctl/cmd"
+	"mctl/cmd/cancel"
 	"mctl/cmd/deploy"
+	"mctl/cmd/describe"
+	"mctl/cmd/diagnose"
+	"mctl/cmd/diff"
+	"mctl/cmd/graph"
 	"mctl/cmd/initial"
 	"mctl/cmd/list"
+	"mctl/cmd/lock"
+	"mctl/cmd/output"
+	"mctl/cmd/packaging"
+	"mctl/cmd/push"
+	"mctl/cmd/search"
 	"mctl/cmd/validate"
 	"mctl/cmd/version"
 )
@@ -29,6 +40,18 @@ func main() {
 	addr := cmd.PersistentFlags().Lookup("addr")
 	addr.Shorthand = "s"
 
+	// Workspace
+	cmd.PersistentFlags().StringP("workspace", "w", "", "Workspace of Dice, the default workspace is used if it's empty")
+
+	// TLS
+	cmd.PersistentFlags().Bool("tls", false, "Connect Dice with https:// & wss://, or using https:// in --addr")
+	cmd.PersistentFlags().String("ca-file", "", "CA bundle to verify Dice's certificate")
+	cmd.PersistentFlags().String("client-cert", "", "Client certificate if Dice requires mTLS")
+	cmd.PersistentFlags().String("client-key", "", "Private key of client certificate")
+	cmd.PersistentPreRun = func(c *cobra.Command, args []string) {
+		mcmd.ApplyFlags(c)
+	}
+
 	// dry-run
 	cmd.PersistentFlags().Bool("dry-run", false, "Only print out the yaml that would be executed")
 
@@ -42,7 +65,17 @@ func main() {
 		validate.Validate,
 		deploy.Deploy,
 		version.Version,
-		list.Repo)
+		list.Repo,
+		lock.Lock,
+		packaging.Package,
+		push.Push,
+		search.Search,
+		graph.Graph,
+		describe.Describe,
+		diff.Diff,
+		output.Output,
+		diagnose.Diagnose,
+		cancel.Cancel)
 	cmd.TraverseChildren = true
 
 	// Running mctl